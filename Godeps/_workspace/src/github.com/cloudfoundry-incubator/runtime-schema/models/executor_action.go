@@ -13,12 +13,23 @@ type DownloadAction struct {
 	To       string `json:"to"`
 	Extract  bool   `json:"extract"`
 	CacheKey string `json:"cache_key"`
+
+	ChecksumAlgorithm string `json:"checksum_algorithm,omitempty"`
+	ChecksumValue     string `json:"checksum_value,omitempty"`
 }
 
+const (
+	UploadFormatRaw = "raw"
+	UploadFormatTar = "tar"
+	UploadFormatTgz = "tgz"
+	UploadFormatZip = "zip"
+)
+
 type UploadAction struct {
 	To       string `json:"to"`
 	From     string `json:"from"`
 	Compress bool   `json:"compress"`
+	Format   string `json:"format,omitempty"`
 }
 
 type RunAction struct {
@@ -38,8 +49,13 @@ type ResourceLimits struct {
 	Nofile *uint64 `json:"nofile,omitempty"`
 }
 
+const FetchResultFormatJSON = "json"
+
 type FetchResultAction struct {
-	File string `json:"file"`
+	File         string `json:"file"`
+	Name         string `json:"name,omitempty"`
+	MaxSizeBytes int64  `json:"max_size_bytes,omitempty"`
+	Format       string `json:"format,omitempty"`
 }
 
 type TryAction struct {
@@ -52,11 +68,32 @@ type MonitorAction struct {
 	UnhealthyHook      HealthRequest  `json:"unhealthy_hook"`
 	HealthyThreshold   uint           `json:"healthy_threshold"`
 	UnhealthyThreshold uint           `json:"unhealthy_threshold"`
+
+	InitialInterval time.Duration `json:"initial_interval,omitempty"`
+	MaxInterval     time.Duration `json:"max_interval,omitempty"`
+	CheckTimeout    time.Duration `json:"check_timeout,omitempty"`
 }
 
 type HealthRequest struct {
 	Method string `json:"method"`
 	URL    string `json:"url"`
+
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Timeout time.Duration     `json:"timeout,omitempty"`
+	Retries uint              `json:"retries,omitempty"`
+}
+
+type TCPCheckAction struct {
+	Port    uint32        `json:"port"`
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+type HTTPCheckAction struct {
+	Port                uint32        `json:"port"`
+	Path                string        `json:"path,omitempty"`
+	ExpectedStatusCodes []int         `json:"expected_status_codes,omitempty"`
+	Timeout             time.Duration `json:"timeout,omitempty"`
 }
 
 type ParallelAction struct {
@@ -68,6 +105,11 @@ type EmitProgressAction struct {
 	StartMessage   string         `json:"start_message"`
 	SuccessMessage string         `json:"success_message"`
 	FailureMessage string         `json:"failure_message"`
+
+	// Templated messages are Go text/templates; Timestamps prefixes every
+	// line streamed by the action, and the messages, with the time.
+	Templated  bool `json:"templated,omitempty"`
+	Timestamps bool `json:"timestamps,omitempty"`
 }
 
 func EmitProgressFor(action ExecutorAction, startMessage string, successMessage string, failureMessage string) ExecutorAction {
@@ -106,33 +148,46 @@ type ExecutorAction struct {
 	Action interface{} `json:"-"`
 }
 
-func (a ExecutorAction) MarshalJSON() ([]byte, error) {
-	var envelope executorActionEnvelope
-
-	payload, err := json.Marshal(a.Action)
-
-	if err != nil {
-		return nil, err
-	}
-
+// Name is what the action is called in its JSON envelope, or "" if it is
+// not an action.
+func (a ExecutorAction) Name() string {
 	switch a.Action.(type) {
 	case DownloadAction:
-		envelope.Name = "download"
+		return "download"
 	case RunAction:
-		envelope.Name = "run"
+		return "run"
 	case UploadAction:
-		envelope.Name = "upload"
+		return "upload"
 	case FetchResultAction:
-		envelope.Name = "fetch_result"
+		return "fetch_result"
 	case EmitProgressAction:
-		envelope.Name = "emit_progress"
+		return "emit_progress"
 	case TryAction:
-		envelope.Name = "try"
+		return "try"
 	case MonitorAction:
-		envelope.Name = "monitor"
+		return "monitor"
 	case ParallelAction:
-		envelope.Name = "parallel"
-	default:
+		return "parallel"
+	case TCPCheckAction:
+		return "tcp_check"
+	case HTTPCheckAction:
+		return "http_check"
+	}
+
+	return ""
+}
+
+func (a ExecutorAction) MarshalJSON() ([]byte, error) {
+	var envelope executorActionEnvelope
+
+	payload, err := json.Marshal(a.Action)
+
+	if err != nil {
+		return nil, err
+	}
+
+	envelope.Name = a.Name()
+	if envelope.Name == "" {
 		return nil, InvalidActionConversion
 	}
 
@@ -182,6 +237,14 @@ func (a *ExecutorAction) UnmarshalJSON(bytes []byte) error {
 		action := ParallelAction{}
 		err = json.Unmarshal(*envelope.ActionPayload, &action)
 		a.Action = action
+	case "tcp_check":
+		action := TCPCheckAction{}
+		err = json.Unmarshal(*envelope.ActionPayload, &action)
+		a.Action = action
+	case "http_check":
+		action := HTTPCheckAction{}
+		err = json.Unmarshal(*envelope.ActionPayload, &action)
+		a.Action = action
 	default:
 		err = InvalidActionConversion
 	}
@@ -25,6 +25,18 @@ var _ = Describe("ExecutorAction", func() {
 			err := json.Unmarshal([]byte(actionPayload), &unmarshalledAction)
 			Ω(err).Should(Equal(InvalidActionConversion))
 		})
+
+		It("has no name", func() {
+			Ω(ExecutorAction{Action: "aliens"}.Name()).Should(BeEmpty())
+		})
+	})
+
+	Describe("Name", func() {
+		It("is the name the action has in JSON", func() {
+			Ω(ExecutorAction{RunAction{}}.Name()).Should(Equal("run"))
+			Ω(ExecutorAction{EmitProgressAction{}}.Name()).Should(Equal("emit_progress"))
+			Ω(ExecutorAction{HTTPCheckAction{}}.Name()).Should(Equal("http_check"))
+		})
 	})
 
 	itSerializesAndDeserializes := func(actionPayload string, action interface{}) {
@@ -68,6 +80,32 @@ var _ = Describe("ExecutorAction", func() {
 				},
 			},
 		)
+
+		Context("with a checksum", func() {
+			itSerializesAndDeserializes(
+				`{
+					"action": "download",
+					"args": {
+						"from": "web_location",
+						"to": "local_location",
+						"cache_key": "elephant",
+						"extract": true,
+						"checksum_algorithm": "sha256",
+						"checksum_value": "some-checksum"
+					}
+				}`,
+				ExecutorAction{
+					Action: DownloadAction{
+						From:              "web_location",
+						To:                "local_location",
+						Extract:           true,
+						CacheKey:          "elephant",
+						ChecksumAlgorithm: "sha256",
+						ChecksumValue:     "some-checksum",
+					},
+				},
+			)
+		})
 	})
 
 	Describe("Upload", func() {
@@ -88,6 +126,27 @@ var _ = Describe("ExecutorAction", func() {
 				},
 			},
 		)
+
+		Context("with a format", func() {
+			itSerializesAndDeserializes(
+				`{
+					"action": "upload",
+					"args": {
+						"from": "local_location",
+						"to": "web_location",
+						"compress": false,
+						"format": "zip"
+					}
+				}`,
+				ExecutorAction{
+					Action: UploadAction{
+						From:   "local_location",
+						To:     "web_location",
+						Format: UploadFormatZip,
+					},
+				},
+			)
+		})
 	})
 
 	Describe("Run", func() {
@@ -133,6 +192,28 @@ var _ = Describe("ExecutorAction", func() {
 				},
 			},
 		)
+
+		Context("with a name, a size limit and a format", func() {
+			itSerializesAndDeserializes(
+				`{
+					"action": "fetch_result",
+					"args": {
+						"file": "/tmp/foo",
+						"name": "staging_info",
+						"max_size_bytes": 1024,
+						"format": "json"
+					}
+				}`,
+				ExecutorAction{
+					FetchResultAction{
+						File:         "/tmp/foo",
+						Name:         "staging_info",
+						MaxSizeBytes: 1024,
+						Format:       FetchResultFormatJSON,
+					},
+				},
+			)
+		})
 	})
 
 	Describe("EmitProgressAction", func() {
@@ -162,6 +243,41 @@ var _ = Describe("ExecutorAction", func() {
 					},
 				}, "reticulating splines", "reticulated splines", "reticulation failed"),
 		)
+
+		Context("with templated messages and timestamps", func() {
+			itSerializesAndDeserializes(
+				`{
+					"action": "emit_progress",
+					"args": {
+						"start_message": "reticulating splines",
+						"success_message": "reticulated splines in {{.Elapsed}}",
+						"failure_message": "reticulation failed",
+						"templated": true,
+						"timestamps": true,
+						"action": {
+							"action": "run",
+							"args": {
+								"path": "echo",
+								"args": null,
+								"timeout": 0,
+								"env": null,
+								"resource_limits":{}
+							}
+						}
+					}
+				}`,
+				ExecutorAction{
+					EmitProgressAction{
+						Action:         ExecutorAction{RunAction{Path: "echo"}},
+						StartMessage:   "reticulating splines",
+						SuccessMessage: "reticulated splines in {{.Elapsed}}",
+						FailureMessage: "reticulation failed",
+						Templated:      true,
+						Timestamps:     true,
+					},
+				},
+			)
+		})
 	})
 
 	Describe("Try", func() {
@@ -230,6 +346,105 @@ var _ = Describe("ExecutorAction", func() {
 				},
 			},
 		)
+
+		Context("with intervals, timeouts and hook payloads", func() {
+			itSerializesAndDeserializes(
+				`{
+					"action": "monitor",
+					"args": {
+						"action": {
+							"action": "run",
+							"args": {
+								"resource_limits": {},
+								"env": null,
+								"timeout": 0,
+								"path": "echo",
+								"args": null
+							}
+						},
+						"healthy_hook": {
+							"method": "POST",
+							"url": "bogus_healthy_hook",
+							"headers": {"Content-Type": "application/json"},
+							"body": "{\"guid\": {{json .Guid}}}",
+							"timeout": 1000000000,
+							"retries": 3
+						},
+						"unhealthy_hook": {
+							"method": "DELETE",
+							"url": "bogus_unhealthy_hook"
+						},
+						"healthy_threshold": 2,
+						"unhealthy_threshold": 5,
+						"initial_interval": 1000000000,
+						"max_interval": 60000000000,
+						"check_timeout": 5000000000
+					}
+				}`,
+				ExecutorAction{
+					MonitorAction{
+						Action: ExecutorAction{RunAction{Path: "echo"}},
+						HealthyHook: HealthRequest{
+							Method:  "POST",
+							URL:     "bogus_healthy_hook",
+							Headers: map[string]string{"Content-Type": "application/json"},
+							Body:    `{"guid": {{json .Guid}}}`,
+							Timeout: time.Second,
+							Retries: 3,
+						},
+						UnhealthyHook: HealthRequest{
+							Method: "DELETE",
+							URL:    "bogus_unhealthy_hook",
+						},
+						HealthyThreshold:   2,
+						UnhealthyThreshold: 5,
+						InitialInterval:    time.Second,
+						MaxInterval:        time.Minute,
+						CheckTimeout:       5 * time.Second,
+					},
+				},
+			)
+		})
+	})
+
+	Describe("TCPCheck", func() {
+		itSerializesAndDeserializes(
+			`{
+				"action": "tcp_check",
+				"args": {
+					"port": 8080,
+					"timeout": 1000000000
+				}
+			}`,
+			ExecutorAction{
+				TCPCheckAction{
+					Port:    8080,
+					Timeout: time.Second,
+				},
+			},
+		)
+	})
+
+	Describe("HTTPCheck", func() {
+		itSerializesAndDeserializes(
+			`{
+				"action": "http_check",
+				"args": {
+					"port": 8080,
+					"path": "/health",
+					"expected_status_codes": [200, 204],
+					"timeout": 1000000000
+				}
+			}`,
+			ExecutorAction{
+				HTTPCheckAction{
+					Port:                8080,
+					Path:                "/health",
+					ExpectedStatusCodes: []int{200, 204},
+					Timeout:             time.Second,
+				},
+			},
+		)
 	})
 
 	Describe("Parallel", func() {
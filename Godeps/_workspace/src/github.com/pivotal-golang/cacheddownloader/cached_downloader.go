@@ -1,6 +1,7 @@
 package cacheddownloader
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
 	"io"
@@ -13,7 +14,8 @@ import (
 )
 
 type CachedDownloader interface {
-	Fetch(url *url.URL, cacheKey string) (io.ReadCloser, error)
+	Fetch(ctx context.Context, url *url.URL, cacheKey string, progress ProgressFunc) (io.ReadCloser, error)
+	Remove(cacheKey string)
 }
 
 type CachingInfoType struct {
@@ -31,41 +33,53 @@ type cachedDownloader struct {
 	cachedPath     string
 	uncachedPath   string
 	maxSizeInBytes int64
-	downloader     *Downloader
+	downloader     RemoteDownloader
 	lock           *sync.Mutex
 
 	cachedFiles map[string]CachedFile
 }
 
 func New(cachedPath string, uncachedPath string, maxSizeInBytes int64, downloadTimeout time.Duration) *cachedDownloader {
+	return NewWithDownloader(cachedPath, uncachedPath, maxSizeInBytes, NewDownloader(downloadTimeout))
+}
+
+func NewWithDownloader(cachedPath string, uncachedPath string, maxSizeInBytes int64, downloader RemoteDownloader) *cachedDownloader {
 	os.RemoveAll(cachedPath)
 	os.MkdirAll(cachedPath, 0770)
 	return &cachedDownloader{
 		cachedPath:     cachedPath,
 		uncachedPath:   uncachedPath,
 		maxSizeInBytes: maxSizeInBytes,
-		downloader:     NewDownloader(downloadTimeout),
+		downloader:     downloader,
 		lock:           &sync.Mutex{},
 		cachedFiles:    map[string]CachedFile{},
 	}
 }
 
-func (c *cachedDownloader) Fetch(url *url.URL, cacheKey string) (io.ReadCloser, error) {
+func (c *cachedDownloader) Fetch(ctx context.Context, url *url.URL, cacheKey string, progress ProgressFunc) (io.ReadCloser, error) {
 	if cacheKey == "" {
-		return c.fetchUncachedFile(url)
+		return c.fetchUncachedFile(ctx, url, progress)
 	} else {
 		cacheKey = fmt.Sprintf("%x", md5.Sum([]byte(cacheKey)))
-		return c.fetchCachedFile(url, cacheKey)
+		return c.fetchCachedFile(ctx, url, cacheKey, progress)
+	}
+}
+
+func (c *cachedDownloader) Remove(cacheKey string) {
+	if cacheKey == "" {
+		return
 	}
+
+	c.removeCacheEntryFor(fmt.Sprintf("%x", md5.Sum([]byte(cacheKey))))
 }
 
-func (c *cachedDownloader) fetchUncachedFile(url *url.URL) (io.ReadCloser, error) {
+func (c *cachedDownloader) fetchUncachedFile(ctx context.Context, url *url.URL, progress ProgressFunc) (io.ReadCloser, error) {
 	destinationFile, err := ioutil.TempFile(c.uncachedPath, "uncached")
 	if err != nil {
 		return nil, err
 	}
 
-	_, _, _, err = c.downloader.Download(url, destinationFile, CachingInfoType{})
+	_, _, _, err = c.downloader.Download(ctx, url, destinationFile, CachingInfoType{}, progress)
 	if err != nil {
 		os.Remove(destinationFile.Name())
 		return nil, err
@@ -76,7 +90,7 @@ func (c *cachedDownloader) fetchUncachedFile(url *url.URL) (io.ReadCloser, error
 	return destinationFile, nil
 }
 
-func (c *cachedDownloader) fetchCachedFile(url *url.URL, cacheKey string) (io.ReadCloser, error) {
+func (c *cachedDownloader) fetchCachedFile(ctx context.Context, url *url.URL, cacheKey string, progress ProgressFunc) (io.ReadCloser, error) {
 	c.recordAccessForCacheKey(cacheKey)
 
 	path := c.pathForCacheKey(cacheKey)
@@ -94,7 +108,7 @@ func (c *cachedDownloader) fetchCachedFile(url *url.URL, cacheKey string) (io.Re
 	}
 	defer os.Remove(tempFile.Name()) //OK, even if we return tempFile 'cause that's how UNIX works.
 
-	didDownload, size, cachingInfo, err := c.downloader.Download(url, tempFile, c.cachingInfoForCacheKey(cacheKey))
+	didDownload, size, cachingInfo, err := c.downloader.Download(ctx, url, tempFile, c.cachingInfoForCacheKey(cacheKey), progress)
 	if err != nil {
 		if fileExists {
 			f.Close()
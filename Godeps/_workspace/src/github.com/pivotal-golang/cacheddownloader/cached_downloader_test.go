@@ -1,6 +1,7 @@
 package cacheddownloader_test
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
 	"io"
@@ -94,7 +95,7 @@ var _ = Describe("File cache", func() {
 					ghttp.RespondWith(http.StatusOK, string(downloadContent), header),
 				))
 
-				file, err = cache.Fetch(url, "")
+				file, err = cache.Fetch(context.Background(), url, "", nil)
 			})
 
 			It("should not error", func() {
@@ -117,7 +118,7 @@ var _ = Describe("File cache", func() {
 		Context("when the download fails", func() {
 			BeforeEach(func() {
 				server.AllowUnhandledRequests = true //will 500 for any attempted requests
-				file, err = cache.Fetch(url, "")
+				file, err = cache.Fetch(context.Background(), url, "", nil)
 			})
 
 			It("should return an error and no file", func() {
@@ -153,7 +154,7 @@ var _ = Describe("File cache", func() {
 						ghttp.RespondWith(http.StatusOK, string(downloadContent), returnedHeader),
 					))
 
-					file, err = cache.Fetch(url, cacheKey)
+					file, err = cache.Fetch(context.Background(), url, cacheKey, nil)
 				})
 
 				It("should not error", func() {
@@ -185,7 +186,7 @@ var _ = Describe("File cache", func() {
 						ghttp.RespondWith(http.StatusOK, string(downloadContent)),
 					))
 
-					file, err = cache.Fetch(url, cacheKey)
+					file, err = cache.Fetch(context.Background(), url, cacheKey, nil)
 				})
 
 				It("should not error", func() {
@@ -206,7 +207,7 @@ var _ = Describe("File cache", func() {
 			Context("when the download fails", func() {
 				BeforeEach(func() {
 					server.AllowUnhandledRequests = true //will 500 for any attempted requests
-					file, err = cache.Fetch(url, cacheKey)
+					file, err = cache.Fetch(context.Background(), url, cacheKey, nil)
 				})
 
 				It("should return an error and no file", func() {
@@ -235,7 +236,7 @@ var _ = Describe("File cache", func() {
 					ghttp.RespondWith(http.StatusOK, string(fileContent), returnedHeader),
 				))
 
-				cache.Fetch(url, cacheKey)
+				cache.Fetch(context.Background(), url, cacheKey, nil)
 
 				downloadContent = "now you don't"
 
@@ -252,28 +253,38 @@ var _ = Describe("File cache", func() {
 			})
 
 			It("should perform the request with the correct modified headers", func() {
-				cache.Fetch(url, cacheKey)
+				cache.Fetch(context.Background(), url, cacheKey, nil)
 				Ω(server.ReceivedRequests()).Should(HaveLen(2))
 			})
 
+			Context("when the cache entry is removed", func() {
+				BeforeEach(func() {
+					cache.Remove(cacheKey)
+				})
+
+				It("should delete the file from the cache", func() {
+					Ω(ioutil.ReadDir(cachedPath)).Should(HaveLen(0))
+				})
+			})
+
 			Context("if the file has been modified", func() {
 				BeforeEach(func() {
 					status = http.StatusOK
 				})
 
 				It("should redownload the file", func() {
-					cache.Fetch(url, cacheKey)
+					cache.Fetch(context.Background(), url, cacheKey, nil)
 					Ω(ioutil.ReadFile(cacheFilePath)).Should(Equal([]byte(downloadContent)))
 				})
 
 				It("should return a readcloser pointing to the file", func() {
-					file, err := cache.Fetch(url, cacheKey)
+					file, err := cache.Fetch(context.Background(), url, cacheKey, nil)
 					Ω(err).ShouldNot(HaveOccurred())
 					Ω(ioutil.ReadAll(file)).Should(Equal([]byte(downloadContent)))
 				})
 
 				It("should have put the file in the cache", func() {
-					_, err := cache.Fetch(url, cacheKey)
+					_, err := cache.Fetch(context.Background(), url, cacheKey, nil)
 					Ω(err).ShouldNot(HaveOccurred())
 					Ω(ioutil.ReadDir(cachedPath)).Should(HaveLen(1))
 					Ω(ioutil.ReadDir(uncachedPath)).Should(HaveLen(0))
@@ -287,13 +298,13 @@ var _ = Describe("File cache", func() {
 				})
 
 				It("should return a readcloser pointing to the file", func() {
-					file, err := cache.Fetch(url, cacheKey)
+					file, err := cache.Fetch(context.Background(), url, cacheKey, nil)
 					Ω(err).ShouldNot(HaveOccurred())
 					Ω(ioutil.ReadAll(file)).Should(Equal([]byte(downloadContent)))
 				})
 
 				It("should have removed the file from the cache", func() {
-					_, err := cache.Fetch(url, cacheKey)
+					_, err := cache.Fetch(context.Background(), url, cacheKey, nil)
 					Ω(err).ShouldNot(HaveOccurred())
 					Ω(ioutil.ReadDir(cachedPath)).Should(HaveLen(0))
 					Ω(ioutil.ReadDir(uncachedPath)).Should(HaveLen(0))
@@ -306,13 +317,13 @@ var _ = Describe("File cache", func() {
 				})
 
 				It("should not redownload the file", func() {
-					_, err := cache.Fetch(url, cacheKey)
+					_, err := cache.Fetch(context.Background(), url, cacheKey, nil)
 					Ω(err).ShouldNot(HaveOccurred())
 					Ω(ioutil.ReadFile(cacheFilePath)).Should(Equal(fileContent))
 				})
 
 				It("should return a readcloser pointing to the file", func() {
-					file, err := cache.Fetch(url, cacheKey)
+					file, err := cache.Fetch(context.Background(), url, cacheKey, nil)
 					Ω(err).ShouldNot(HaveOccurred())
 					Ω(ioutil.ReadAll(file)).Should(Equal(fileContent))
 				})
@@ -328,7 +339,7 @@ var _ = Describe("File cache", func() {
 					ghttp.RespondWith(http.StatusOK, string(downloadContent), returnedHeader),
 				))
 
-				file, err = cache.Fetch(url, cacheKey)
+				file, err = cache.Fetch(context.Background(), url, cacheKey, nil)
 			})
 
 			It("should not error", func() {
@@ -358,7 +369,7 @@ var _ = Describe("File cache", func() {
 					ghttp.RespondWith(http.StatusOK, string(downloadContent), returnedHeader),
 				))
 
-				cachedFile, err := cache.Fetch(url, name)
+				cachedFile, err := cache.Fetch(context.Background(), url, name, nil)
 				Ω(err).ShouldNot(HaveOccurred())
 				Ω(ioutil.ReadAll(cachedFile)).Should(Equal(downloadContent))
 				cachedFile.Close()
@@ -2,10 +2,12 @@ package cacheddownloader
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
@@ -15,11 +17,55 @@ import (
 
 const MAX_DOWNLOAD_ATTEMPTS = 3
 
+// RemoteDownloader fetches url into destinationFile, unless cachingInfoIn
+// shows that the copy already cached is current. It gives up as soon as ctx
+// is done.
+type RemoteDownloader interface {
+	Download(ctx context.Context, url *url.URL, destinationFile *os.File, cachingInfoIn CachingInfoType, progress ProgressFunc) (didDownload bool, length int64, cachingInfoOut CachingInfoType, err error)
+}
+
+// ProgressFunc is called as a download is written, with the number of bytes
+// written so far and the expected total, or -1 if that is not known. A retry
+// starts counting from zero again.
+type ProgressFunc func(written int64, total int64)
+
+type progressWriter struct {
+	written  int64
+	total    int64
+	progress ProgressFunc
+}
+
+func NewProgressWriter(total int64, progress ProgressFunc) io.Writer {
+	if progress == nil {
+		return ioutil.Discard
+	}
+
+	return &progressWriter{
+		total:    total,
+		progress: progress,
+	}
+}
+
+func (writer *progressWriter) Write(p []byte) (int, error) {
+	writer.written += int64(len(p))
+	writer.progress(writer.written, writer.total)
+	return len(p), nil
+}
+
+// RequestPreparer gets to modify every request right before it is sent,
+// e.g. to point it at a different host and sign it.
+type RequestPreparer func(request *http.Request) error
+
 type Downloader struct {
-	client *http.Client
+	client  *http.Client
+	prepare RequestPreparer
 }
 
 func NewDownloader(timeout time.Duration) *Downloader {
+	return NewPreparingDownloader(timeout, nil)
+}
+
+func NewPreparingDownloader(timeout time.Duration, prepare RequestPreparer) *Downloader {
 	transport := &http.Transport{
 		ResponseHeaderTimeout: timeout,
 	}
@@ -28,14 +74,15 @@ func NewDownloader(timeout time.Duration) *Downloader {
 	}
 
 	return &Downloader{
-		client: client,
+		client:  client,
+		prepare: prepare,
 	}
 }
 
-func (downloader *Downloader) Download(url *url.URL, destinationFile *os.File, cachingInfoIn CachingInfoType) (didDownload bool, length int64, cachingInfoOut CachingInfoType, err error) {
+func (downloader *Downloader) Download(ctx context.Context, url *url.URL, destinationFile *os.File, cachingInfoIn CachingInfoType, progress ProgressFunc) (didDownload bool, length int64, cachingInfoOut CachingInfoType, err error) {
 	for attempt := 0; attempt < MAX_DOWNLOAD_ATTEMPTS; attempt++ {
-		didDownload, length, cachingInfoOut, err = downloader.fetchToFile(url, destinationFile, cachingInfoIn)
-		if err == nil {
+		didDownload, length, cachingInfoOut, err = downloader.fetchToFile(ctx, url, destinationFile, cachingInfoIn, progress)
+		if err == nil || ctx.Err() != nil {
 			break
 		}
 	}
@@ -46,7 +93,7 @@ func (downloader *Downloader) Download(url *url.URL, destinationFile *os.File, c
 	return
 }
 
-func (downloader *Downloader) fetchToFile(url *url.URL, destinationFile *os.File, cachingInfoIn CachingInfoType) (bool, int64, CachingInfoType, error) {
+func (downloader *Downloader) fetchToFile(ctx context.Context, url *url.URL, destinationFile *os.File, cachingInfoIn CachingInfoType, progress ProgressFunc) (bool, int64, CachingInfoType, error) {
 	_, err := destinationFile.Seek(0, 0)
 	if err != nil {
 		return false, 0, CachingInfoType{}, err
@@ -57,7 +104,7 @@ func (downloader *Downloader) fetchToFile(url *url.URL, destinationFile *os.File
 		return false, 0, CachingInfoType{}, err
 	}
 
-	req, err := http.NewRequest("GET", url.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url.String(), nil)
 	if err != nil {
 		return false, 0, CachingInfoType{}, err
 	}
@@ -69,6 +116,13 @@ func (downloader *Downloader) fetchToFile(url *url.URL, destinationFile *os.File
 		req.Header.Add("If-Modified-Since", cachingInfoIn.LastModified)
 	}
 
+	if downloader.prepare != nil {
+		err = downloader.prepare(req)
+		if err != nil {
+			return false, 0, CachingInfoType{}, err
+		}
+	}
+
 	resp, err := downloader.client.Do(req)
 	if err != nil {
 		return false, 0, CachingInfoType{}, err
@@ -86,7 +140,7 @@ func (downloader *Downloader) fetchToFile(url *url.URL, destinationFile *os.File
 
 	hash := md5.New()
 
-	count, err := io.Copy(io.MultiWriter(destinationFile, hash), resp.Body)
+	count, err := io.Copy(io.MultiWriter(destinationFile, hash, NewProgressWriter(resp.ContentLength, progress)), resp.Body)
 	if err != nil {
 		return false, 0, CachingInfoType{}, err
 	}
@@ -1,6 +1,7 @@
 package cacheddownloader_test
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
 	"io/ioutil"
@@ -70,7 +71,7 @@ var _ = Describe("Downloader", func() {
 			JustBeforeEach(func() {
 				serverUrl := testServer.URL + "/somepath"
 				url, _ = url.Parse(serverUrl)
-				didDownload, downloadSize, downloadCachingInfo, downloadErr = downloader.Download(url, file, CachingInfoType{})
+				didDownload, downloadSize, downloadCachingInfo, downloadErr = downloader.Download(context.Background(), url, file, CachingInfoType{}, nil)
 			})
 
 			Context("and contains a matching MD5 Hash in the Etag", func() {
@@ -190,7 +191,7 @@ var _ = Describe("Downloader", func() {
 				didDownloads := make(chan bool)
 
 				go func() {
-					didDownload, _, _, err := downloader.Download(url, file, CachingInfoType{})
+					didDownload, _, _, err := downloader.Download(context.Background(), url, file, CachingInfoType{}, nil)
 					errs <- err
 					didDownloads <- didDownload
 				}()
@@ -213,7 +214,7 @@ var _ = Describe("Downloader", func() {
 			})
 
 			It("should return the error", func() {
-				didDownload, _, _, err := downloader.Download(url, file, CachingInfoType{})
+				didDownload, _, _, err := downloader.Download(context.Background(), url, file, CachingInfoType{}, nil)
 				Ω(err).NotTo(BeNil())
 				Ω(didDownload).Should(BeFalse())
 			})
@@ -228,7 +229,7 @@ var _ = Describe("Downloader", func() {
 			})
 
 			It("should return the error", func() {
-				didDownload, _, _, err := downloader.Download(url, file, CachingInfoType{})
+				didDownload, _, _, err := downloader.Download(context.Background(), url, file, CachingInfoType{}, nil)
 				Ω(err).NotTo(BeNil())
 				Ω(didDownload).Should(BeFalse())
 			})
@@ -250,7 +251,7 @@ var _ = Describe("Downloader", func() {
 			})
 
 			It("should return an error", func() {
-				didDownload, _, cachingInfo, err := downloader.Download(url, file, CachingInfoType{})
+				didDownload, _, cachingInfo, err := downloader.Download(context.Background(), url, file, CachingInfoType{}, nil)
 				Ω(err).NotTo(BeNil())
 				Ω(didDownload).Should(BeFalse())
 				Ω(cachingInfo).Should(BeZero())
@@ -297,14 +298,14 @@ var _ = Describe("Downloader", func() {
 			})
 
 			It("should return that it did not download", func() {
-				didDownload, size, _, err := downloader.Download(url, file, cachedInfo)
+				didDownload, size, _, err := downloader.Download(context.Background(), url, file, cachedInfo, nil)
 				Ω(didDownload).Should(BeFalse())
 				Ω(size).Should(Equal(int64(0)))
 				Ω(err).ShouldNot(HaveOccurred())
 			})
 
 			It("should not download anything", func() {
-				downloader.Download(url, file, cachedInfo)
+				downloader.Download(context.Background(), url, file, cachedInfo, nil)
 				info, err := os.Stat(file.Name())
 				Ω(err).ShouldNot(HaveOccurred())
 				Ω(info.Size()).Should(Equal(int64(0)))
@@ -318,14 +319,14 @@ var _ = Describe("Downloader", func() {
 			})
 
 			It("should return that it did download and the file size", func() {
-				didDownload, size, _, err := downloader.Download(url, file, cachedInfo)
+				didDownload, size, _, err := downloader.Download(context.Background(), url, file, cachedInfo, nil)
 				Ω(didDownload).Should(BeTrue())
 				Ω(size).Should(Equal(int64(len(body))))
 				Ω(err).ShouldNot(HaveOccurred())
 			})
 
 			It("should download the file", func() {
-				downloader.Download(url, file, cachedInfo)
+				downloader.Download(context.Background(), url, file, cachedInfo, nil)
 				info, err := os.Stat(file.Name())
 				Ω(err).ShouldNot(HaveOccurred())
 				Ω(info.Size()).Should(Equal(int64(len(body))))
@@ -350,18 +351,94 @@ var _ = Describe("Downloader", func() {
 			})
 
 			It("should return false with an error", func() {
-				didDownload, size, _, err := downloader.Download(url, file, cachedInfo)
+				didDownload, size, _, err := downloader.Download(context.Background(), url, file, cachedInfo, nil)
 				Ω(didDownload).Should(BeFalse())
 				Ω(size).Should(Equal(int64(0)))
 				Ω(err).Should(HaveOccurred())
 			})
 
 			It("should not download anything", func() {
-				downloader.Download(url, file, cachedInfo)
+				downloader.Download(context.Background(), url, file, cachedInfo, nil)
 				info, err := os.Stat(file.Name())
 				Ω(err).ShouldNot(HaveOccurred())
 				Ω(info.Size()).Should(Equal(int64(0)))
 			})
 		})
 	})
+
+	Context("with a request preparer", func() {
+		var (
+			server *ghttp.Server
+			file   *os.File
+		)
+
+		BeforeEach(func() {
+			file, _ = ioutil.TempFile("", "foo")
+			server = ghttp.NewServer()
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/bucket/the-file"),
+				ghttp.VerifyHeader(http.Header{
+					"Authorization": []string{"signed"},
+				}),
+				ghttp.RespondWith(http.StatusOK, "quarb!"),
+			))
+
+			serverUrl, _ := Url.Parse(server.URL())
+
+			downloader = NewPreparingDownloader(100*time.Millisecond, func(request *http.Request) error {
+				request.URL.Scheme = serverUrl.Scheme
+				request.URL.Host = serverUrl.Host
+				request.URL.Path = "/bucket" + request.URL.Path
+				request.Header.Set("Authorization", "signed")
+				return nil
+			})
+		})
+
+		AfterEach(func() {
+			file.Close()
+			server.Close()
+		})
+
+		It("sends the prepared request", func() {
+			url, _ := Url.Parse("s3://bucket/the-file")
+
+			didDownload, size, _, err := downloader.Download(context.Background(), url, file, CachingInfoType{}, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(didDownload).Should(BeTrue())
+			Ω(size).Should(Equal(int64(len("quarb!"))))
+		})
+	})
+
+	Context("with a progress func", func() {
+		var (
+			server *ghttp.Server
+			file   *os.File
+		)
+
+		BeforeEach(func() {
+			file, _ = ioutil.TempFile("", "foo")
+			server = ghttp.NewServer()
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, "quarb!"))
+		})
+
+		AfterEach(func() {
+			file.Close()
+			server.Close()
+		})
+
+		It("reports the bytes written and the expected total", func() {
+			url, _ := Url.Parse(server.URL() + "/the-file")
+
+			var written, total int64
+
+			_, _, _, err := downloader.Download(context.Background(), url, file, CachingInfoType{}, func(w int64, t int64) {
+				written = w
+				total = t
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(written).Should(Equal(int64(len("quarb!"))))
+			Ω(total).Should(Equal(int64(len("quarb!"))))
+		})
+	})
 })
@@ -2,22 +2,30 @@ package fakecacheddownloader
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/url"
+
+	"github.com/pivotal-golang/cacheddownloader"
 )
 
 type FakeCachedDownloader struct {
+	FetchedContext  context.Context
 	FetchedURL      *url.URL
 	FetchedCacheKey string
 	FetchedContent  []byte
 	FetchError      error
+	FetchProgress   []int64
+
+	RemovedCacheKey string
 }
 
 func New() *FakeCachedDownloader {
 	return &FakeCachedDownloader{}
 }
 
-func (c *FakeCachedDownloader) Fetch(url *url.URL, cacheKey string) (io.ReadCloser, error) {
+func (c *FakeCachedDownloader) Fetch(ctx context.Context, url *url.URL, cacheKey string, progress cacheddownloader.ProgressFunc) (io.ReadCloser, error) {
+	c.FetchedContext = ctx
 	c.FetchedURL = url
 	c.FetchedCacheKey = cacheKey
 
@@ -25,9 +33,19 @@ func (c *FakeCachedDownloader) Fetch(url *url.URL, cacheKey string) (io.ReadClos
 		return nil, c.FetchError
 	}
 
+	if progress != nil {
+		for _, written := range c.FetchProgress {
+			progress(written, int64(len(c.FetchedContent)))
+		}
+	}
+
 	return &readCloser{bytes.NewBuffer(c.FetchedContent)}, c.FetchError
 }
 
+func (c *FakeCachedDownloader) Remove(cacheKey string) {
+	c.RemovedCacheKey = cacheKey
+}
+
 type readCloser struct {
 	buffer *bytes.Buffer
 }
@@ -39,3 +57,5 @@ func (r *readCloser) Read(p []byte) (n int, err error) {
 func (r *readCloser) Close() error {
 	return nil
 }
+
+var _ cacheddownloader.CachedDownloader = &FakeCachedDownloader{}
@@ -1,6 +1,7 @@
 package cacheddownloader_test
 
 import (
+	"context"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -57,7 +58,7 @@ var _ = Describe("Integration", func() {
 		url, err := url.Parse(server.URL + "/file")
 		Ω(err).ShouldNot(HaveOccurred())
 
-		reader, err := downloader.Fetch(url, "the-cache-key")
+		reader, err := downloader.Fetch(context.Background(), url, "the-cache-key", nil)
 		Ω(err).ShouldNot(HaveOccurred())
 
 		readData, err := ioutil.ReadAll(reader)
@@ -4,6 +4,7 @@ type Client interface {
 	Ping() error
 	AllocateContainer(allocationGuid string, request ContainerAllocationRequest) (Container, error)
 	GetContainer(allocationGuid string) (Container, error)
+	GetContainerMetrics(allocationGuid string) (ContainerMetrics, error)
 	InitializeContainer(allocationGuid string, request ContainerInitializationRequest) (Container, error)
 	Run(allocationGuid string, request ContainerRunRequest) error
 	DeleteContainer(allocationGuid string) error
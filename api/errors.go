@@ -42,4 +42,6 @@ var (
 	ErrDeleteInProgress               = registerError("DeleteInProgress", "delete in progress", http.StatusConflict)
 	ErrStepsInvalid                   = registerError("StepsInvalid", "steps invalid", http.StatusBadRequest)
 	ErrLimitsInvalid                  = registerError("LimitsInvalid", "container limits invalid", http.StatusBadRequest)
+	ErrArtifactsInvalid               = registerError("ArtifactsInvalid", "artifacts invalid", http.StatusBadRequest)
+	ErrLogConfigInvalid               = registerError("LogConfigInvalid", "log config invalid", http.StatusBadRequest)
 )
@@ -33,6 +33,15 @@ type FakeClient struct {
 		result1 api.Container
 		result2 error
 	}
+	GetContainerMetricsStub        func(allocationGuid string) (api.ContainerMetrics, error)
+	getContainerMetricsMutex       sync.RWMutex
+	getContainerMetricsArgsForCall []struct {
+		allocationGuid string
+	}
+	getContainerMetricsReturns struct {
+		result1 api.ContainerMetrics
+		result2 error
+	}
 	InitializeContainerStub        func(allocationGuid string, request api.ContainerInitializationRequest) (api.Container, error)
 	initializeContainerMutex       sync.RWMutex
 	initializeContainerArgsForCall []struct {
@@ -174,6 +183,39 @@ func (fake *FakeClient) GetContainerReturns(result1 api.Container, result2 error
 	}{result1, result2}
 }
 
+func (fake *FakeClient) GetContainerMetrics(allocationGuid string) (api.ContainerMetrics, error) {
+	fake.getContainerMetricsMutex.Lock()
+	fake.getContainerMetricsArgsForCall = append(fake.getContainerMetricsArgsForCall, struct {
+		allocationGuid string
+	}{allocationGuid})
+	fake.getContainerMetricsMutex.Unlock()
+	if fake.GetContainerMetricsStub != nil {
+		return fake.GetContainerMetricsStub(allocationGuid)
+	} else {
+		return fake.getContainerMetricsReturns.result1, fake.getContainerMetricsReturns.result2
+	}
+}
+
+func (fake *FakeClient) GetContainerMetricsCallCount() int {
+	fake.getContainerMetricsMutex.RLock()
+	defer fake.getContainerMetricsMutex.RUnlock()
+	return len(fake.getContainerMetricsArgsForCall)
+}
+
+func (fake *FakeClient) GetContainerMetricsArgsForCall(i int) string {
+	fake.getContainerMetricsMutex.RLock()
+	defer fake.getContainerMetricsMutex.RUnlock()
+	return fake.getContainerMetricsArgsForCall[i].allocationGuid
+}
+
+func (fake *FakeClient) GetContainerMetricsReturns(result1 api.ContainerMetrics, result2 error) {
+	fake.GetContainerMetricsStub = nil
+	fake.getContainerMetricsReturns = struct {
+		result1 api.ContainerMetrics
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeClient) InitializeContainer(allocationGuid string, request api.ContainerInitializationRequest) (api.Container, error) {
 	fake.initializeContainerMutex.Lock()
 	fake.initializeContainerArgsForCall = append(fake.initializeContainerArgsForCall, struct {
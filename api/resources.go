@@ -1,6 +1,9 @@
 package api
 
 import (
+	"net"
+	"time"
+
 	"github.com/cloudfoundry-incubator/runtime-schema/models"
 	"github.com/tedsuo/ifrit"
 )
@@ -13,6 +16,32 @@ const (
 	StateDeleting     = "deleting"
 )
 
+const (
+	HealthUnknown   = "unknown"
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
+
+const (
+	StepPending   = "pending"
+	StepRunning   = "running"
+	StepSucceeded = "succeeded"
+	StepFailed    = "failed"
+	StepCancelled = "cancelled"
+)
+
+// MaxHealthTransitions is how many of its most recent transitions a
+// container's Health keeps.
+const MaxHealthTransitions = 10
+
+// MaxUsageSamples is how many of its most recent usage samples a container
+// keeps.
+const MaxUsageSamples = 60
+
+// OutOfMemoryEvent is the warden event raised when a container's processes
+// run out of memory.
+const OutOfMemoryEvent = "out of memory"
+
 type Container struct {
 	Guid string `json:"guid"`
 
@@ -28,11 +57,31 @@ type Container struct {
 	Ports      []PortMapping `json:"ports"`
 	Log        LogConfig     `json:"log"`
 
+	// in bytes per second; zero if unlimited
+	BandwidthRate  uint64 `json:"bandwidth_rate,omitempty"`
+	BandwidthBurst uint64 `json:"bandwidth_burst,omitempty"`
+
+	// the executor's default egress rules, then those requested
+	EgressRules []EgressRule `json:"egress_rules,omitempty"`
+
 	// run
 	Actions []models.ExecutorAction `json:"actions"`
 	Env     []EnvironmentVariable   `json:"env,omitempty"`
 
 	RunResult ContainerRunResult `json:"run_result"`
+	Transfers TransferProgress   `json:"transfers"`
+	Health    *Health            `json:"health,omitempty"`
+	Steps     []StepProgress     `json:"steps,omitempty"`
+
+	// lines dropped by the container's log rate limit
+	LogLinesDropped int64 `json:"log_lines_dropped,omitempty"`
+
+	// warden events seen while running, such as OutOfMemoryEvent
+	Events    []string `json:"events,omitempty"`
+	OOMKilled bool     `json:"oom_killed,omitempty"`
+
+	// served separately, as ContainerMetrics
+	Usage []UsageSample `json:"-"`
 
 	// internally updated
 	State           string        `json:"state"`
@@ -40,15 +89,45 @@ type Container struct {
 	Process         ifrit.Process `json:"-"`
 }
 
+// EnvironmentVariable values marked Secret are masked in logs and results,
+// and left out when containers are listed.
 type EnvironmentVariable struct {
-	Name  string `json:"name"`
-	Value string `json:"value"`
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// WithoutSecrets is container as it may be shown to API clients.
+func (container Container) WithoutSecrets() Container {
+	if container.Env == nil {
+		return container
+	}
+
+	env := []EnvironmentVariable{}
+	for _, e := range container.Env {
+		if !e.Secret {
+			env = append(env, e)
+		}
+	}
+
+	container.Env = env
+	return container
 }
 
 type LogConfig struct {
-	Guid       string `json:"guid"`
-	SourceName string `json:"source_name"`
-	Index      *int   `json:"index"`
+	Guid       string           `json:"guid"`
+	SourceName string           `json:"source_name"`
+	Index      *int             `json:"index"`
+	MultiLine  *MultiLineConfig `json:"multi_line,omitempty"`
+}
+
+// MultiLineConfig has lines that continue the one before them, like the
+// frames of a stack trace, logged together with it as one message. Lines
+// matching ContinuationPattern continue; without one, indented lines do.
+type MultiLineConfig struct {
+	ContinuationPattern string        `json:"continuation_pattern,omitempty"`
+	MaxSizeInBytes      int           `json:"max_size_in_bytes,omitempty"`
+	FlushTimeout        time.Duration `json:"flush_timeout,omitempty"`
 }
 
 type PortMapping struct {
@@ -62,24 +141,164 @@ type ContainerAllocationRequest struct {
 }
 
 type ContainerInitializationRequest struct {
-	CpuPercent float64       `json:"cpu_percent"`
-	Ports      []PortMapping `json:"ports"`
-	Log        LogConfig     `json:"log"`
-	RootFSPath string        `json:"root_fs"`
+	CpuPercent  float64       `json:"cpu_percent"`
+	Ports       []PortMapping `json:"ports"`
+	Log         LogConfig     `json:"log"`
+	RootFSPath  string        `json:"root_fs"`
+	EgressRules []EgressRule  `json:"egress_rules,omitempty"`
+
+	// BandwidthRate and BandwidthBurst are in bytes per second; zero takes
+	// the executor's default.
+	BandwidthRate  uint64 `json:"bandwidth_rate,omitempty"`
+	BandwidthBurst uint64 `json:"bandwidth_burst,omitempty"`
+}
+
+// EgressProtocolAll opens a rule to every protocol. Warden cannot narrow
+// net-out by protocol, so it is the only protocol a rule may name.
+const EgressProtocolAll = "all"
+
+// EgressRule lets a container reach Network, a CIDR, on Port; a zero Port
+// allows every port. An empty Protocol is the same as EgressProtocolAll.
+type EgressRule struct {
+	Network  string `json:"network"`
+	Port     uint32 `json:"port,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// Valid says whether the rule's network is a well-formed CIDR, its port fits
+// in 16 bits and its protocol is one warden can enforce.
+func (rule EgressRule) Valid() bool {
+	if rule.Port > 65535 {
+		return false
+	}
+
+	switch rule.Protocol {
+	case "", EgressProtocolAll:
+	default:
+		return false
+	}
+
+	_, _, err := net.ParseCIDR(rule.Network)
+	return err == nil
 }
 
 type ContainerRunRequest struct {
 	Actions     []models.ExecutorAction `json:"actions"`
 	Env         []EnvironmentVariable   `json:"env,omitempty"`
+	Artifacts   []Artifact              `json:"artifacts,omitempty"`
 	CompleteURL string                  `json:"complete_url"`
+
+	// EventURL, if set, is sent a ContainerEvent as soon as the container
+	// runs out of memory, whether or not its process has exited.
+	EventURL string `json:"event_url,omitempty"`
+}
+
+// ContainerEvent is a warden event seen while a container was running.
+type ContainerEvent struct {
+	Guid  string `json:"guid"`
+	Event string `json:"event"`
+}
+
+// Artifact names files to collect from the container once the run is over,
+// whether it succeeded or not. Everything matching the glob in Path is
+// uploaded to To as a single .tgz.
+type Artifact struct {
+	Path string `json:"path"`
+	To   string `json:"to"`
 }
 
 type ContainerRunResult struct {
 	Guid string `json:"guid"`
 
-	Failed        bool   `json:"failed"`
-	FailureReason string `json:"failure_reason"`
-	Result        string `json:"result"`
+	Failed        bool              `json:"failed"`
+	FailureReason string            `json:"failure_reason"`
+	Result        string            `json:"result"`
+	Results       map[string]string `json:"results,omitempty"`
+	Artifacts     []ArtifactResult  `json:"artifacts,omitempty"`
+	Steps         []StepProgress    `json:"steps,omitempty"`
+
+	// ExitStatus is that of the last process run, if any exited; of processes
+	// run in parallel, the first to exit non-zero wins, and processes run to
+	// check a container's health are not counted. Events are the warden events
+	// seen during the run; OOMKilled is set if one was OutOfMemoryEvent.
+	ExitStatus *int     `json:"exit_status,omitempty"`
+	OOMKilled  bool     `json:"oom_killed"`
+	Events     []string `json:"events,omitempty"`
+
+	// CleanupFailed is set if any step could not be cleaned up after it ran,
+	// which leaves Failed as it was; CleanupErrors says what went wrong.
+	CleanupFailed bool     `json:"cleanup_failed,omitempty"`
+	CleanupErrors []string `json:"cleanup_errors,omitempty"`
+}
+
+type ArtifactResult struct {
+	Path  string `json:"path"`
+	URL   string `json:"url,omitempty"`
+	Files int    `json:"files"`
+	Error string `json:"error,omitempty"`
+}
+
+// StepProgress is where one step of a run is at. Its Steps are those of the
+// actions nested in it, so together they mirror the run's actions. Times are
+// in nanoseconds since the epoch, like AllocatedAt.
+type StepProgress struct {
+	Action    string         `json:"action"`
+	Status    string         `json:"status"`
+	StartedAt int64          `json:"started_at,omitempty"`
+	EndedAt   int64          `json:"ended_at,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	Steps     []StepProgress `json:"steps,omitempty"`
+}
+
+// UsageSample is what a container was using when warden was last asked.
+// CPUUsage is the total CPU time it has used, in nanoseconds; CPUPercent is
+// how much of a core it used since the sample before. The bandwidth fields
+// are the rates and bursts warden reports shaping the container's traffic
+// to, in bytes per second.
+type UsageSample struct {
+	Time        int64   `json:"time"`
+	MemoryBytes uint64  `json:"memory_bytes"`
+	DiskBytes   uint64  `json:"disk_bytes"`
+	DiskInodes  uint64  `json:"disk_inodes"`
+	CPUUsage    uint64  `json:"cpu_usage"`
+	CPUPercent  float64 `json:"cpu_percent"`
+
+	BandwidthInRate   uint64 `json:"bandwidth_in_rate"`
+	BandwidthInBurst  uint64 `json:"bandwidth_in_burst"`
+	BandwidthOutRate  uint64 `json:"bandwidth_out_rate"`
+	BandwidthOutBurst uint64 `json:"bandwidth_out_burst"`
+}
+
+// ContainerMetrics is a container's latest usage, and what it used before,
+// oldest first.
+type ContainerMetrics struct {
+	Guid    string        `json:"guid"`
+	Current *UsageSample  `json:"current,omitempty"`
+	History []UsageSample `json:"history"`
+}
+
+type TransferProgress struct {
+	BytesDownloaded int64 `json:"bytes_downloaded"`
+	BytesUploaded   int64 `json:"bytes_uploaded"`
+}
+
+// Health is what the monitor action running in a container last found out.
+// Containers without a monitor have none.
+type Health struct {
+	Status         string `json:"status"`
+	HealthyCount   uint   `json:"healthy_count"`
+	UnhealthyCount uint   `json:"unhealthy_count"`
+	LastCheckError string `json:"last_check_error,omitempty"`
+	LastHookError  string `json:"last_hook_error,omitempty"`
+
+	LastTransitionAt int64              `json:"last_transition_at"`
+	Transitions      []HealthTransition `json:"transitions,omitempty"`
+}
+
+type HealthTransition struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	At   int64  `json:"at"`
 }
 
 type ExecutorResources struct {
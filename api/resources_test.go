@@ -0,0 +1,36 @@
+package api_test
+
+import (
+	. "github.com/cloudfoundry-incubator/executor/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EgressRule", func() {
+	Describe("Valid", func() {
+		rules := []struct {
+			description string
+			rule        EgressRule
+			valid       bool
+		}{
+			{"a bare CIDR", EgressRule{Network: "10.0.0.0/8"}, true},
+			{"a CIDR with a port", EgressRule{Network: "10.0.0.0/8", Port: 53}, true},
+			{"the highest port", EgressRule{Network: "10.0.0.0/8", Port: 65535}, true},
+			{"every protocol", EgressRule{Network: "10.0.0.0/8", Protocol: EgressProtocolAll}, true},
+			{"a port above 65535", EgressRule{Network: "10.0.0.0/8", Port: 65536}, false},
+			{"a protocol warden cannot enforce", EgressRule{Network: "10.0.0.0/8", Protocol: "tcp"}, false},
+			{"an unknown protocol", EgressRule{Network: "10.0.0.0/8", Protocol: "bogus"}, false},
+			{"a bare IP", EgressRule{Network: "10.0.0.1"}, false},
+			{"no network", EgressRule{}, false},
+		}
+
+		for _, r := range rules {
+			r := r
+
+			It("says whether "+r.description+" is valid", func() {
+				Ω(r.rule.Valid()).Should(Equal(r.valid))
+			})
+		}
+	})
+})
@@ -5,6 +5,7 @@ import "github.com/tedsuo/rata"
 const (
 	Ping                  = "Ping"
 	GetContainer          = "GetContainer"
+	GetContainerMetrics   = "GetContainerMetrics"
 	AllocateContainer     = "AllocateContainer"
 	InitializeContainer   = "InitializeContainer"
 	RunActions            = "RunActions"
@@ -18,6 +19,7 @@ var Routes = rata.Routes{
 	{Path: "/ping", Method: "GET", Name: Ping},
 	{Path: "/containers", Method: "GET", Name: ListContainers},
 	{Path: "/containers/:guid", Method: "GET", Name: GetContainer},
+	{Path: "/containers/:guid/metrics", Method: "GET", Name: GetContainerMetrics},
 	{Path: "/containers/:guid", Method: "POST", Name: AllocateContainer},
 	{Path: "/containers/:guid/initialize", Method: "POST", Name: InitializeContainer},
 	{Path: "/containers/:guid/run", Method: "POST", Name: RunActions},
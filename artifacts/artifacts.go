@@ -0,0 +1,246 @@
+package artifacts
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/executor/api"
+	"github.com/cloudfoundry-incubator/executor/cancellable"
+	"github.com/cloudfoundry-incubator/executor/uploader"
+	"github.com/cloudfoundry-incubator/garden/warden"
+	"github.com/pivotal-golang/lager"
+)
+
+var ErrNoMatchingFiles = errors.New("no files matched")
+var ErrGlobAtRoot = errors.New("artifact glob must not match at the root of the container")
+
+type Collector interface {
+	Collect(ctx context.Context, container warden.Container, artifacts []api.Artifact) []api.ArtifactResult
+}
+
+type collector struct {
+	uploader uploader.Uploader
+	logger   lager.Logger
+}
+
+func New(uploader uploader.Uploader, logger lager.Logger) Collector {
+	return &collector{
+		uploader: uploader,
+		logger:   logger.Session("artifacts"),
+	}
+}
+
+func Validate(artifacts []api.Artifact) error {
+	for _, artifact := range artifacts {
+		if !path.IsAbs(artifact.Path) {
+			return errors.New("artifact path must be absolute: " + artifact.Path)
+		}
+
+		_, err := path.Match(artifact.Path, "")
+		if err != nil {
+			return err
+		}
+
+		_, _, err = streamSource(path.Clean(artifact.Path))
+		if err != nil {
+			return err
+		}
+
+		_, err = url.ParseRequestURI(artifact.To)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Collect uploads every artifact it can. A failure to collect one is noted
+// in its result and does not stop the others, but once ctx is done the
+// remaining artifacts all fail with its error.
+func (c *collector) Collect(ctx context.Context, container warden.Container, artifacts []api.Artifact) []api.ArtifactResult {
+	results := make([]api.ArtifactResult, len(artifacts))
+
+	for i, artifact := range artifacts {
+		results[i] = api.ArtifactResult{
+			Path: artifact.Path,
+		}
+
+		files, err := c.collect(ctx, container, artifact)
+		results[i].Files = files
+
+		if err != nil {
+			c.logger.Error("failed-to-collect", err, lager.Data{
+				"handle": container.Handle(),
+				"path":   artifact.Path,
+			})
+
+			results[i].Error = err.Error()
+			continue
+		}
+
+		results[i].URL = artifact.To
+	}
+
+	return results
+}
+
+func (c *collector) collect(ctx context.Context, container warden.Container, artifact api.Artifact) (int, error) {
+	destination, err := url.ParseRequestURI(artifact.To)
+	if err != nil {
+		return 0, err
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		return 0, err
+	}
+
+	pattern := path.Clean(artifact.Path)
+
+	source, root, err := streamSource(pattern)
+	if err != nil {
+		return 0, err
+	}
+
+	streamOut, err := container.StreamOut(source)
+	if err != nil {
+		return 0, err
+	}
+	defer streamOut.Close()
+
+	stopClosing := cancellable.CloseOnDone(ctx, streamOut)
+	defer stopClosing()
+
+	reader, writer := io.Pipe()
+
+	archiveResult := make(chan archiveOutcome, 1)
+
+	go func() {
+		files, err := writeMatching(writer, streamOut, root, pattern)
+		if err == nil && files == 0 {
+			err = ErrNoMatchingFiles
+		}
+
+		writer.CloseWithError(err)
+		archiveResult <- archiveOutcome{files, err}
+	}()
+
+	_, uploadErr := c.uploader.Upload(ctx, reader, destination, c.logger)
+
+	reader.Close()
+
+	outcome := <-archiveResult
+	if outcome.err != nil && outcome.err != io.ErrClosedPipe {
+		return outcome.files, outcome.err
+	}
+
+	return outcome.files, uploadErr
+}
+
+type archiveOutcome struct {
+	files int
+	err   error
+}
+
+// writeMatching copies the entries of tarStream whose path matches pattern,
+// along with anything underneath a matching directory, into a tgz.
+func writeMatching(destination io.Writer, tarStream io.Reader, root string, pattern string) (int, error) {
+	gzipWriter := gzip.NewWriter(destination)
+	tarWriter := tar.NewWriter(gzipWriter)
+	tarReader := tar.NewReader(tarStream)
+
+	files := 0
+	matchedDirs := []string{}
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return files, err
+		}
+
+		relative := path.Clean(header.Name)
+		if relative == "." {
+			continue
+		}
+
+		fullPath := path.Join(root, relative)
+
+		matched, _ := path.Match(pattern, fullPath)
+		if !matched && !isUnderAny(fullPath, matchedDirs) {
+			continue
+		}
+
+		if header.FileInfo().IsDir() {
+			matchedDirs = append(matchedDirs, fullPath)
+		} else {
+			files++
+		}
+
+		header.Name = relative
+		if header.FileInfo().IsDir() {
+			header.Name += "/"
+		}
+
+		err = tarWriter.WriteHeader(header)
+		if err != nil {
+			return files, err
+		}
+
+		_, err = io.Copy(tarWriter, tarReader)
+		if err != nil {
+			return files, err
+		}
+	}
+
+	err := tarWriter.Close()
+	if err != nil {
+		return files, err
+	}
+
+	return files, gzipWriter.Close()
+}
+
+// streamSource is what to stream out of the container to find the matches
+// of pattern, and the directory the streamed entries are relative to. A
+// plain path is streamed out by itself. A glob needs the directory holding
+// its non-wildcard prefix, with a trailing slash so that its contents are
+// streamed rather than the directory itself; that is never the root, which
+// would stream the whole filesystem.
+func streamSource(pattern string) (string, string, error) {
+	wildcard := strings.IndexAny(pattern, `*?[\`)
+	if wildcard < 0 {
+		if pattern == "/" {
+			return "", "", ErrGlobAtRoot
+		}
+
+		return pattern, path.Dir(pattern), nil
+	}
+
+	root := path.Dir(pattern[:wildcard])
+	if root == "/" {
+		return "", "", ErrGlobAtRoot
+	}
+
+	return root + "/", root, nil
+}
+
+func isUnderAny(fullPath string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(fullPath, dir+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,304 @@
+package artifacts_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/url"
+
+	"github.com/cloudfoundry-incubator/executor/api"
+	. "github.com/cloudfoundry-incubator/executor/artifacts"
+	"github.com/cloudfoundry-incubator/executor/uploader/fake_uploader"
+	"github.com/cloudfoundry-incubator/garden/client/fake_warden_client"
+	"github.com/cloudfoundry-incubator/garden/warden"
+	"github.com/pivotal-golang/lager"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type entry struct {
+	name     string
+	contents string
+	dir      bool
+}
+
+func tarOf(entries ...entry) io.ReadCloser {
+	buffer := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(buffer)
+
+	for _, e := range entries {
+		header := &tar.Header{
+			Name:     e.name,
+			Mode:     0644,
+			Size:     int64(len(e.contents)),
+			Typeflag: tar.TypeReg,
+		}
+
+		if e.dir {
+			header.Mode = 0755
+			header.Typeflag = tar.TypeDir
+		}
+
+		err := tarWriter.WriteHeader(header)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, err = tarWriter.Write([]byte(e.contents))
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+
+	err := tarWriter.Close()
+	Ω(err).ShouldNot(HaveOccurred())
+
+	return ioutil.NopCloser(buffer)
+}
+
+func readTgz(payload []byte) map[string]string {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(payload))
+	Ω(err).ShouldNot(HaveOccurred())
+
+	tarReader := tar.NewReader(gzipReader)
+
+	contents := map[string]string{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		Ω(err).ShouldNot(HaveOccurred())
+
+		body, err := ioutil.ReadAll(tarReader)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		contents[header.Name] = string(body)
+	}
+
+	return contents
+}
+
+var _ = Describe("Artifacts", func() {
+	var (
+		wardenClient *fake_warden_client.FakeClient
+		container    warden.Container
+		fakeUploader *fake_uploader.FakeUploader
+		collector    Collector
+
+		uploadedTo       []string
+		uploadedPayloads [][]byte
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		wardenClient = fake_warden_client.New()
+		wardenClient.Connection.CreateReturns("some-handle", nil)
+
+		container, err = wardenClient.Create(warden.ContainerSpec{})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		uploadedTo = []string{}
+		uploadedPayloads = [][]byte{}
+
+		fakeUploader = &fake_uploader.FakeUploader{}
+		fakeUploader.UploadStub = func(ctx context.Context, source io.Reader, destination *url.URL, logger lager.Logger) (int64, error) {
+			payload, err := ioutil.ReadAll(source)
+			if err != nil {
+				return 0, err
+			}
+
+			uploadedTo = append(uploadedTo, destination.String())
+			uploadedPayloads = append(uploadedPayloads, payload)
+
+			return int64(len(payload)), nil
+		}
+
+		collector = New(fakeUploader, lagertest.NewTestLogger("test"))
+	})
+
+	Describe("Collect", func() {
+		Context("when the path is a glob", func() {
+			BeforeEach(func() {
+				wardenClient.Connection.StreamOutStub = func(handle string, src string) (io.ReadCloser, error) {
+					Ω(src).Should(Equal("/var/log/"))
+
+					return tarOf(
+						entry{name: "./", dir: true},
+						entry{name: "./app.log", contents: "app"},
+						entry{name: "./app.err", contents: "err"},
+						entry{name: "./crash.log", contents: "crash"},
+					), nil
+				}
+			})
+
+			It("uploads the matching files as a tgz and reports where they went", func() {
+				results := collector.Collect(context.Background(), container, []api.Artifact{
+					{Path: "/var/log/*.log", To: "http://example.com/logs"},
+				})
+
+				Ω(results).Should(Equal([]api.ArtifactResult{
+					{Path: "/var/log/*.log", URL: "http://example.com/logs", Files: 2},
+				}))
+
+				Ω(uploadedTo).Should(Equal([]string{"http://example.com/logs"}))
+				Ω(readTgz(uploadedPayloads[0])).Should(Equal(map[string]string{
+					"app.log":   "app",
+					"crash.log": "crash",
+				}))
+			})
+		})
+
+		Context("when the path is a directory", func() {
+			BeforeEach(func() {
+				wardenClient.Connection.StreamOutStub = func(handle string, src string) (io.ReadCloser, error) {
+					Ω(src).Should(Equal("/tmp/reports"))
+
+					return tarOf(
+						entry{name: "reports/", dir: true},
+						entry{name: "reports/junit.xml", contents: "<testsuite/>"},
+					), nil
+				}
+			})
+
+			It("uploads everything underneath it", func() {
+				results := collector.Collect(context.Background(), container, []api.Artifact{
+					{Path: "/tmp/reports", To: "http://example.com/reports"},
+				})
+
+				Ω(results[0].Files).Should(Equal(1))
+				Ω(results[0].Error).Should(BeEmpty())
+
+				Ω(readTgz(uploadedPayloads[0])).Should(Equal(map[string]string{
+					"reports/":          "",
+					"reports/junit.xml": "<testsuite/>",
+				}))
+			})
+		})
+
+		Context("when the glob is at the root of the container", func() {
+			It("refuses it without streaming anything out", func() {
+				results := collector.Collect(context.Background(), container, []api.Artifact{
+					{Path: "/*.log", To: "http://example.com/logs"},
+				})
+
+				Ω(results).Should(Equal([]api.ArtifactResult{
+					{Path: "/*.log", Error: ErrGlobAtRoot.Error()},
+				}))
+
+				Ω(wardenClient.Connection.StreamOutCallCount()).Should(Equal(0))
+				Ω(fakeUploader.UploadCallCount()).Should(Equal(0))
+			})
+		})
+
+		Context("when a plain path is at the root of the container", func() {
+			BeforeEach(func() {
+				wardenClient.Connection.StreamOutStub = func(handle string, src string) (io.ReadCloser, error) {
+					Ω(src).Should(Equal("/core"))
+
+					return tarOf(entry{name: "core", contents: "dump"}), nil
+				}
+			})
+
+			It("streams out only that path", func() {
+				results := collector.Collect(context.Background(), container, []api.Artifact{
+					{Path: "/core", To: "http://example.com/core"},
+				})
+
+				Ω(results[0].Files).Should(Equal(1))
+				Ω(readTgz(uploadedPayloads[0])).Should(Equal(map[string]string{
+					"core": "dump",
+				}))
+			})
+		})
+
+		Context("when nothing matches", func() {
+			BeforeEach(func() {
+				wardenClient.Connection.StreamOutStub = func(handle string, src string) (io.ReadCloser, error) {
+					return tarOf(entry{name: "./other", contents: "other"}), nil
+				}
+			})
+
+			It("reports it without a URL", func() {
+				results := collector.Collect(context.Background(), container, []api.Artifact{
+					{Path: "/tmp/core*", To: "http://example.com/core"},
+				})
+
+				Ω(results).Should(Equal([]api.ArtifactResult{
+					{Path: "/tmp/core*", Error: ErrNoMatchingFiles.Error()},
+				}))
+			})
+		})
+
+		Context("when streaming out fails", func() {
+			BeforeEach(func() {
+				wardenClient.Connection.StreamOutStub = func(handle string, src string) (io.ReadCloser, error) {
+					if src == "/missing/core" {
+						return nil, errors.New("no such file")
+					}
+
+					return tarOf(entry{name: "core", contents: "core"}), nil
+				}
+			})
+
+			It("reports the error and still collects the other artifacts", func() {
+				results := collector.Collect(context.Background(), container, []api.Artifact{
+					{Path: "/missing/core", To: "http://example.com/missing"},
+					{Path: "/tmp/core", To: "http://example.com/core"},
+				})
+
+				Ω(results).Should(Equal([]api.ArtifactResult{
+					{Path: "/missing/core", Error: "no such file"},
+					{Path: "/tmp/core", URL: "http://example.com/core", Files: 1},
+				}))
+			})
+		})
+
+		Context("when the upload fails", func() {
+			BeforeEach(func() {
+				wardenClient.Connection.StreamOutStub = func(handle string, src string) (io.ReadCloser, error) {
+					return tarOf(entry{name: "core", contents: "core"}), nil
+				}
+
+				fakeUploader.UploadStub = func(ctx context.Context, source io.Reader, destination *url.URL, logger lager.Logger) (int64, error) {
+					return 0, errors.New("upload failed")
+				}
+			})
+
+			It("reports the error", func() {
+				results := collector.Collect(context.Background(), container, []api.Artifact{
+					{Path: "/tmp/core", To: "http://example.com/core"},
+				})
+
+				Ω(results[0].URL).Should(BeEmpty())
+				Ω(results[0].Error).Should(Equal("upload failed"))
+			})
+		})
+	})
+
+	Describe("Validate", func() {
+		It("accepts absolute globs with upload URLs", func() {
+			Ω(Validate([]api.Artifact{{Path: "/tmp/*.log", To: "http://example.com"}})).ShouldNot(HaveOccurred())
+		})
+
+		It("rejects relative paths", func() {
+			Ω(Validate([]api.Artifact{{Path: "tmp/*.log", To: "http://example.com"}})).Should(HaveOccurred())
+		})
+
+		It("rejects globs at the root of the container", func() {
+			Ω(Validate([]api.Artifact{{Path: "/*.log", To: "http://example.com"}})).Should(Equal(ErrGlobAtRoot))
+			Ω(Validate([]api.Artifact{{Path: "/", To: "http://example.com"}})).Should(Equal(ErrGlobAtRoot))
+		})
+
+		It("rejects malformed globs", func() {
+			Ω(Validate([]api.Artifact{{Path: "/tmp/[", To: "http://example.com"}})).Should(HaveOccurred())
+		})
+
+		It("rejects malformed URLs", func() {
+			Ω(Validate([]api.Artifact{{Path: "/tmp/core", To: "not a url"}})).Should(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,58 @@
+// Package cancellable makes blocking I/O give up when a context is done.
+package cancellable
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+type reader struct {
+	ctx    context.Context
+	source io.Reader
+}
+
+// Reader reads from source until ctx is done, after which every read fails
+// with ctx's error.
+func Reader(ctx context.Context, source io.Reader) io.Reader {
+	return &reader{
+		ctx:    ctx,
+		source: source,
+	}
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	err := r.ctx.Err()
+	if err != nil {
+		return 0, err
+	}
+
+	return r.source.Read(p)
+}
+
+// CloseOnDone closes closer as soon as ctx is done, interrupting anything
+// blocked on it. Calling the returned func stops watching ctx; it does not
+// close closer itself.
+func CloseOnDone(ctx context.Context, closer io.Closer) func() {
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		select {
+		case <-ctx.Done():
+			closer.Close()
+		case <-stop:
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			close(stop)
+			<-stopped
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package cancellable_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	. "github.com/cloudfoundry-incubator/executor/cancellable"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeCloser struct {
+	closed chan struct{}
+}
+
+func (closer *fakeCloser) Close() error {
+	close(closer.closed)
+	return nil
+}
+
+var _ = Describe("Cancellable", func() {
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Describe("Reader", func() {
+		It("reads from the source", func() {
+			content, err := ioutil.ReadAll(Reader(ctx, strings.NewReader("hello")))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(content)).Should(Equal("hello"))
+		})
+
+		Context("when the context is done", func() {
+			It("fails with its error", func() {
+				reader := Reader(ctx, strings.NewReader("hello"))
+
+				buf := make([]byte, 2)
+				_, err := io.ReadFull(reader, buf)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				cancel()
+
+				_, err = reader.Read(buf)
+				Ω(err).Should(Equal(context.Canceled))
+			})
+		})
+	})
+
+	Describe("CloseOnDone", func() {
+		var closer *fakeCloser
+
+		BeforeEach(func() {
+			closer = &fakeCloser{closed: make(chan struct{})}
+		})
+
+		It("closes once the context is done", func() {
+			stop := CloseOnDone(ctx, closer)
+			defer stop()
+
+			Consistently(closer.closed).ShouldNot(BeClosed())
+
+			cancel()
+
+			Eventually(closer.closed).Should(BeClosed())
+		})
+
+		Context("when stopped first", func() {
+			It("does not close", func() {
+				stop := CloseOnDone(ctx, closer)
+				stop()
+				stop()
+
+				cancel()
+
+				Consistently(closer.closed).ShouldNot(BeClosed())
+			})
+		})
+	})
+})
@@ -55,6 +55,24 @@ func (c client) GetContainer(allocationGuid string) (api.Container, error) {
 	return c.buildContainerFromApiResponse(response)
 }
 
+func (c client) GetContainerMetrics(allocationGuid string) (api.ContainerMetrics, error) {
+	metrics := api.ContainerMetrics{}
+
+	response, err := c.makeRequest(api.GetContainerMetrics, rata.Params{"guid": allocationGuid}, nil)
+	if err != nil {
+		return metrics, err
+	}
+
+	defer response.Body.Close()
+
+	err = json.NewDecoder(response.Body).Decode(&metrics)
+	if err != nil {
+		return metrics, err
+	}
+
+	return metrics, nil
+}
+
 func (c client) InitializeContainer(allocationGuid string, request api.ContainerInitializationRequest) (api.Container, error) {
 	response, err := c.makeRequest(api.InitializeContainer, rata.Params{"guid": allocationGuid}, request)
 	if err != nil {
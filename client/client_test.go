@@ -171,6 +171,55 @@ var _ = Describe("Client", func() {
 		})
 	})
 
+	Describe("GetContainerMetrics", func() {
+		Context("when the call succeeds", func() {
+			BeforeEach(func() {
+				fakeExecutor.AppendHandlers(ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/containers/"+containerGuid+"/metrics"),
+					ghttp.RespondWith(http.StatusOK, `
+						{
+							"guid": "guid-123",
+							"current": {"time": 2, "memory_bytes": 2048, "cpu_usage": 20, "cpu_percent": 50},
+							"history": [
+								{"time": 1, "memory_bytes": 1024, "cpu_usage": 10},
+								{"time": 2, "memory_bytes": 2048, "cpu_usage": 20, "cpu_percent": 50}
+							]
+						}`),
+				))
+			})
+
+			It("returns the metrics", func() {
+				metrics, err := client.GetContainerMetrics(containerGuid)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(metrics).Should(Equal(api.ContainerMetrics{
+					Guid:    "guid-123",
+					Current: &api.UsageSample{Time: 2, MemoryBytes: 2048, CPUUsage: 20, CPUPercent: 50},
+					History: []api.UsageSample{
+						{Time: 1, MemoryBytes: 1024, CPUUsage: 10},
+						{Time: 2, MemoryBytes: 2048, CPUUsage: 20, CPUPercent: 50},
+					},
+				}))
+			})
+		})
+
+		Context("when the container was not found", func() {
+			BeforeEach(func() {
+				fakeExecutor.AppendHandlers(ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/containers/"+containerGuid+"/metrics"),
+					ghttp.RespondWith(api.ErrContainerNotFound.HttpCode(), "", http.Header{
+						"X-Executor-Error": []string{api.ErrContainerNotFound.Name()},
+					})),
+				)
+			})
+
+			It("returns an error", func() {
+				_, err := client.GetContainerMetrics(containerGuid)
+				Ω(err).Should(Equal(api.ErrContainerNotFound))
+			})
+		})
+	})
+
 	Describe("InitializeContainer", func() {
 		var validRequest api.ContainerInitializationRequest
 
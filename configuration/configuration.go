@@ -3,7 +3,9 @@ package configuration
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
+	"github.com/cloudfoundry-incubator/executor/api"
 	"github.com/cloudfoundry-incubator/executor/registry"
 	WardenClient "github.com/cloudfoundry-incubator/garden/client"
 	"github.com/cloudfoundry-incubator/garden/warden"
@@ -14,6 +16,8 @@ const Automatic = "auto"
 var (
 	ErrMemoryFlagInvalid = fmt.Errorf("memory limit must be a positive number or '%s'", Automatic)
 	ErrDiskFlagInvalid   = fmt.Errorf("disk limit must be a positive number or '%s'", Automatic)
+	ErrEgressFlagInvalid = fmt.Errorf("egress rules must be comma-separated CIDRs, each with an optional ':port'")
+	ErrBandwidthInvalid  = fmt.Errorf("default bandwidth must be set and within the maximum, if there is one")
 	EmptyCapacity        = registry.Capacity{}
 )
 
@@ -68,3 +72,59 @@ func diskInMB(capacity warden.Capacity, diskMBFlag string) (int, error) {
 		return diskMB, nil
 	}
 }
+
+// ParseEgressRules parses rules like "10.0.0.0/8:53,192.168.0.0/16", where a
+// rule without a port allows every port.
+func ParseEgressRules(egressFlag string) ([]api.EgressRule, error) {
+	rules := []api.EgressRule{}
+
+	for _, ruleFlag := range strings.Split(egressFlag, ",") {
+		ruleFlag = strings.TrimSpace(ruleFlag)
+		if ruleFlag == "" {
+			continue
+		}
+
+		rule := api.EgressRule{Network: ruleFlag}
+
+		if i := strings.LastIndex(ruleFlag, ":"); i != -1 {
+			port, err := strconv.ParseUint(ruleFlag[i+1:], 10, 16)
+			if err != nil {
+				return nil, ErrEgressFlagInvalid
+			}
+
+			rule.Network = ruleFlag[:i]
+			rule.Port = uint32(port)
+		}
+
+		if !rule.Valid() {
+			return nil, ErrEgressFlagInvalid
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// ValidateBandwidth checks the default bandwidth limits against the maximum,
+// which containers that ask for no limit get held to as well.
+func ValidateBandwidth(defaultLimits, maxLimits warden.BandwidthLimits) error {
+	if maxLimits.RateInBytesPerSecond != 0 {
+		if defaultLimits.RateInBytesPerSecond == 0 || defaultLimits.RateInBytesPerSecond > maxLimits.RateInBytesPerSecond {
+			return ErrBandwidthInvalid
+		}
+	}
+
+	if maxLimits.BurstRateInBytesPerSecond != 0 {
+		burst := defaultLimits.BurstRateInBytesPerSecond
+		if burst == 0 {
+			burst = defaultLimits.RateInBytesPerSecond
+		}
+
+		if burst > maxLimits.BurstRateInBytesPerSecond {
+			return ErrBandwidthInvalid
+		}
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package configuration_test
 import (
 	"errors"
 
+	"github.com/cloudfoundry-incubator/executor/api"
 	"github.com/cloudfoundry-incubator/executor/configuration"
 	"github.com/cloudfoundry-incubator/executor/registry"
 	"github.com/cloudfoundry-incubator/garden/client/fake_warden_client"
@@ -159,4 +160,80 @@ var _ = Describe("configuration", func() {
 			})
 		})
 	})
+
+	Describe("ParseEgressRules", func() {
+		It("parses each rule, with its port if it has one", func() {
+			rules, err := configuration.ParseEgressRules("10.0.0.0/8:53, 192.168.0.0/16")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rules).Should(Equal([]api.EgressRule{
+				{Network: "10.0.0.0/8", Port: 53},
+				{Network: "192.168.0.0/16"},
+			}))
+		})
+
+		It("has no rules when the flag is empty", func() {
+			rules, err := configuration.ParseEgressRules("")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rules).Should(BeEmpty())
+		})
+
+		Context("when a network is not a CIDR", func() {
+			It("returns an error", func() {
+				_, err := configuration.ParseEgressRules("10.0.0.1:53")
+				Ω(err).Should(Equal(configuration.ErrEgressFlagInvalid))
+			})
+		})
+
+		Context("when a port is not a number", func() {
+			It("returns an error", func() {
+				_, err := configuration.ParseEgressRules("10.0.0.0/8:dns")
+				Ω(err).Should(Equal(configuration.ErrEgressFlagInvalid))
+			})
+		})
+	})
+
+	Describe("ValidateBandwidth", func() {
+		It("allows any default when there is no maximum", func() {
+			err := configuration.ValidateBandwidth(warden.BandwidthLimits{}, warden.BandwidthLimits{})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			err = configuration.ValidateBandwidth(warden.BandwidthLimits{RateInBytesPerSecond: 100}, warden.BandwidthLimits{})
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		It("allows a default within the maximum", func() {
+			err := configuration.ValidateBandwidth(
+				warden.BandwidthLimits{RateInBytesPerSecond: 100, BurstRateInBytesPerSecond: 200},
+				warden.BandwidthLimits{RateInBytesPerSecond: 100, BurstRateInBytesPerSecond: 200},
+			)
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		Context("when there is a maximum rate but no default", func() {
+			It("returns an error", func() {
+				err := configuration.ValidateBandwidth(warden.BandwidthLimits{}, warden.BandwidthLimits{RateInBytesPerSecond: 100})
+				Ω(err).Should(Equal(configuration.ErrBandwidthInvalid))
+			})
+		})
+
+		Context("when the default rate is over the maximum", func() {
+			It("returns an error", func() {
+				err := configuration.ValidateBandwidth(
+					warden.BandwidthLimits{RateInBytesPerSecond: 101},
+					warden.BandwidthLimits{RateInBytesPerSecond: 100},
+				)
+				Ω(err).Should(Equal(configuration.ErrBandwidthInvalid))
+			})
+		})
+
+		Context("when the default burst, which defaults to the rate, is over the maximum", func() {
+			It("returns an error", func() {
+				err := configuration.ValidateBandwidth(
+					warden.BandwidthLimits{RateInBytesPerSecond: 100},
+					warden.BandwidthLimits{RateInBytesPerSecond: 100, BurstRateInBytesPerSecond: 50},
+				)
+				Ω(err).Should(Equal(configuration.ErrBandwidthInvalid))
+			})
+		})
+	})
 })
@@ -7,15 +7,15 @@ import (
 	"net/http"
 	"os"
 	"time"
-
-	"github.com/cloudfoundry-incubator/executor/api"
 )
 
 const MAX_CALLBACK_ATTEMPTS = 42
 
+// Callback PUTs its payload, as JSON, to URL until it succeeds. The payload
+// is an api.ContainerRunResult or an api.ContainerEvent.
 type Callback struct {
 	URL     string
-	Payload api.ContainerRunResult
+	Payload interface{}
 }
 
 func (c *Callback) Run(sigChan <-chan os.Signal, readyChan chan<- struct{}) error {
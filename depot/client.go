@@ -4,10 +4,15 @@ import (
 	"os"
 
 	"github.com/cloudfoundry-incubator/executor/api"
+	"github.com/cloudfoundry-incubator/executor/artifacts"
+	"github.com/cloudfoundry-incubator/executor/log_streamer"
+	"github.com/cloudfoundry-incubator/executor/redaction"
 	"github.com/cloudfoundry-incubator/executor/registry"
 	"github.com/cloudfoundry-incubator/executor/sequence"
+	"github.com/cloudfoundry-incubator/executor/steps/fetch_result_step"
 	"github.com/cloudfoundry-incubator/executor/transformer"
 	"github.com/cloudfoundry-incubator/garden/warden"
+	"github.com/cloudfoundry/gunk/timeprovider"
 	"github.com/pivotal-golang/lager"
 	"github.com/tedsuo/ifrit"
 )
@@ -16,9 +21,14 @@ type client struct {
 	containerOwnerName    string
 	containerMaxCPUShares uint64
 	containerInodeLimit   uint64
+	defaultEgressRules    []api.EgressRule
+	defaultBandwidth      warden.BandwidthLimits
+	maxBandwidth          warden.BandwidthLimits
 	wardenClient          warden.Client
 	registry              registry.Registry
 	transformer           *transformer.Transformer
+	artifactCollector     artifacts.Collector
+	timeProvider          timeprovider.TimeProvider
 	logger                lager.Logger
 }
 
@@ -26,27 +36,81 @@ func NewClient(
 	containerOwnerName string,
 	containerMaxCPUShares uint64,
 	containerInodeLimit uint64,
+	defaultEgressRules []api.EgressRule,
+	defaultBandwidth warden.BandwidthLimits,
+	maxBandwidth warden.BandwidthLimits,
 	wardenClient warden.Client,
 	registry registry.Registry,
 	transformer *transformer.Transformer,
+	artifactCollector artifacts.Collector,
+	timeProvider timeprovider.TimeProvider,
 	logger lager.Logger,
 ) api.Client {
 	return &client{
 		containerOwnerName:    containerOwnerName,
 		containerMaxCPUShares: containerMaxCPUShares,
 		containerInodeLimit:   containerInodeLimit,
+		defaultEgressRules:    defaultEgressRules,
+		defaultBandwidth:      defaultBandwidth,
+		maxBandwidth:          maxBandwidth,
 		wardenClient:          wardenClient,
 		registry:              registry,
 		transformer:           transformer,
+		artifactCollector:     artifactCollector,
+		timeProvider:          timeProvider,
 		logger:                logger.Session("depot-client"),
 	}
 }
 
+func (c *client) GetContainerMetrics(guid string) (api.ContainerMetrics, error) {
+	getLog := c.logger.Session("get-metrics", lager.Data{
+		"guid": guid,
+	})
+
+	container, err := c.registry.FindByGuid(guid)
+	if err != nil {
+		getLog.Error("container-not-found", err)
+		return api.ContainerMetrics{}, api.ErrContainerNotFound
+	}
+
+	metrics := api.ContainerMetrics{
+		Guid:    guid,
+		History: container.Usage,
+	}
+
+	if len(container.Usage) > 0 {
+		current := container.Usage[len(container.Usage)-1]
+		metrics.Current = &current
+	}
+
+	if metrics.History == nil {
+		metrics.History = []api.UsageSample{}
+	}
+
+	return metrics, nil
+}
+
 func (c *client) InitializeContainer(guid string, request api.ContainerInitializationRequest) (api.Container, error) {
 	if request.CpuPercent > 100 || request.CpuPercent < 0 {
 		return api.Container{}, api.ErrLimitsInvalid
 	}
 
+	request.BandwidthRate, request.BandwidthBurst = c.effectiveBandwidth(request)
+	if !c.bandwidthAllowed(request.BandwidthRate, request.BandwidthBurst) {
+		return api.Container{}, api.ErrLimitsInvalid
+	}
+
+	for _, rule := range request.EgressRules {
+		if !rule.Valid() {
+			return api.Container{}, api.ErrLimitsInvalid
+		}
+	}
+
+	_, err := log_streamer.MultiLineFor(request.Log.MultiLine)
+	if err != nil {
+		return api.Container{}, api.ErrLogConfigInvalid
+	}
+
 	initLog := c.logger.Session("initialize", lager.Data{
 		"guid": guid,
 	})
@@ -96,6 +160,12 @@ func (c *client) InitializeContainer(guid string, request api.ContainerInitializ
 		return api.Container{}, err
 	}
 
+	err = c.limitContainerBandwidth(request, containerClient)
+	if err != nil {
+		initLog.Error("failed-to-limit-bandwidth", err)
+		return api.Container{}, err
+	}
+
 	portMapping, err := c.mapPorts(request, containerClient)
 	if err != nil {
 		initLog.Error("failed-to-map-ports", err)
@@ -104,6 +174,14 @@ func (c *client) InitializeContainer(guid string, request api.ContainerInitializ
 
 	request.Ports = portMapping
 
+	request.EgressRules = append(append([]api.EgressRule{}, c.defaultEgressRules...), request.EgressRules...)
+
+	err = c.allowEgress(request.EgressRules, containerClient)
+	if err != nil {
+		initLog.Error("failed-to-allow-egress", err)
+		return api.Container{}, err
+	}
+
 	container, err = c.registry.Create(guid, containerClient.Handle(), request)
 	if err != nil {
 		initLog.Error("failed-to-register-container", err)
@@ -147,6 +225,71 @@ func (c *client) limitContainerCPU(request api.ContainerInitializationRequest, c
 	return nil
 }
 
+// effectiveBandwidth is the requested bandwidth limits, falling back to the
+// executor's defaults. The burst is the rate if neither gives one.
+func (c *client) effectiveBandwidth(request api.ContainerInitializationRequest) (uint64, uint64) {
+	rate := request.BandwidthRate
+	if rate == 0 {
+		rate = c.defaultBandwidth.RateInBytesPerSecond
+	}
+
+	burst := request.BandwidthBurst
+	if burst == 0 {
+		burst = c.defaultBandwidth.BurstRateInBytesPerSecond
+	}
+
+	if rate == 0 {
+		return 0, 0
+	}
+
+	if burst == 0 {
+		burst = rate
+	}
+
+	return rate, burst
+}
+
+// bandwidthAllowed says whether the limits are within the executor's
+// maximum. Once there is a maximum rate, a container cannot go unlimited.
+func (c *client) bandwidthAllowed(rate, burst uint64) bool {
+	if c.maxBandwidth.RateInBytesPerSecond != 0 {
+		if rate == 0 || rate > c.maxBandwidth.RateInBytesPerSecond {
+			return false
+		}
+	}
+
+	if c.maxBandwidth.BurstRateInBytesPerSecond != 0 && burst > c.maxBandwidth.BurstRateInBytesPerSecond {
+		return false
+	}
+
+	return true
+}
+
+func (c *client) limitContainerBandwidth(request api.ContainerInitializationRequest, containerClient warden.Container) error {
+	if request.BandwidthRate != 0 {
+		err := containerClient.LimitBandwidth(warden.BandwidthLimits{
+			RateInBytesPerSecond:      request.BandwidthRate,
+			BurstRateInBytesPerSecond: request.BandwidthBurst,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *client) allowEgress(rules []api.EgressRule, containerClient warden.Container) error {
+	for _, rule := range rules {
+		err := containerClient.NetOut(rule.Network, rule.Port)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (c *client) mapPorts(request api.ContainerInitializationRequest, containerClient warden.Container) ([]api.PortMapping, error) {
 	var result []api.PortMapping
 	for _, mapping := range request.Ports {
@@ -213,23 +356,105 @@ func (c *client) Run(guid string, request api.ContainerRunRequest) error {
 		return err
 	}
 
-	var result string
-	steps, err := c.transformer.StepsFor(registration.Log, request.Actions, request.Env, container, &result)
+	results := fetch_result_step.NewResults()
+	redactor := redaction.ForEnv(request.Env)
+	recordTransfer := func(transferred api.TransferProgress) {
+		err := c.registry.RecordTransfer(guid, transferred)
+		if err != nil {
+			runLog.Error("failed-to-record-transfer", err)
+		}
+	}
+
+	recordHealth := func(health api.Health) {
+		err := c.registry.RecordHealth(guid, health)
+		if err != nil {
+			runLog.Error("failed-to-record-health", err)
+		}
+	}
+
+	recordLogsDropped := func(lines int) {
+		err := c.registry.RecordLogsDropped(guid, lines)
+		if err != nil {
+			runLog.Error("failed-to-record-logs-dropped", err)
+		}
+	}
+
+	outcome := newRunOutcome()
+	recordEvents := func(events []string) {
+		events = outcome.recordEvents(events)
+		if len(events) == 0 {
+			return
+		}
+
+		err := c.registry.RecordEvents(guid, events)
+		if err != nil {
+			runLog.Error("failed-to-record-events", err)
+		}
+
+		for _, ev := range events {
+			if ev != api.OutOfMemoryEvent {
+				continue
+			}
+
+			runLog.Info("out-of-memory")
+
+			if request.EventURL != "" {
+				ifrit.Envoke(&Callback{
+					URL:     request.EventURL,
+					Payload: api.ContainerEvent{Guid: guid, Event: ev},
+				})
+			}
+		}
+	}
+
+	progress := sequence.NewProgress(c.timeProvider, func(steps []api.StepProgress) {
+		err := c.registry.RecordSteps(guid, redactor.RedactSteps(steps))
+		if err != nil {
+			runLog.Error("failed-to-record-steps", err)
+		}
+	})
+
+	steps, err := c.transformer.StepsFor(transformer.Run{
+		Guid:              guid,
+		LogConfig:         registration.Log,
+		Env:               request.Env,
+		Container:         container,
+		Results:           results,
+		Progress:          progress,
+		RecordTransfer:    recordTransfer,
+		RecordHealth:      recordHealth,
+		RecordLogsDropped: recordLogsDropped,
+		RecordExitStatus:  outcome.recordExitStatus,
+		RecordEvents:      recordEvents,
+		Redactor:          redactor,
+	}, request.Actions)
 	if err != nil {
 		runLog.Error("steps-invalid", err)
 		return api.ErrStepsInvalid
 	}
 
+	err = artifacts.Validate(request.Artifacts)
+	if err != nil {
+		runLog.Error("artifacts-invalid", err)
+		return api.ErrArtifactsInvalid
+	}
+
 	run := RunSequence{
 		CompleteURL:  request.CompleteURL,
 		Registration: registration,
 		Sequence:     sequence.New(steps),
-		Result:       &result,
+		Results:      results,
+		Progress:     progress,
+		Redactor:     redactor,
+		Outcome:      outcome,
+		Container:    container,
+		Artifacts:    request.Artifacts,
+		Collector:    c.artifactCollector,
 		Registry:     c.registry,
 		Logger:       c.logger,
 	}
 	process := ifrit.Envoke(run)
-	c.registry.Start(run.Registration.Guid, process)
+	c.registry.Start(run.Registration.Guid, request.Env, process)
 
 	runLog.Info("started", lager.Data{
 		"handle": registration.ContainerHandle,
@@ -254,6 +479,9 @@ func (c *client) DeleteContainer(guid string) error {
 		return handleDeleteError(err, deleteLog)
 	}
 
+	// the run is over however the rest goes, so let go of its log state
+	defer c.transformer.ReleaseContainer(guid)
+
 	logData["handle"] = reg.ContainerHandle
 
 	deleteLog.Debug("deleting")
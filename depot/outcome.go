@@ -0,0 +1,61 @@
+package depot
+
+import (
+	"sync"
+
+	"github.com/cloudfoundry-incubator/executor/api"
+)
+
+// runOutcome is what a run's processes did, for its result.
+type runOutcome struct {
+	lock       sync.Mutex
+	exitStatus *int
+	events     []string
+	seen       map[string]bool
+}
+
+func newRunOutcome() *runOutcome {
+	return &runOutcome{
+		seen: map[string]bool{},
+	}
+}
+
+func (o *runOutcome) recordExitStatus(exitStatus int) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.exitStatus = &exitStatus
+}
+
+// recordEvents keeps the events not seen before, and returns them.
+func (o *runOutcome) recordEvents(events []string) []string {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	newEvents := []string{}
+	for _, ev := range events {
+		if o.seen[ev] {
+			continue
+		}
+
+		o.seen[ev] = true
+		o.events = append(o.events, ev)
+		newEvents = append(newEvents, ev)
+	}
+
+	return newEvents
+}
+
+func (o *runOutcome) fill(payload *api.ContainerRunResult) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	payload.ExitStatus = o.exitStatus
+	payload.Events = append([]string(nil), o.events...)
+
+	for _, ev := range o.events {
+		if ev == api.OutOfMemoryEvent {
+			payload.OOMKilled = true
+		}
+	}
+}
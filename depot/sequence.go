@@ -1,11 +1,16 @@
 package depot
 
 import (
+	"context"
 	"os"
 
 	"github.com/cloudfoundry-incubator/executor/api"
+	"github.com/cloudfoundry-incubator/executor/artifacts"
+	"github.com/cloudfoundry-incubator/executor/redaction"
 	"github.com/cloudfoundry-incubator/executor/registry"
 	"github.com/cloudfoundry-incubator/executor/sequence"
+	"github.com/cloudfoundry-incubator/executor/steps/fetch_result_step"
+	"github.com/cloudfoundry-incubator/garden/warden"
 	"github.com/pivotal-golang/lager"
 	"github.com/tedsuo/ifrit"
 )
@@ -14,67 +19,143 @@ type RunSequence struct {
 	CompleteURL  string
 	Registration api.Container
 	Sequence     sequence.Step
-	Result       *string
+	Results      *fetch_result_step.Results
+	Progress     *sequence.Progress
+	Redactor     *redaction.Redactor
+	Outcome      *runOutcome
+	Container    warden.Container
+	Artifacts    []api.Artifact
+	Collector    artifacts.Collector
 	Registry     registry.Registry
 	Logger       lager.Logger
 }
 
 func (r RunSequence) Run(sigChan <-chan os.Signal, readyChan chan<- struct{}) error {
-	seqComplete := make(chan error)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	runLog := r.Logger.Session("run", lager.Data{
 		"guid":   r.Registration.Guid,
 		"handle": r.Registration.ContainerHandle,
 	})
 
+	// a signal cancels whatever is in flight, be it a step or collecting
+	// the artifacts afterwards
 	go func() {
-		runLog.Info("starting")
-		seqComplete <- r.Sequence.Perform()
+		select {
+		case <-sigChan:
+			runLog.Info("cancelled")
+			cancel()
+		case <-ctx.Done():
+		}
 	}()
 
 	close(readyChan)
 
-	for {
-		select {
-		case <-sigChan:
-			sigChan = nil
-			r.Sequence.Cancel()
-			runLog.Info("cancelled")
+	runLog.Info("starting")
 
-		case err := <-seqComplete:
-			if err == sequence.CancelledError {
-				return err
-			}
+	err := r.Sequence.Perform(ctx)
 
-			runLog.Info("completed")
+	cleanupErrs := cleanupErrors(r.Sequence.Cleanup())
+	for _, cleanupErr := range cleanupErrs {
+		runLog.Error("failed-to-clean-up", cleanupErr.Err, lager.Data{
+			"action": cleanupErr.Action,
+		})
+	}
 
-			payload := api.ContainerRunResult{
-				Guid:   r.Registration.Guid,
-				Result: *r.Result,
-			}
+	if err == sequence.CancelledError {
+		return err
+	}
 
-			if err != nil {
-				payload.Failed = true
-				payload.FailureReason = err.Error()
-			}
+	runLog.Info("completed")
 
-			err = r.Registry.Complete(r.Registration.Guid, payload)
-			if err != nil {
-				runLog.Error("failed-to-complete", err)
-			}
+	payload := api.ContainerRunResult{
+		Guid:    r.Registration.Guid,
+		Result:  r.Redactor.Redact(r.Results.Result()),
+		Results: r.redactNamed(r.Results.Named()),
+		Steps:   r.Redactor.RedactSteps(r.Progress.Steps()),
+	}
 
-			if r.CompleteURL == "" {
-				return err
-			}
+	r.Outcome.fill(&payload)
 
-			ifrit.Envoke(&Callback{
-				URL:     r.CompleteURL,
-				Payload: payload,
-			})
+	if err != nil {
+		payload.Failed = true
+		payload.FailureReason = r.Redactor.Redact(err.Error())
+	}
 
-			runLog.Info("callback-started")
+	if len(cleanupErrs) > 0 {
+		payload.CleanupFailed = true
 
-			return err
+		for _, cleanupErr := range cleanupErrs {
+			payload.CleanupErrors = append(payload.CleanupErrors, r.Redactor.Redact(cleanupErr.Error()))
 		}
 	}
+
+	if len(r.Artifacts) > 0 {
+		payload.Artifacts = r.Collector.Collect(ctx, r.Container, r.Artifacts)
+		if ctx.Err() != nil {
+			return sequence.CancelledError
+		}
+
+		for i, artifact := range payload.Artifacts {
+			payload.Artifacts[i].Error = r.Redactor.Redact(artifact.Error)
+		}
+
+		runLog.Info("collected-artifacts", lager.Data{
+			"artifacts": payload.Artifacts,
+		})
+	}
+
+	err = r.Registry.Complete(r.Registration.Guid, payload)
+	if err != nil {
+		runLog.Error("failed-to-complete", err)
+	}
+
+	if r.CompleteURL == "" {
+		return err
+	}
+
+	ifrit.Envoke(&Callback{
+		URL:     r.CompleteURL,
+		Payload: payload,
+	})
+
+	runLog.Info("callback-started")
+
+	return err
+}
+
+func (r RunSequence) redactNamed(results map[string]string) map[string]string {
+	for name, value := range results {
+		results[name] = r.Redactor.Redact(value)
+	}
+
+	return results
+}
+
+// cleanupErrors lists the failures in what a sequence's Cleanup returned,
+// each with the action it came from, if known.
+func cleanupErrors(err error) []*sequence.StepCleanupError {
+	var errs []error
+
+	switch cleanupErr := err.(type) {
+	case nil:
+		return nil
+	case *sequence.CleanupError:
+		errs = cleanupErr.Errors
+	default:
+		errs = []error{err}
+	}
+
+	stepErrs := make([]*sequence.StepCleanupError, len(errs))
+	for i, err := range errs {
+		stepErr, ok := err.(*sequence.StepCleanupError)
+		if !ok {
+			stepErr = &sequence.StepCleanupError{Err: err}
+		}
+
+		stepErrs[i] = stepErr
+	}
+
+	return stepErrs
 }
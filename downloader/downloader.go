@@ -0,0 +1,46 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pivotal-golang/cacheddownloader"
+)
+
+type UnsupportedSchemeError struct {
+	Scheme string
+}
+
+func (err UnsupportedSchemeError) Error() string {
+	return fmt.Sprintf("no downloader for scheme: %s", err.Scheme)
+}
+
+// SchemeDownloader hands each download to the backend registered for the
+// scheme of its URL, e.g. "https", "file" or "s3".
+type SchemeDownloader struct {
+	backends map[string]cacheddownloader.RemoteDownloader
+}
+
+func New(backends map[string]cacheddownloader.RemoteDownloader) *SchemeDownloader {
+	return &SchemeDownloader{
+		backends: backends,
+	}
+}
+
+func (downloader *SchemeDownloader) Download(
+	ctx context.Context,
+	url *url.URL,
+	destinationFile *os.File,
+	cachingInfoIn cacheddownloader.CachingInfoType,
+	progress cacheddownloader.ProgressFunc,
+) (bool, int64, cacheddownloader.CachingInfoType, error) {
+	backend, found := downloader.backends[strings.ToLower(url.Scheme)]
+	if !found {
+		return false, 0, cacheddownloader.CachingInfoType{}, UnsupportedSchemeError{Scheme: url.Scheme}
+	}
+
+	return backend.Download(ctx, url, destinationFile, cachingInfoIn, progress)
+}
@@ -0,0 +1,167 @@
+package downloader_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/executor/downloader"
+	"github.com/cloudfoundry-incubator/executor/object_store"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	"github.com/pivotal-golang/cacheddownloader"
+)
+
+var _ = Describe("Downloader", func() {
+	var (
+		sourceDir       string
+		destinationFile *os.File
+		downloader      cacheddownloader.RemoteDownloader
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		sourceDir, err = ioutil.TempDir("", "download-source")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		destinationFile, err = ioutil.TempFile("", "download-destination")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		downloader = New(map[string]cacheddownloader.RemoteDownloader{
+			"file": NewFileDownloader(sourceDir),
+		})
+	})
+
+	AfterEach(func() {
+		destinationFile.Close()
+		os.Remove(destinationFile.Name())
+		os.RemoveAll(sourceDir)
+	})
+
+	Context("when no backend is registered for the scheme", func() {
+		It("returns an error", func() {
+			ftpURL, err := url.Parse("ftp://example.com/file")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			_, _, _, err = downloader.Download(context.Background(), ftpURL, destinationFile, cacheddownloader.CachingInfoType{}, nil)
+			Ω(err).Should(Equal(UnsupportedSchemeError{Scheme: "ftp"}))
+		})
+	})
+
+	Describe("file URLs", func() {
+		var sourceURL *url.URL
+
+		BeforeEach(func() {
+			sourcePath := filepath.Join(sourceDir, "some-file")
+
+			err := ioutil.WriteFile(sourcePath, []byte("some-contents"), 0644)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			sourceURL = &url.URL{Scheme: "file", Path: sourcePath}
+		})
+
+		It("copies the file", func() {
+			didDownload, length, cachingInfo, err := downloader.Download(context.Background(), sourceURL, destinationFile, cacheddownloader.CachingInfoType{}, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(didDownload).Should(BeTrue())
+			Ω(length).Should(Equal(int64(len("some-contents"))))
+			Ω(cachingInfo.LastModified).ShouldNot(BeEmpty())
+
+			contents, err := ioutil.ReadFile(destinationFile.Name())
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(contents)).Should(Equal("some-contents"))
+		})
+
+		It("does not copy a file that has not changed since it was cached", func() {
+			_, _, cachingInfo, err := downloader.Download(context.Background(), sourceURL, destinationFile, cacheddownloader.CachingInfoType{}, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			didDownload, _, _, err := downloader.Download(context.Background(), sourceURL, destinationFile, cachingInfo, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(didDownload).Should(BeFalse())
+		})
+
+		It("copies the file again once it has changed", func() {
+			_, _, cachingInfo, err := downloader.Download(context.Background(), sourceURL, destinationFile, cacheddownloader.CachingInfoType{}, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			later := time.Now().Add(time.Hour)
+			err = os.Chtimes(sourceURL.Path, later, later)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			didDownload, _, _, err := downloader.Download(context.Background(), sourceURL, destinationFile, cachingInfo, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(didDownload).Should(BeTrue())
+		})
+
+		It("refuses files outside the root directory", func() {
+			secretPath := sourceDir + "-secret"
+			err := ioutil.WriteFile(secretPath, []byte("secret"), 0644)
+			Ω(err).ShouldNot(HaveOccurred())
+			defer os.Remove(secretPath)
+
+			escapingURL := &url.URL{Scheme: "file", Path: sourceDir + "/../" + filepath.Base(secretPath)}
+			_, _, _, err = downloader.Download(context.Background(), escapingURL, destinationFile, cacheddownloader.CachingInfoType{}, nil)
+			Ω(err).Should(Equal(ErrOutsideRoot))
+
+			absoluteURL := &url.URL{Scheme: "file", Path: "/etc/passwd"}
+			_, _, _, err = downloader.Download(context.Background(), absoluteURL, destinationFile, cacheddownloader.CachingInfoType{}, nil)
+			Ω(err).Should(Equal(ErrOutsideRoot))
+		})
+
+		It("returns an error when the file does not exist", func() {
+			missingURL := &url.URL{Scheme: "file", Path: filepath.Join(sourceDir, "missing")}
+
+			_, _, _, err := downloader.Download(context.Background(), missingURL, destinationFile, cacheddownloader.CachingInfoType{}, nil)
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+
+	Describe("object store URLs", func() {
+		var server *ghttp.Server
+
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/some-bucket/some/key"),
+				func(w http.ResponseWriter, r *http.Request) {
+					Ω(strings.HasPrefix(r.Header.Get("Authorization"), "AWS some-access-key:")).Should(BeTrue())
+				},
+				ghttp.RespondWith(http.StatusOK, "some-object"),
+			))
+
+			endpoint, err := url.Parse(server.URL())
+			Ω(err).ShouldNot(HaveOccurred())
+
+			store := object_store.New(endpoint, "some-access-key", "some-secret-key")
+
+			downloader = New(map[string]cacheddownloader.RemoteDownloader{
+				object_store.Scheme: cacheddownloader.NewPreparingDownloader(time.Second, store.Prepare),
+			})
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("fetches the object from the endpoint with a signed request", func() {
+			objectURL, err := url.Parse("s3://some-bucket/some/key")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			didDownload, _, _, err := downloader.Download(context.Background(), objectURL, destinationFile, cacheddownloader.CachingInfoType{}, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(didDownload).Should(BeTrue())
+
+			contents, err := ioutil.ReadFile(destinationFile.Name())
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(contents)).Should(Equal("some-object"))
+		})
+	})
+})
@@ -0,0 +1,77 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/cloudfoundry-incubator/executor/cancellable"
+	"github.com/cloudfoundry-incubator/executor/file_scheme"
+	"github.com/pivotal-golang/cacheddownloader"
+)
+
+var ErrMissingPath = file_scheme.ErrMissingPath
+var ErrOutsideRoot = file_scheme.ErrOutsideRoot
+
+// FileDownloader copies from file:// URLs within root, e.g. off a shared
+// volume. The modification time stands in for Last-Modified, so cached
+// copies are only refreshed when the file changes.
+type FileDownloader struct {
+	root string
+}
+
+func NewFileDownloader(root string) *FileDownloader {
+	return &FileDownloader{
+		root: root,
+	}
+}
+
+func (downloader *FileDownloader) Download(
+	ctx context.Context,
+	url *url.URL,
+	destinationFile *os.File,
+	cachingInfoIn cacheddownloader.CachingInfoType,
+	progress cacheddownloader.ProgressFunc,
+) (bool, int64, cacheddownloader.CachingInfoType, error) {
+	path, err := file_scheme.Resolve(downloader.root, url)
+	if err != nil {
+		return false, 0, cacheddownloader.CachingInfoType{}, err
+	}
+
+	source, err := os.Open(path)
+	if err != nil {
+		return false, 0, cacheddownloader.CachingInfoType{}, err
+	}
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		return false, 0, cacheddownloader.CachingInfoType{}, err
+	}
+
+	lastModified := info.ModTime().UTC().Format(http.TimeFormat)
+	if cachingInfoIn.LastModified == lastModified {
+		return false, 0, cacheddownloader.CachingInfoType{}, nil
+	}
+
+	_, err = destinationFile.Seek(0, 0)
+	if err != nil {
+		return false, 0, cacheddownloader.CachingInfoType{}, err
+	}
+
+	err = destinationFile.Truncate(0)
+	if err != nil {
+		return false, 0, cacheddownloader.CachingInfoType{}, err
+	}
+
+	length, err := io.Copy(io.MultiWriter(destinationFile, cacheddownloader.NewProgressWriter(info.Size(), progress)), cancellable.Reader(ctx, source))
+	if err != nil {
+		return false, 0, cacheddownloader.CachingInfoType{}, err
+	}
+
+	return true, length, cacheddownloader.CachingInfoType{
+		LastModified: lastModified,
+	}, nil
+}
@@ -0,0 +1,67 @@
+// Package file_scheme confines file:// URLs to a root directory.
+package file_scheme
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrMissingPath = errors.New("file URL has no path")
+var ErrOutsideRoot = errors.New("file URL is outside the root directory")
+
+// Resolve is the path url names, provided it lies within root once cleaned
+// and once any symlinks along it are followed.
+func Resolve(root string, url *url.URL) (string, error) {
+	if url.Path == "" {
+		return "", ErrMissingPath
+	}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Clean(filepath.FromSlash(url.Path))
+	if !filepath.IsAbs(path) || !within(root, path) {
+		return "", ErrOutsideRoot
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+
+	// the path may not exist yet, e.g. when uploading, so check where its
+	// deepest existing ancestor really is
+	existing := path
+	for {
+		realPath, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			if !within(realRoot, realPath) {
+				return "", ErrOutsideRoot
+			}
+
+			break
+		}
+
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		existing = filepath.Dir(existing)
+	}
+
+	return path, nil
+}
+
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,13 @@
+package file_scheme_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestFileScheme(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "File Scheme Suite")
+}
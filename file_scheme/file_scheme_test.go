@@ -0,0 +1,102 @@
+package file_scheme_test
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	. "github.com/cloudfoundry-incubator/executor/file_scheme"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resolve", func() {
+	var parent, root string
+
+	fileURL := func(path string) *url.URL {
+		return &url.URL{Scheme: "file", Path: path}
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		parent, err = ioutil.TempDir("", "file-scheme")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		root = filepath.Join(parent, "root")
+
+		err = os.MkdirAll(filepath.Join(root, "existing"), 0755)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		err = ioutil.WriteFile(filepath.Join(parent, "secret"), []byte("secret"), 0644)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(parent)
+	})
+
+	It("resolves paths within the root, whether or not they exist yet", func() {
+		path, err := Resolve(root, fileURL(filepath.Join(root, "existing")))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(path).Should(Equal(filepath.Join(root, "existing")))
+
+		path, err = Resolve(root, fileURL(filepath.Join(root, "new", "droplet")))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(path).Should(Equal(filepath.Join(root, "new", "droplet")))
+	})
+
+	It("cleans the path", func() {
+		path, err := Resolve(root, fileURL(root+"/existing/../droplet"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(path).Should(Equal(filepath.Join(root, "droplet")))
+	})
+
+	It("requires a path", func() {
+		_, err := Resolve(root, &url.URL{Scheme: "file"})
+		Ω(err).Should(Equal(ErrMissingPath))
+	})
+
+	Context("when the path escapes the root with ../", func() {
+		It("returns an error", func() {
+			_, err := Resolve(root, fileURL(root+"/../secret"))
+			Ω(err).Should(Equal(ErrOutsideRoot))
+
+			_, err = Resolve(root, fileURL(root+"/existing/../../secret"))
+			Ω(err).Should(Equal(ErrOutsideRoot))
+		})
+	})
+
+	Context("when the path is absolute and outside the root", func() {
+		It("returns an error", func() {
+			_, err := Resolve(root, fileURL("/etc/passwd"))
+			Ω(err).Should(Equal(ErrOutsideRoot))
+
+			_, err = Resolve(root, fileURL(root+"-sibling/file"))
+			Ω(err).Should(Equal(ErrOutsideRoot))
+		})
+	})
+
+	Context("when the path is relative", func() {
+		It("returns an error", func() {
+			_, err := Resolve(root, fileURL("existing"))
+			Ω(err).Should(Equal(ErrOutsideRoot))
+		})
+	})
+
+	Context("when a symlink in the root points outside it", func() {
+		BeforeEach(func() {
+			err := os.Symlink(parent, filepath.Join(root, "link"))
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		It("returns an error for paths through it", func() {
+			_, err := Resolve(root, fileURL(filepath.Join(root, "link", "secret")))
+			Ω(err).Should(Equal(ErrOutsideRoot))
+
+			_, err = Resolve(root, fileURL(filepath.Join(root, "link", "new", "droplet")))
+			Ω(err).Should(Equal(ErrOutsideRoot))
+		})
+	})
+})
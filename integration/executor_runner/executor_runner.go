@@ -34,6 +34,14 @@ type Config struct {
 	RegistryPruningInterval time.Duration
 	DebugAddr               string
 	ContainerInodeLimit     uint64
+	DefaultEgressRules      string
+	DefaultBandwidthRate    uint64
+	MaxBandwidthRate        uint64
+	EventPollInterval       time.Duration
+	StopWaitTimeout         time.Duration
+	HookRetryInterval       time.Duration
+	UploadRetryBaseDelay    time.Duration
+	UploadRetryMaxDelay     time.Duration
 }
 
 var defaultConfig = Config{
@@ -43,6 +51,13 @@ var defaultConfig = Config{
 	ContainerOwnerName:      "",
 	ContainerMaxCpuShares:   1024,
 	RegistryPruningInterval: time.Minute,
+
+	// keep specs from waiting on the real polls and backoffs
+	EventPollInterval:    50 * time.Millisecond,
+	StopWaitTimeout:      100 * time.Millisecond,
+	HookRetryInterval:    10 * time.Millisecond,
+	UploadRetryBaseDelay: 10 * time.Millisecond,
+	UploadRetryMaxDelay:  100 * time.Millisecond,
 }
 
 func New(executorBin, listenAddr, wardenNetwork, wardenAddr string, loggregatorServer string, loggregatorSecret string) *ExecutorRunner {
@@ -85,6 +100,23 @@ func (r *ExecutorRunner) StartWithoutCheck(config ...Config) {
 		"-containerMaxCpuShares", fmt.Sprintf("%d", configToUse.ContainerMaxCpuShares),
 		"-pruneInterval", fmt.Sprintf("%s", configToUse.RegistryPruningInterval),
 		"-containerInodeLimit", fmt.Sprintf("%d", configToUse.ContainerInodeLimit),
+		"-eventPollInterval", fmt.Sprintf("%s", configToUse.EventPollInterval),
+		"-stopWaitTimeout", fmt.Sprintf("%s", configToUse.StopWaitTimeout),
+		"-hookRetryInterval", fmt.Sprintf("%s", configToUse.HookRetryInterval),
+		"-uploadRetryBaseDelay", fmt.Sprintf("%s", configToUse.UploadRetryBaseDelay),
+		"-uploadRetryMaxDelay", fmt.Sprintf("%s", configToUse.UploadRetryMaxDelay),
+	}
+
+	if configToUse.DefaultEgressRules != "" {
+		args = append(args, "-defaultEgressRules", configToUse.DefaultEgressRules)
+	}
+
+	if configToUse.DefaultBandwidthRate != 0 {
+		args = append(args, "-defaultBandwidthRate", fmt.Sprintf("%d", configToUse.DefaultBandwidthRate))
+	}
+
+	if configToUse.MaxBandwidthRate != 0 {
+		args = append(args, "-maxBandwidthRate", fmt.Sprintf("%d", configToUse.MaxBandwidthRate))
 	}
 
 	if configToUse.DebugAddr != "" {
@@ -150,8 +182,26 @@ func (r *ExecutorRunner) generateConfig(configs ...Config) Config {
 	if givenConfig.ContainerInodeLimit != 0 {
 		configToReturn.ContainerInodeLimit = givenConfig.ContainerInodeLimit
 	}
+	if givenConfig.EventPollInterval != 0 {
+		configToReturn.EventPollInterval = givenConfig.EventPollInterval
+	}
+	if givenConfig.StopWaitTimeout != 0 {
+		configToReturn.StopWaitTimeout = givenConfig.StopWaitTimeout
+	}
+	if givenConfig.HookRetryInterval != 0 {
+		configToReturn.HookRetryInterval = givenConfig.HookRetryInterval
+	}
+	if givenConfig.UploadRetryBaseDelay != 0 {
+		configToReturn.UploadRetryBaseDelay = givenConfig.UploadRetryBaseDelay
+	}
+	if givenConfig.UploadRetryMaxDelay != 0 {
+		configToReturn.UploadRetryMaxDelay = givenConfig.UploadRetryMaxDelay
+	}
 
 	configToReturn.DebugAddr = givenConfig.DebugAddr
+	configToReturn.DefaultEgressRules = givenConfig.DefaultEgressRules
+	configToReturn.DefaultBandwidthRate = givenConfig.DefaultBandwidthRate
+	configToReturn.MaxBandwidthRate = givenConfig.MaxBandwidthRate
 
 	return configToReturn
 }
@@ -1,6 +1,9 @@
 package integration_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -235,6 +238,9 @@ var _ = Describe("Main", func() {
 				RegistryPruningInterval: pruningInterval,
 				DebugAddr:               debugAddr,
 				ContainerInodeLimit:     245000,
+				DefaultEgressRules:      "10.0.0.0/8:53",
+				DefaultBandwidthRate:    1024,
+				MaxBandwidthRate:        4096,
 			})
 		})
 
@@ -424,6 +430,36 @@ var _ = Describe("Main", func() {
 				})
 			})
 
+			Context("when the requested bandwidth is over the maximum", func() {
+				BeforeEach(func() {
+					initializeContainerRequest = api.ContainerInitializationRequest{
+						BandwidthRate: 4097,
+					}
+				})
+
+				It("returns an error", func() {
+					Ω(err).Should(HaveOccurred())
+					Ω(err).Should(Equal(api.ErrLimitsInvalid))
+				})
+			})
+
+			Context("when an egress rule's network is not a CIDR", func() {
+				BeforeEach(func() {
+					initializeContainerRequest = api.ContainerInitializationRequest{
+						EgressRules: []api.EgressRule{{Network: "not-a-network", Port: 80}},
+					}
+				})
+
+				It("returns an error", func() {
+					Ω(err).Should(HaveOccurred())
+					Ω(err).Should(Equal(api.ErrLimitsInvalid))
+				})
+
+				It("does not create a container", func() {
+					Ω(fakeBackend.CreateCallCount()).Should(Equal(0))
+				})
+			})
+
 			Context("when the container specifies a docker root_fs", func() {
 				var expectedRootFS = "docker:///docker.com/my-image"
 
@@ -507,6 +543,96 @@ var _ = Describe("Main", func() {
 					})
 				})
 
+				It("limits the bandwidth to the default, bursting to the rate", func() {
+					limitedBandwidth := container.LimitBandwidthArgsForCall(0)
+					Ω(limitedBandwidth.RateInBytesPerSecond).Should(Equal(uint64(1024)))
+					Ω(limitedBandwidth.BurstRateInBytesPerSecond).Should(Equal(uint64(1024)))
+
+					Ω(initializedContainer.BandwidthRate).Should(Equal(uint64(1024)))
+					Ω(initializedContainer.BandwidthBurst).Should(Equal(uint64(1024)))
+				})
+
+				Context("when bandwidth is requested", func() {
+					BeforeEach(func() {
+						initializeContainerRequest.BandwidthRate = 2048
+						initializeContainerRequest.BandwidthBurst = 4096
+					})
+
+					It("limits the bandwidth to what was requested, and records it", func() {
+						limitedBandwidth := container.LimitBandwidthArgsForCall(0)
+						Ω(limitedBandwidth.RateInBytesPerSecond).Should(Equal(uint64(2048)))
+						Ω(limitedBandwidth.BurstRateInBytesPerSecond).Should(Equal(uint64(4096)))
+
+						c, err := executorClient.GetContainer(guid)
+						Ω(err).ShouldNot(HaveOccurred())
+						Ω(c.BandwidthRate).Should(Equal(uint64(2048)))
+						Ω(c.BandwidthBurst).Should(Equal(uint64(4096)))
+					})
+				})
+
+				Context("when limiting the bandwidth fails", func() {
+					BeforeEach(func() {
+						container.LimitBandwidthReturns(errors.New("oh no!"))
+					})
+
+					It("returns an error", func() {
+						Ω(err).Should(HaveOccurred())
+						Ω(err.Error()).Should(ContainSubstring("status: 500"))
+					})
+				})
+
+				It("allows egress to the default networks", func() {
+					Ω(container.NetOutCallCount()).Should(Equal(1))
+
+					network, port := container.NetOutArgsForCall(0)
+					Ω(network).Should(Equal("10.0.0.0/8"))
+					Ω(port).Should(Equal(uint32(53)))
+				})
+
+				Context("when egress rules are requested", func() {
+					BeforeEach(func() {
+						initializeContainerRequest.EgressRules = []api.EgressRule{
+							{Network: "192.168.1.0/24", Port: 8080},
+							{Network: "0.0.0.0/0"},
+						}
+					})
+
+					It("allows egress to the default networks, then the requested ones", func() {
+						Ω(container.NetOutCallCount()).Should(Equal(3))
+
+						network, port := container.NetOutArgsForCall(1)
+						Ω(network).Should(Equal("192.168.1.0/24"))
+						Ω(port).Should(Equal(uint32(8080)))
+
+						network, port = container.NetOutArgsForCall(2)
+						Ω(network).Should(Equal("0.0.0.0/0"))
+						Ω(port).Should(BeZero())
+					})
+
+					It("records the rules on the container", func() {
+						Ω(initializedContainer.EgressRules).Should(Equal([]api.EgressRule{
+							{Network: "10.0.0.0/8", Port: 53},
+							{Network: "192.168.1.0/24", Port: 8080},
+							{Network: "0.0.0.0/0"},
+						}))
+					})
+
+					Context("when allowing egress fails", func() {
+						BeforeEach(func() {
+							container.NetOutReturns(errors.New("oh no!"))
+						})
+
+						It("returns an error", func() {
+							Ω(err).Should(HaveOccurred())
+							Ω(err.Error()).Should(ContainSubstring("status: 500"))
+						})
+
+						It("destroys the container", func() {
+							Ω(fakeBackend.DestroyCallCount()).Should(Equal(1))
+						})
+					})
+				})
+
 				Context("when ports are exposed", func() {
 					BeforeEach(func() {
 						initializeContainerRequest = api.ContainerInitializationRequest{
@@ -694,6 +820,109 @@ var _ = Describe("Main", func() {
 				}))
 			})
 
+			It("shows the run's environment variables, leaving out the secret ones", func() {
+				guid, fakeContainer := initNewContainer()
+
+				process := new(wfakes.FakeProcess)
+				fakeContainer.RunReturns(process, nil)
+
+				err := executorClient.Run(
+					guid,
+					api.ContainerRunRequest{
+						Env: []api.EnvironmentVariable{
+							{Name: "PASSWORD", Value: "hunter2", Secret: true},
+							{Name: "HOME", Value: "/home/vcap"},
+						},
+						Actions: []models.ExecutorAction{
+							{Action: models.RunAction{Path: "ls"}},
+						},
+					},
+				)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				expectedEnv := []api.EnvironmentVariable{
+					{Name: "HOME", Value: "/home/vcap"},
+				}
+
+				container, err := executorClient.GetContainer(guid)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(container.Env).Should(Equal(expectedEnv))
+
+				containers, err := executorClient.ListContainers()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(containers).Should(HaveLen(1))
+				Ω(containers[0].Env).Should(Equal(expectedEnv))
+			})
+
+			Context("when the container runs out of memory and there is an eventURL", func() {
+				var callbackHandler *ghttp.Server
+				var containerGuid string
+
+				BeforeEach(func() {
+					callbackHandler = ghttp.NewServer()
+
+					var fakeContainer *wfakes.FakeContainer
+					containerGuid, fakeContainer = initNewContainer()
+
+					process := new(wfakes.FakeProcess)
+					process.WaitReturns(137, nil)
+					fakeContainer.RunReturns(process, nil)
+
+					fakeContainer.InfoReturns(warden.ContainerInfo{
+						Events: []string{api.OutOfMemoryEvent},
+					}, nil)
+
+					callbackHandler.AppendHandlers(
+						ghttp.CombineHandlers(
+							ghttp.VerifyRequest("PUT", "/event"),
+							ghttp.VerifyJSONRepresenting(api.ContainerEvent{
+								Guid:  containerGuid,
+								Event: api.OutOfMemoryEvent,
+							}),
+						),
+					)
+
+					err := executorClient.Run(
+						containerGuid,
+						api.ContainerRunRequest{
+							Actions: []models.ExecutorAction{
+								{Action: models.RunAction{Path: "ls"}},
+							},
+							EventURL: callbackHandler.URL() + "/event",
+						},
+					)
+					Ω(err).ShouldNot(HaveOccurred())
+				})
+
+				AfterEach(func() {
+					callbackHandler.Close()
+				})
+
+				It("sends the event to the eventURL", func() {
+					Eventually(callbackHandler.ReceivedRequests).Should(HaveLen(1))
+				})
+
+				It("records the event and the exit status in the container's run result", func() {
+					Eventually(func() string {
+						container, err := executorClient.GetContainer(containerGuid)
+						Ω(err).ShouldNot(HaveOccurred())
+						return container.State
+					}).Should(Equal(api.StateCompleted))
+
+					container, err := executorClient.GetContainer(containerGuid)
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(container.OOMKilled).Should(BeTrue())
+					Ω(container.Events).Should(Equal([]string{api.OutOfMemoryEvent}))
+
+					Ω(container.RunResult.OOMKilled).Should(BeTrue())
+					Ω(container.RunResult.Events).Should(Equal([]string{api.OutOfMemoryEvent}))
+					Ω(container.RunResult.ExitStatus).ShouldNot(BeNil())
+					Ω(*container.RunResult.ExitStatus).Should(Equal(137))
+					Ω(container.RunResult.FailureReason).Should(Equal("Exited with status 137 (out of memory)"))
+				})
+			})
+
 			Context("when there is a completeURL and metadata", func() {
 				var callbackHandler *ghttp.Server
 				var containerGuid string
@@ -734,12 +963,13 @@ var _ = Describe("Main", func() {
 						callbackHandler.AppendHandlers(
 							ghttp.CombineHandlers(
 								ghttp.VerifyRequest("PUT", "/result"),
-								ghttp.VerifyJSONRepresenting(api.ContainerRunResult{
+								verifyRunResult(api.ContainerRunResult{
 									Guid:          containerGuid,
 									Failed:        false,
 									FailureReason: "",
 									Result:        "",
-								}),
+									ExitStatus:    intPtr(0),
+								}, api.StepSucceeded),
 							),
 						)
 					})
@@ -802,12 +1032,12 @@ var _ = Describe("Main", func() {
 						callbackHandler.AppendHandlers(
 							ghttp.CombineHandlers(
 								ghttp.VerifyRequest("PUT", "/result"),
-								ghttp.VerifyJSONRepresenting(api.ContainerRunResult{
+								verifyRunResult(api.ContainerRunResult{
 									Guid:          containerGuid,
 									Failed:        true,
 									FailureReason: "process error: because i said so",
 									Result:        "",
-								}),
+								}, api.StepFailed),
 							),
 						)
 
@@ -837,6 +1067,77 @@ var _ = Describe("Main", func() {
 						Eventually(callbackHandler.ReceivedRequests).Should(HaveLen(1))
 					})
 				})
+
+				Context("and there are artifacts to collect", func() {
+					var callbackHandler *ghttp.Server
+
+					BeforeEach(func() {
+						callbackHandler = ghttp.NewServer()
+
+						artifact := &bytes.Buffer{}
+						tarWriter := tar.NewWriter(artifact)
+
+						err := tarWriter.WriteHeader(&tar.Header{Name: "core", Size: 4, Mode: 0644})
+						Ω(err).ShouldNot(HaveOccurred())
+
+						_, err = tarWriter.Write([]byte("core"))
+						Ω(err).ShouldNot(HaveOccurred())
+
+						err = tarWriter.Close()
+						Ω(err).ShouldNot(HaveOccurred())
+
+						fakeContainer.StreamOutReturns(ioutil.NopCloser(artifact), nil)
+
+						callbackHandler.AppendHandlers(
+							ghttp.VerifyRequest("POST", "/artifacts/core"),
+							ghttp.CombineHandlers(
+								ghttp.VerifyRequest("PUT", "/result"),
+								verifyRunResult(api.ContainerRunResult{
+									Guid:          containerGuid,
+									Failed:        true,
+									FailureReason: "process error: because i said so",
+									Artifacts: []api.ArtifactResult{
+										{
+											Path:  "/tmp/core",
+											URL:   callbackHandler.URL() + "/artifacts/core",
+											Files: 1,
+										},
+									},
+								}, api.StepFailed),
+							),
+						)
+
+						err = executorClient.Run(
+							containerGuid,
+							api.ContainerRunRequest{
+								Actions: []models.ExecutorAction{
+									{
+										Action: models.RunAction{
+											Path: "ls",
+										},
+									},
+								},
+								Artifacts: []api.Artifact{
+									{Path: "/tmp/core", To: callbackHandler.URL() + "/artifacts/core"},
+								},
+								CompleteURL: callbackHandler.URL() + "/result",
+							},
+						)
+
+						Ω(err).ShouldNot(HaveOccurred())
+					})
+
+					AfterEach(func() {
+						callbackHandler.Close()
+					})
+
+					It("uploads the artifacts before invoking the callback", func() {
+						Eventually(callbackHandler.ReceivedRequests).Should(HaveLen(2))
+
+						src := fakeContainer.StreamOutArgsForCall(0)
+						Ω(src).Should(Equal("/tmp/core"))
+					})
+				})
 			})
 		})
 
@@ -1050,3 +1351,27 @@ func startFakeLoggregatorServer(logConfirmations chan<- struct{}) (string, <-cha
 
 	return conn.LocalAddr().String(), logMessages
 }
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// verifyRunResult checks a run's result, expecting a single run step that
+// ended with the given status. When exactly the step ran is not checked.
+func verifyRunResult(expected api.ContainerRunResult, stepStatus string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var result api.ContainerRunResult
+
+		err := json.NewDecoder(r.Body).Decode(&result)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(result.Steps).Should(HaveLen(1))
+		Ω(result.Steps[0].Action).Should(Equal("run"))
+		Ω(result.Steps[0].Status).Should(Equal(stepStatus))
+		Ω(result.Steps[0].StartedAt).ShouldNot(BeZero())
+		Ω(result.Steps[0].EndedAt).Should(BeNumerically(">=", result.Steps[0].StartedAt))
+
+		result.Steps = nil
+		Ω(result).Should(Equal(expected))
+	}
+}
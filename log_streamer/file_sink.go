@@ -0,0 +1,224 @@
+package log_streamer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/gunk/timeprovider"
+	"github.com/cloudfoundry/loggregatorlib/logmessage"
+	"github.com/pivotal-golang/lager"
+)
+
+// ReleasedFileTTL is how long a released container's lines are dropped for.
+// Lines flushed late, e.g. by a multi-line timer, arrive well within it;
+// after it the container's guid may be used again.
+const ReleasedFileTTL = time.Minute
+
+type fileSink struct {
+	dir          string
+	maxSize      int64
+	maxFiles     int
+	timeProvider timeprovider.TimeProvider
+	logger       lager.Logger
+
+	lock  sync.Mutex
+	files map[string]*logFile
+}
+
+// logFile is a container's open log file. Once released it is closed for
+// good and its lines dropped, but it stays in the sink as a tombstone until
+// ReleasedFileTTL has passed, so a late line cannot create the file again.
+// released and releasedAt are only changed holding both the sink's and the
+// file's lock.
+type logFile struct {
+	lock       sync.Mutex
+	path       string
+	file       *os.File
+	size       int64
+	released   bool
+	releasedAt time.Time
+}
+
+// NewFileSink appends each container's lines to <container guid>.log in dir,
+// keeping the file open until the container is released. Once a file would
+// grow past maxSize it is rotated to <container guid>.log.1, shifting older
+// files along and keeping at most maxFiles of them. A maxSize of 0 never
+// rotates. Lines without a container guid go to a file named after their log
+// guid instead. A released container's files are left in dir for operators
+// to collect, so rotation bounds how much of it each container takes up.
+func NewFileSink(dir string, maxSize int64, maxFiles int, timeProvider timeprovider.TimeProvider, logger lager.Logger) Sink {
+	return &fileSink{
+		dir:          dir,
+		maxSize:      maxSize,
+		maxFiles:     maxFiles,
+		timeProvider: timeProvider,
+		logger:       logger.Session("file-sink"),
+		files:        map[string]*logFile{},
+	}
+}
+
+func (sink *fileSink) Emit(message *logmessage.LogMessage, metadata Metadata) {
+	key := metadata.ContainerGuid
+	if key == "" {
+		key = message.GetAppId()
+	}
+
+	logFile := sink.fileFor(key)
+	line := formatLogLine(message)
+
+	logFile.lock.Lock()
+	defer logFile.lock.Unlock()
+
+	if logFile.released {
+		return
+	}
+
+	if logFile.file == nil && !sink.open(logFile) {
+		return
+	}
+
+	if sink.maxSize > 0 && logFile.size > 0 && logFile.size+int64(len(line)) > sink.maxSize {
+		logFile.close()
+		sink.rotate(logFile.path)
+
+		if !sink.open(logFile) {
+			return
+		}
+	}
+
+	n, err := logFile.file.Write(line)
+	logFile.size += int64(n)
+	if err != nil {
+		sink.logger.Error("failed-to-write", err, lager.Data{"path": logFile.path})
+		logFile.close()
+	}
+}
+
+// ReleaseContainer closes the container's log file, keeping it and its
+// rotated files on disk, and drops any lines that arrive for it afterwards.
+func (sink *fileSink) ReleaseContainer(containerGuid string) {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+
+	now := sink.timeProvider.Time()
+
+	for key, file := range sink.files {
+		if file.released && now.Sub(file.releasedAt) >= ReleasedFileTTL {
+			delete(sink.files, key)
+		}
+	}
+
+	logFile := sink.fileForLocked(containerGuid, now)
+
+	logFile.lock.Lock()
+	defer logFile.lock.Unlock()
+
+	logFile.close()
+	logFile.released = true
+	logFile.releasedAt = now
+}
+
+func (sink *fileSink) fileFor(key string) *logFile {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+
+	return sink.fileForLocked(key, sink.timeProvider.Time())
+}
+
+func (sink *fileSink) fileForLocked(key string, now time.Time) *logFile {
+	file, ok := sink.files[key]
+	if !ok || (file.released && now.Sub(file.releasedAt) >= ReleasedFileTTL) {
+		file = &logFile{path: filepath.Join(sink.dir, logFileName(key))}
+		sink.files[key] = file
+	}
+
+	return file
+}
+
+func (sink *fileSink) open(logFile *logFile) bool {
+	err := logFile.open()
+	if err != nil {
+		sink.logger.Error("failed-to-open", err, lager.Data{"path": logFile.path})
+		return false
+	}
+
+	return true
+}
+
+func (sink *fileSink) rotate(path string) {
+	os.Remove(rotatedName(path, sink.maxFiles))
+
+	for i := sink.maxFiles - 1; i > 0; i-- {
+		os.Rename(rotatedName(path, i), rotatedName(path, i+1))
+	}
+
+	if sink.maxFiles > 0 {
+		os.Rename(path, rotatedName(path, 1))
+	} else {
+		os.Remove(path)
+	}
+}
+
+func (logFile *logFile) open() error {
+	file, err := os.OpenFile(logFile.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	logFile.file = file
+	logFile.size = info.Size()
+
+	return nil
+}
+
+func (logFile *logFile) close() {
+	if logFile.file != nil {
+		logFile.file.Close()
+		logFile.file = nil
+	}
+
+	logFile.size = 0
+}
+
+func rotatedName(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// guids come from API clients, so keep them from naming other paths
+func logFileName(guid string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, guid)
+
+	if name == "" || name == "." || name == ".." {
+		name = "_" + name
+	}
+
+	return name + ".log"
+}
+
+func formatLogLine(message *logmessage.LogMessage) []byte {
+	header := fmt.Sprintf(
+		"%s [%s/%s] %s ",
+		time.Unix(0, message.GetTimestamp()).UTC().Format(time.RFC3339Nano),
+		message.GetSourceName(),
+		message.GetSourceId(),
+		message.GetMessageType(),
+	)
+
+	line := append([]byte(header), message.GetMessage()...)
+	return append(line, '\n')
+}
@@ -0,0 +1,195 @@
+package log_streamer_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.google.com/p/goprotobuf/proto"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	"github.com/cloudfoundry/loggregatorlib/logmessage"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/cloudfoundry-incubator/executor/log_streamer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FileSink", func() {
+	var (
+		dir          string
+		maxSize      int64
+		maxFiles     int
+		timeProvider *faketimeprovider.FakeTimeProvider
+		sink         Sink
+	)
+
+	messageFor := func(guid string, msg string, messageType logmessage.LogMessage_MessageType) *logmessage.LogMessage {
+		return &logmessage.LogMessage{
+			AppId:       proto.String(guid),
+			SourceName:  proto.String("App"),
+			SourceId:    proto.String("0"),
+			Message:     []byte(msg),
+			MessageType: messageType.Enum(),
+			Timestamp:   proto.Int64(time.Date(2014, time.July, 4, 12, 30, 0, 0, time.UTC).UnixNano()),
+		}
+	}
+
+	contents := func(name string) string {
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		Ω(err).ShouldNot(HaveOccurred())
+		return string(content)
+	}
+
+	line := func(msg string) string {
+		return "2014-07-04T12:30:00Z [App/0] OUT " + msg + "\n"
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "file-sink")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		maxSize = 0
+		maxFiles = 2
+		timeProvider = faketimeprovider.New(time.Now())
+	})
+
+	JustBeforeEach(func() {
+		sink = NewFileSink(dir, maxSize, maxFiles, timeProvider, lagertest.NewTestLogger("test"))
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("appends each container's lines to its own file", func() {
+		sink.Emit(messageFor("guid-a", "hello", logmessage.LogMessage_OUT), Metadata{})
+		sink.Emit(messageFor("guid-b", "other", logmessage.LogMessage_OUT), Metadata{})
+		sink.Emit(messageFor("guid-a", "oops", logmessage.LogMessage_ERR), Metadata{})
+
+		Ω(contents("guid-a.log")).Should(Equal(line("hello") + "2014-07-04T12:30:00Z [App/0] ERR oops\n"))
+		Ω(contents("guid-b.log")).Should(Equal(line("other")))
+	})
+
+	It("names files after the container guid when there is one", func() {
+		sink.Emit(messageFor("log-guid", "hello", logmessage.LogMessage_OUT), Metadata{ContainerGuid: "container-guid"})
+
+		Ω(contents("container-guid.log")).Should(Equal(line("hello")))
+	})
+
+	Describe("releasing a container", func() {
+		BeforeEach(func() {
+			maxSize = int64(len(line("first")) + 1)
+		})
+
+		It("keeps its files, rotated files included", func() {
+			sink.Emit(messageFor("guid-a", "first", logmessage.LogMessage_OUT), Metadata{ContainerGuid: "guid-a"})
+			sink.Emit(messageFor("guid-a", "second", logmessage.LogMessage_OUT), Metadata{ContainerGuid: "guid-a"})
+
+			ReleaseContainer(sink, "guid-a")
+
+			Ω(contents("guid-a.log")).Should(Equal(line("second")))
+			Ω(contents("guid-a.log.1")).Should(Equal(line("first")))
+		})
+
+		It("drops lines that arrive for it afterwards", func() {
+			sink.Emit(messageFor("guid-a", "first", logmessage.LogMessage_OUT), Metadata{ContainerGuid: "guid-a"})
+
+			ReleaseContainer(sink, "guid-a")
+
+			sink.Emit(messageFor("guid-a", "late", logmessage.LogMessage_OUT), Metadata{ContainerGuid: "guid-a"})
+
+			Ω(contents("guid-a.log")).Should(Equal(line("first")))
+		})
+
+		It("does not create a file for lines that arrive after it was released without any", func() {
+			ReleaseContainer(sink, "guid-a")
+
+			sink.Emit(messageFor("guid-a", "late", logmessage.LogMessage_OUT), Metadata{ContainerGuid: "guid-a"})
+
+			_, err := os.Stat(filepath.Join(dir, "guid-a.log"))
+			Ω(os.IsNotExist(err)).Should(BeTrue())
+		})
+
+		It("leaves other containers' files alone", func() {
+			sink.Emit(messageFor("guid-b", "other", logmessage.LogMessage_OUT), Metadata{ContainerGuid: "guid-b"})
+
+			ReleaseContainer(sink, "guid-a")
+
+			sink.Emit(messageFor("guid-b", "more", logmessage.LogMessage_OUT), Metadata{ContainerGuid: "guid-b"})
+
+			Ω(contents("guid-b.log")).Should(Equal(line("more")))
+			Ω(contents("guid-b.log.1")).Should(Equal(line("other")))
+		})
+
+		Context("once the released file TTL has passed", func() {
+			It("writes the guid's lines again", func() {
+				sink.Emit(messageFor("guid-a", "first", logmessage.LogMessage_OUT), Metadata{ContainerGuid: "guid-a"})
+
+				ReleaseContainer(sink, "guid-a")
+				timeProvider.Increment(ReleasedFileTTL)
+
+				sink.Emit(messageFor("guid-a", "again", logmessage.LogMessage_OUT), Metadata{ContainerGuid: "guid-a"})
+
+				Ω(contents("guid-a.log")).Should(Equal(line("again")))
+				Ω(contents("guid-a.log.1")).Should(Equal(line("first")))
+			})
+		})
+
+		It("is released through a multi sink", func() {
+			sink = NewMultiSink(sink)
+
+			sink.Emit(messageFor("guid-a", "first", logmessage.LogMessage_OUT), Metadata{ContainerGuid: "guid-a"})
+
+			ReleaseContainer(sink, "guid-a")
+
+			sink.Emit(messageFor("guid-a", "late", logmessage.LogMessage_OUT), Metadata{ContainerGuid: "guid-a"})
+
+			Ω(contents("guid-a.log")).Should(Equal(line("first")))
+		})
+	})
+
+	It("keeps guids from escaping the directory", func() {
+		sink.Emit(messageFor("../escaped", "hello", logmessage.LogMessage_OUT), Metadata{})
+
+		Ω(contents(".._escaped.log")).Should(Equal(line("hello")))
+	})
+
+	Context("when a file would grow past the max size", func() {
+		BeforeEach(func() {
+			maxSize = int64(len(line("first")) + 1)
+		})
+
+		It("rotates it, keeping up to max files", func() {
+			sink.Emit(messageFor("guid-a", "first", logmessage.LogMessage_OUT), Metadata{})
+			sink.Emit(messageFor("guid-a", "second", logmessage.LogMessage_OUT), Metadata{})
+			sink.Emit(messageFor("guid-a", "third", logmessage.LogMessage_OUT), Metadata{})
+			sink.Emit(messageFor("guid-a", "fourth", logmessage.LogMessage_OUT), Metadata{})
+
+			Ω(contents("guid-a.log")).Should(Equal(line("fourth")))
+			Ω(contents("guid-a.log.1")).Should(Equal(line("third")))
+			Ω(contents("guid-a.log.2")).Should(Equal(line("second")))
+
+			_, err := os.Stat(filepath.Join(dir, "guid-a.log.3"))
+			Ω(os.IsNotExist(err)).Should(BeTrue())
+		})
+
+		Context("when no rotated files are kept", func() {
+			BeforeEach(func() {
+				maxFiles = 0
+			})
+
+			It("starts the file over", func() {
+				sink.Emit(messageFor("guid-a", "first", logmessage.LogMessage_OUT), Metadata{})
+				sink.Emit(messageFor("guid-a", "second", logmessage.LogMessage_OUT), Metadata{})
+
+				Ω(contents("guid-a.log")).Should(Equal(line("second")))
+
+				_, err := os.Stat(filepath.Join(dir, "guid-a.log.1"))
+				Ω(os.IsNotExist(err)).Should(BeTrue())
+			})
+		})
+	})
+})
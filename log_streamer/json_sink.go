@@ -0,0 +1,69 @@
+package log_streamer
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/cloudfoundry/loggregatorlib/logmessage"
+	"github.com/pivotal-golang/lager"
+)
+
+// JSONRecord is a line as the JSON sink writes it. Timestamp is in
+// nanoseconds since the epoch.
+type JSONRecord struct {
+	ContainerGuid string `json:"container_guid"`
+	Handle        string `json:"handle,omitempty"`
+	Step          string `json:"step,omitempty"`
+	Action        string `json:"action,omitempty"`
+	LogGuid       string `json:"log_guid"`
+	SourceName    string `json:"source_name"`
+	SourceId      string `json:"source_id"`
+	Stream        string `json:"stream"`
+	Timestamp     int64  `json:"timestamp"`
+	Message       string `json:"message"`
+}
+
+type jsonSink struct {
+	logger lager.Logger
+
+	lock    sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSONSink writes each line to destination as a JSONRecord on a line of
+// its own.
+func NewJSONSink(destination io.Writer, logger lager.Logger) Sink {
+	return &jsonSink{
+		logger:  logger.Session("json-sink"),
+		encoder: json.NewEncoder(destination),
+	}
+}
+
+func (sink *jsonSink) Emit(message *logmessage.LogMessage, metadata Metadata) {
+	stream := "stdout"
+	if message.GetMessageType() == logmessage.LogMessage_ERR {
+		stream = "stderr"
+	}
+
+	record := JSONRecord{
+		ContainerGuid: metadata.ContainerGuid,
+		Handle:        metadata.Handle,
+		Step:          metadata.Step,
+		Action:        metadata.Action,
+		LogGuid:       message.GetAppId(),
+		SourceName:    message.GetSourceName(),
+		SourceId:      message.GetSourceId(),
+		Stream:        stream,
+		Timestamp:     message.GetTimestamp(),
+		Message:       string(message.GetMessage()),
+	}
+
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+
+	err := sink.encoder.Encode(record)
+	if err != nil {
+		sink.logger.Error("failed-to-write", err)
+	}
+}
@@ -0,0 +1,111 @@
+package log_streamer_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"code.google.com/p/goprotobuf/proto"
+	"github.com/cloudfoundry/loggregatorlib/logmessage"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/cloudfoundry-incubator/executor/log_streamer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JSONSink", func() {
+	var (
+		destination *bytes.Buffer
+		sink        Sink
+		metadata    Metadata
+	)
+
+	records := func() []JSONRecord {
+		records := []JSONRecord{}
+
+		for _, line := range strings.Split(strings.TrimSuffix(destination.String(), "\n"), "\n") {
+			var record JSONRecord
+			err := json.Unmarshal([]byte(line), &record)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			records = append(records, record)
+		}
+
+		return records
+	}
+
+	BeforeEach(func() {
+		destination = new(bytes.Buffer)
+		sink = NewJSONSink(destination, lagertest.NewTestLogger("test"))
+
+		metadata = Metadata{
+			ContainerGuid: "container-guid",
+			Handle:        "the-handle",
+			Step:          "emit_progress/run",
+			Action:        "run",
+		}
+	})
+
+	It("writes a record per line, with where it came from", func() {
+		sink.Emit(&logmessage.LogMessage{
+			AppId:       proto.String("log-guid"),
+			SourceName:  proto.String("STG"),
+			SourceId:    proto.String("1"),
+			Message:     []byte("hello"),
+			MessageType: logmessage.LogMessage_OUT.Enum(),
+			Timestamp:   proto.Int64(1234),
+		}, metadata)
+
+		sink.Emit(&logmessage.LogMessage{
+			AppId:       proto.String("log-guid"),
+			SourceName:  proto.String("STG"),
+			SourceId:    proto.String("1"),
+			Message:     []byte("oops"),
+			MessageType: logmessage.LogMessage_ERR.Enum(),
+			Timestamp:   proto.Int64(5678),
+		}, metadata)
+
+		Ω(records()).Should(Equal([]JSONRecord{
+			{
+				ContainerGuid: "container-guid",
+				Handle:        "the-handle",
+				Step:          "emit_progress/run",
+				Action:        "run",
+				LogGuid:       "log-guid",
+				SourceName:    "STG",
+				SourceId:      "1",
+				Stream:        "stdout",
+				Timestamp:     1234,
+				Message:       "hello",
+			},
+			{
+				ContainerGuid: "container-guid",
+				Handle:        "the-handle",
+				Step:          "emit_progress/run",
+				Action:        "run",
+				LogGuid:       "log-guid",
+				SourceName:    "STG",
+				SourceId:      "1",
+				Stream:        "stderr",
+				Timestamp:     5678,
+				Message:       "oops",
+			},
+		}))
+	})
+
+	Context("when used by a streamer", func() {
+		It("gets the streamer's metadata", func() {
+			index := 0
+			streamer := NewWithOptions("log-guid", "STG", &index, sink, Options{Metadata: metadata})
+
+			fmt.Fprintln(streamer.Stdout(), "hello")
+
+			Ω(records()).Should(HaveLen(1))
+			Ω(records()[0].Step).Should(Equal("emit_progress/run"))
+			Ω(records()[0].Handle).Should(Equal("the-handle"))
+			Ω(records()[0].Message).Should(Equal("hello"))
+		})
+	})
+})
@@ -4,7 +4,8 @@ import (
 	"io"
 	"strconv"
 
-	"github.com/cloudfoundry/loggregatorlib/emitter"
+	"github.com/cloudfoundry-incubator/executor/redaction"
+	"github.com/cloudfoundry/gunk/timeprovider"
 	"github.com/cloudfoundry/loggregatorlib/logmessage"
 )
 
@@ -17,16 +18,38 @@ type LogStreamer interface {
 	Flush()
 }
 
+// Options tune how a streamer sends the lines written to it.
+type Options struct {
+	// shared by every streamer for the same container; nil lets everything
+	// through
+	Limiter *RateLimiter
+
+	// nil sends every line on its own
+	MultiLine *MultiLine
+
+	// if set, each message is prefixed with the time its first line was
+	// started, in RFC3339
+	Timestamps timeprovider.TimeProvider
+
+	// masks secrets in what is written, before it is split into messages;
+	// nil masks nothing
+	Redactor *redaction.Redactor
+
+	// passed along with every line
+	Metadata Metadata
+}
+
 type logStreamer struct {
-	stdout *streamDestination
-	stderr *streamDestination
+	stdout  *streamDestination
+	stderr  *streamDestination
+	limiter *RateLimiter
 }
 
-func New(guid string, sourceName string, index *int, loggregatorEmitter emitter.Emitter) LogStreamer {
-	if guid == "" {
-		return noopStreamer{}
-	}
+func New(guid string, sourceName string, index *int, sink Sink) LogStreamer {
+	return NewWithOptions(guid, sourceName, index, sink, Options{})
+}
 
+func NewWithOptions(guid string, sourceName string, index *int, sink Sink, options Options) LogStreamer {
 	sourceIndex := "0"
 	if index != nil {
 		sourceIndex = strconv.Itoa(*index)
@@ -38,7 +61,8 @@ func New(guid string, sourceName string, index *int, loggregatorEmitter emitter.
 			sourceName,
 			sourceIndex,
 			logmessage.LogMessage_OUT,
-			loggregatorEmitter,
+			sink,
+			options,
 		),
 
 		stderr: newStreamDestination(
@@ -46,8 +70,11 @@ func New(guid string, sourceName string, index *int, loggregatorEmitter emitter.
 			sourceName,
 			sourceIndex,
 			logmessage.LogMessage_ERR,
-			loggregatorEmitter,
+			sink,
+			options,
 		),
+
+		limiter: options.Limiter,
 	}
 }
 
@@ -60,6 +87,14 @@ func (e *logStreamer) Stderr() io.Writer {
 }
 
 func (e *logStreamer) Flush() {
-	e.stdout.flush()
-	e.stderr.flush()
+	e.stdout.flushWritten()
+	e.stderr.flushWritten()
+
+	e.stdout.flushLines()
+	e.stderr.flushLines()
+
+	dropped := e.limiter.takeDropped()
+	if dropped > 0 {
+		e.stderr.emitDropped(dropped)
+	}
 }
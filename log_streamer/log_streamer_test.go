@@ -21,7 +21,7 @@ var _ = Describe("LogStreamer", func() {
 
 	BeforeEach(func() {
 		loggregatorEmitter = NewFakeLoggregatorEmmitter()
-		streamer = New(guid, sourceName, &index, loggregatorEmitter)
+		streamer = New(guid, sourceName, &index, NewLoggregatorSink(loggregatorEmitter))
 	})
 
 	Context("when told to emit", func() {
@@ -203,7 +203,7 @@ var _ = Describe("LogStreamer", func() {
 
 	Context("when there is no app guid", func() {
 		It("does nothing when told to emit or flush", func() {
-			streamer = New("", sourceName, &index, loggregatorEmitter)
+			streamer = New("", sourceName, &index, NewLoggregatorSink(loggregatorEmitter))
 
 			streamer.Stdout().Write([]byte("hi"))
 			streamer.Stderr().Write([]byte("hi"))
@@ -211,11 +211,22 @@ var _ = Describe("LogStreamer", func() {
 
 			Ω(loggregatorEmitter.Emissions).Should(BeEmpty())
 		})
+
+		It("still emits to the other sinks", func() {
+			otherSink := &lockedSink{}
+			streamer = New("", sourceName, &index, NewMultiSink(NewLoggregatorSink(loggregatorEmitter), otherSink))
+
+			fmt.Fprintln(streamer.Stdout(), "hi")
+			streamer.Flush()
+
+			Ω(loggregatorEmitter.Emissions).Should(BeEmpty())
+			Ω(otherSink.Messages()).Should(Equal([]string{"hi"}))
+		})
 	})
 
 	Context("when there is no source index", func() {
 		It("defaults to 0", func() {
-			streamer = New(guid, sourceName, nil, loggregatorEmitter)
+			streamer = New(guid, sourceName, nil, NewLoggregatorSink(loggregatorEmitter))
 
 			streamer.Stdout().Write([]byte("hi"))
 			streamer.Flush()
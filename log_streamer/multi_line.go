@@ -0,0 +1,149 @@
+package log_streamer
+
+import (
+	"errors"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/executor/api"
+)
+
+const DefaultMultiLineFlushTimeout = time.Second
+
+var ErrInvalidMultiLineConfig = errors.New("invalid multi-line config")
+
+// MultiLine groups a line with the lines continuing it, like the frames of a
+// stack trace, into a single message.
+type MultiLine struct {
+	// lines matching Continuation continue the one before them; if nil,
+	// indented lines do
+	Continuation *regexp.Regexp
+
+	// a group is sent once it would grow past MaxSize bytes, or once no line
+	// has come for FlushTimeout
+	MaxSize      int
+	FlushTimeout time.Duration
+}
+
+// MultiLineFor validates a container's multi-line config, filling in
+// defaults. It returns nil if config is.
+func MultiLineFor(config *api.MultiLineConfig) (*MultiLine, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	if config.MaxSizeInBytes < 0 || config.FlushTimeout < 0 {
+		return nil, ErrInvalidMultiLineConfig
+	}
+
+	multiLine := &MultiLine{
+		MaxSize:      config.MaxSizeInBytes,
+		FlushTimeout: config.FlushTimeout,
+	}
+
+	if config.ContinuationPattern != "" {
+		continuation, err := regexp.Compile(config.ContinuationPattern)
+		if err != nil {
+			return nil, ErrInvalidMultiLineConfig
+		}
+
+		multiLine.Continuation = continuation
+	}
+
+	if multiLine.MaxSize == 0 || multiLine.MaxSize > MAX_MESSAGE_SIZE {
+		multiLine.MaxSize = MAX_MESSAGE_SIZE
+	}
+
+	if multiLine.FlushTimeout == 0 {
+		multiLine.FlushTimeout = DefaultMultiLineFlushTimeout
+	}
+
+	return multiLine, nil
+}
+
+func (multiLine *MultiLine) continues(line []byte) bool {
+	if multiLine.Continuation != nil {
+		return multiLine.Continuation.Match(line)
+	}
+
+	return len(line) > 0 && (line[0] == ' ' || line[0] == '\t')
+}
+
+// lineGrouper sends each group with the time its first line was started.
+type lineGrouper struct {
+	multiLine *MultiLine
+	maxSize   int
+	send      func([]byte, time.Time)
+
+	lock      sync.Mutex
+	pending   []byte
+	pendingAt time.Time
+	timer     *time.Timer
+}
+
+func newLineGrouper(multiLine *MultiLine, maxSize int, send func([]byte, time.Time)) *lineGrouper {
+	if multiLine.MaxSize < maxSize {
+		maxSize = multiLine.MaxSize
+	}
+
+	return &lineGrouper{
+		multiLine: multiLine,
+		maxSize:   maxSize,
+		send:      send,
+	}
+}
+
+func (grouper *lineGrouper) add(line []byte, startedAt time.Time) {
+	grouper.lock.Lock()
+	defer grouper.lock.Unlock()
+
+	if len(grouper.pending) > 0 {
+		if grouper.multiLine.continues(line) && len(grouper.pending)+1+len(line) <= grouper.maxSize {
+			grouper.pending = append(grouper.pending, '\n')
+			grouper.pending = append(grouper.pending, line...)
+			grouper.resetTimer()
+			return
+		}
+
+		grouper.send(grouper.pending, grouper.pendingAt)
+	}
+
+	grouper.pending = line
+	grouper.pendingAt = startedAt
+	grouper.resetTimer()
+}
+
+func (grouper *lineGrouper) flush() {
+	grouper.lock.Lock()
+	defer grouper.lock.Unlock()
+
+	if grouper.timer != nil {
+		grouper.timer.Stop()
+	}
+
+	grouper.sendPending()
+}
+
+func (grouper *lineGrouper) resetTimer() {
+	if grouper.timer == nil {
+		grouper.timer = time.AfterFunc(grouper.multiLine.FlushTimeout, grouper.timedOut)
+		return
+	}
+
+	grouper.timer.Reset(grouper.multiLine.FlushTimeout)
+}
+
+func (grouper *lineGrouper) timedOut() {
+	grouper.lock.Lock()
+	defer grouper.lock.Unlock()
+
+	grouper.sendPending()
+}
+
+func (grouper *lineGrouper) sendPending() {
+	if len(grouper.pending) > 0 {
+		grouper.send(grouper.pending, grouper.pendingAt)
+		grouper.pending = nil
+	}
+}
@@ -0,0 +1,171 @@
+package log_streamer_test
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/executor/api"
+	"github.com/cloudfoundry/loggregatorlib/logmessage"
+
+	. "github.com/cloudfoundry-incubator/executor/log_streamer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type lockedSink struct {
+	lock     sync.Mutex
+	messages []string
+}
+
+func (sink *lockedSink) Emit(message *logmessage.LogMessage, metadata Metadata) {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+
+	sink.messages = append(sink.messages, string(message.GetMessage()))
+}
+
+func (sink *lockedSink) Messages() []string {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+
+	return append([]string{}, sink.messages...)
+}
+
+var _ = Describe("MultiLine", func() {
+	var (
+		sink      *lockedSink
+		multiLine *MultiLine
+		streamer  LogStreamer
+	)
+
+	index := 0
+
+	BeforeEach(func() {
+		sink = &lockedSink{}
+		multiLine = &MultiLine{
+			MaxSize:      MAX_MESSAGE_SIZE,
+			FlushTimeout: time.Hour,
+		}
+	})
+
+	JustBeforeEach(func() {
+		streamer = NewWithOptions("the-guid", "the-source-name", &index, sink, Options{MultiLine: multiLine})
+	})
+
+	Context("without a continuation pattern", func() {
+		It("groups indented lines with the one before them", func() {
+			fmt.Fprint(streamer.Stdout(), "Exception in thread main\n\tat Foo.bar\n\tat Foo.main\nnext line\n")
+			streamer.Flush()
+
+			Ω(sink.Messages()).Should(Equal([]string{
+				"Exception in thread main\n\tat Foo.bar\n\tat Foo.main",
+				"next line",
+			}))
+		})
+	})
+
+	Context("with a continuation pattern", func() {
+		BeforeEach(func() {
+			multiLine.Continuation = regexp.MustCompile(`^(\s+at |Caused by:)`)
+		})
+
+		It("groups the lines matching it with the one before them", func() {
+			fmt.Fprint(streamer.Stdout(), "boom\n  at a\nCaused by: oops\n  at b\n  not a frame\n")
+			streamer.Flush()
+
+			Ω(sink.Messages()).Should(Equal([]string{
+				"boom\n  at a\nCaused by: oops\n  at b",
+				"  not a frame",
+			}))
+		})
+	})
+
+	It("holds a line back until it knows nothing continues it", func() {
+		fmt.Fprint(streamer.Stdout(), "first\n")
+		Ω(sink.Messages()).Should(BeEmpty())
+
+		fmt.Fprint(streamer.Stdout(), "second\n")
+		Ω(sink.Messages()).Should(Equal([]string{"first"}))
+	})
+
+	It("keeps stdout and stderr apart", func() {
+		fmt.Fprint(streamer.Stdout(), "out\n")
+		fmt.Fprint(streamer.Stderr(), " err\n")
+		streamer.Flush()
+
+		Ω(sink.Messages()).Should(Equal([]string{"out", " err"}))
+	})
+
+	Context("when a group would grow past the max size", func() {
+		BeforeEach(func() {
+			multiLine.MaxSize = 10
+		})
+
+		It("starts another", func() {
+			fmt.Fprint(streamer.Stdout(), "12345\n 789\n abc\n")
+			streamer.Flush()
+
+			Ω(sink.Messages()).Should(Equal([]string{"12345\n 789", " abc"}))
+		})
+	})
+
+	Context("when no line comes for the flush timeout", func() {
+		BeforeEach(func() {
+			multiLine.FlushTimeout = 50 * time.Millisecond
+		})
+
+		It("sends the group", func() {
+			fmt.Fprint(streamer.Stdout(), "boom\n at a\n")
+
+			Eventually(sink.Messages).Should(Equal([]string{"boom\n at a"}))
+		})
+	})
+
+	Describe("MultiLineFor", func() {
+		It("is nil without a config", func() {
+			Ω(MultiLineFor(nil)).Should(BeNil())
+		})
+
+		It("fills in defaults", func() {
+			multiLine, err := MultiLineFor(&api.MultiLineConfig{})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(multiLine.Continuation).Should(BeNil())
+			Ω(multiLine.MaxSize).Should(Equal(MAX_MESSAGE_SIZE))
+			Ω(multiLine.FlushTimeout).Should(Equal(DefaultMultiLineFlushTimeout))
+		})
+
+		It("caps the max size at the largest message", func() {
+			multiLine, err := MultiLineFor(&api.MultiLineConfig{MaxSizeInBytes: 2 * MAX_MESSAGE_SIZE})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(multiLine.MaxSize).Should(Equal(MAX_MESSAGE_SIZE))
+		})
+
+		It("compiles the continuation pattern", func() {
+			multiLine, err := MultiLineFor(&api.MultiLineConfig{
+				ContinuationPattern: "^\\s",
+				MaxSizeInBytes:      100,
+				FlushTimeout:        time.Second,
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(multiLine.Continuation.String()).Should(Equal("^\\s"))
+			Ω(multiLine.MaxSize).Should(Equal(100))
+			Ω(multiLine.FlushTimeout).Should(Equal(time.Second))
+		})
+
+		It("rejects invalid configs", func() {
+			for _, config := range []api.MultiLineConfig{
+				{ContinuationPattern: "("},
+				{MaxSizeInBytes: -1},
+				{FlushTimeout: -time.Second},
+			} {
+				_, err := MultiLineFor(&config)
+				Ω(err).Should(Equal(ErrInvalidMultiLineConfig), fmt.Sprint(config))
+			}
+		})
+	})
+})
@@ -0,0 +1,159 @@
+package log_streamer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/gunk/timeprovider"
+)
+
+// RateLimit bounds how much a container may log. A zero rate leaves that
+// dimension unlimited; a zero burst allows one second's worth. A line bigger
+// than ByteBurst costs a full bucket, so it still gets through once the
+// bucket has filled up.
+type RateLimit struct {
+	LinesPerSecond int
+	BytesPerSecond int
+
+	LineBurst int
+	ByteBurst int
+}
+
+func (limit RateLimit) Enabled() bool {
+	return limit.LinesPerSecond > 0 || limit.BytesPerSecond > 0
+}
+
+// RateLimiter is a token bucket shared by every streamer of one container.
+// Lines over the limit are dropped and counted; the count is reported, both
+// to the log stream and to onDrop, once lines get through again or the
+// streamer is flushed.
+type RateLimiter struct {
+	lines        *bucket
+	bytes        *bucket
+	timeProvider timeprovider.TimeProvider
+	onDrop       func(lines int)
+
+	lock    sync.Mutex
+	dropped int
+}
+
+func NewRateLimiter(limit RateLimit, timeProvider timeprovider.TimeProvider, onDrop func(lines int)) *RateLimiter {
+	now := timeProvider.Time()
+
+	return &RateLimiter{
+		lines:        newBucket(limit.LinesPerSecond, limit.LineBurst, now),
+		bytes:        newBucket(limit.BytesPerSecond, limit.ByteBurst, now),
+		timeProvider: timeProvider,
+		onDrop:       onDrop,
+	}
+}
+
+// allow reports whether a line of size bytes may be emitted, along with how
+// many lines were dropped before it.
+func (limiter *RateLimiter) allow(size int) (bool, int) {
+	if limiter == nil {
+		return true, 0
+	}
+
+	limiter.lock.Lock()
+
+	now := limiter.timeProvider.Time()
+	limiter.lines.refill(now)
+	limiter.bytes.refill(now)
+
+	if !limiter.lines.has(1) || !limiter.bytes.has(size) {
+		limiter.dropped++
+		limiter.lock.Unlock()
+		return false, 0
+	}
+
+	limiter.lines.take(1)
+	limiter.bytes.take(size)
+
+	dropped := limiter.dropped
+	limiter.dropped = 0
+	limiter.lock.Unlock()
+
+	limiter.reportDropped(dropped)
+
+	return true, dropped
+}
+
+// takeDropped returns how many lines have been dropped since they were last
+// reported.
+func (limiter *RateLimiter) takeDropped() int {
+	if limiter == nil {
+		return 0
+	}
+
+	limiter.lock.Lock()
+	dropped := limiter.dropped
+	limiter.dropped = 0
+	limiter.lock.Unlock()
+
+	limiter.reportDropped(dropped)
+
+	return dropped
+}
+
+func (limiter *RateLimiter) reportDropped(dropped int) {
+	if dropped > 0 && limiter.onDrop != nil {
+		limiter.onDrop(dropped)
+	}
+}
+
+type bucket struct {
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newBucket(rate, burst int, now time.Time) *bucket {
+	if rate <= 0 {
+		return nil
+	}
+
+	if burst <= 0 {
+		burst = rate
+	}
+
+	return &bucket{
+		rate:     float64(rate),
+		capacity: float64(burst),
+		tokens:   float64(burst),
+		last:     now,
+	}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if b == nil || !now.After(b.last) {
+		return
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+
+	b.last = now
+}
+
+func (b *bucket) has(n int) bool {
+	return b == nil || b.tokens >= b.cost(n)
+}
+
+func (b *bucket) take(n int) {
+	if b != nil {
+		b.tokens -= b.cost(n)
+	}
+}
+
+// cost is capped at the capacity, or anything bigger could never be taken.
+func (b *bucket) cost(n int) float64 {
+	if float64(n) > b.capacity {
+		return b.capacity
+	}
+
+	return float64(n)
+}
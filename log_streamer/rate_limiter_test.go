@@ -0,0 +1,183 @@
+package log_streamer_test
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/executor/log_streamer"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	"github.com/cloudfoundry/loggregatorlib/logmessage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RateLimiter", func() {
+	var (
+		loggregatorEmitter *FakeLoggregatorEmitter
+		timeProvider       *faketimeprovider.FakeTimeProvider
+		limit              RateLimit
+		dropReports        []int
+		streamer           LogStreamer
+	)
+
+	index := 0
+
+	messages := func() []string {
+		msgs := []string{}
+		for _, emission := range loggregatorEmitter.Emissions {
+			msgs = append(msgs, string(emission.GetMessage()))
+		}
+		return msgs
+	}
+
+	BeforeEach(func() {
+		loggregatorEmitter = NewFakeLoggregatorEmmitter()
+		timeProvider = faketimeprovider.New(time.Now())
+		limit = RateLimit{}
+		dropReports = nil
+	})
+
+	JustBeforeEach(func() {
+		limiter := NewRateLimiter(limit, timeProvider, func(lines int) {
+			dropReports = append(dropReports, lines)
+		})
+
+		streamer = NewWithOptions("the-guid", "the-source-name", &index, NewLoggregatorSink(loggregatorEmitter), Options{Limiter: limiter})
+	})
+
+	Context("when limiting lines", func() {
+		BeforeEach(func() {
+			limit = RateLimit{LinesPerSecond: 2, LineBurst: 3}
+		})
+
+		It("lets a burst through and drops the rest", func() {
+			for i := 0; i < 5; i++ {
+				fmt.Fprintf(streamer.Stdout(), "line %d\n", i)
+			}
+
+			Ω(messages()).Should(Equal([]string{"line 0", "line 1", "line 2"}))
+		})
+
+		It("reports the dropped lines once output is allowed again", func() {
+			for i := 0; i < 5; i++ {
+				fmt.Fprintf(streamer.Stdout(), "line %d\n", i)
+			}
+
+			timeProvider.Increment(500 * time.Millisecond)
+
+			fmt.Fprintln(streamer.Stderr(), "line 5")
+			fmt.Fprintln(streamer.Stderr(), "line 6")
+
+			Ω(messages()).Should(Equal([]string{
+				"line 0",
+				"line 1",
+				"line 2",
+				"2 log lines dropped due to rate limit",
+				"line 5",
+			}))
+
+			Ω(loggregatorEmitter.Emissions[3].GetMessageType()).Should(Equal(logmessage.LogMessage_ERR))
+			Ω(dropReports).Should(Equal([]int{2}))
+		})
+
+		It("reports the dropped lines when flushed", func() {
+			for i := 0; i < 5; i++ {
+				fmt.Fprintf(streamer.Stdout(), "line %d\n", i)
+			}
+
+			streamer.Flush()
+
+			Ω(messages()).Should(HaveLen(4))
+			Ω(messages()[3]).Should(Equal("2 log lines dropped due to rate limit"))
+			Ω(loggregatorEmitter.Emissions[3].GetMessageType()).Should(Equal(logmessage.LogMessage_ERR))
+			Ω(dropReports).Should(Equal([]int{2}))
+
+			streamer.Flush()
+
+			Ω(messages()).Should(HaveLen(4))
+			Ω(dropReports).Should(Equal([]int{2}))
+		})
+
+		It("does not refill beyond the burst", func() {
+			timeProvider.Increment(time.Minute)
+
+			for i := 0; i < 5; i++ {
+				fmt.Fprintf(streamer.Stdout(), "line %d\n", i)
+			}
+
+			Ω(messages()).Should(HaveLen(3))
+		})
+
+		Context("when no burst is given", func() {
+			BeforeEach(func() {
+				limit = RateLimit{LinesPerSecond: 2}
+			})
+
+			It("allows a second's worth", func() {
+				for i := 0; i < 5; i++ {
+					fmt.Fprintf(streamer.Stdout(), "line %d\n", i)
+				}
+
+				Ω(messages()).Should(HaveLen(2))
+			})
+		})
+	})
+
+	Context("when limiting bytes", func() {
+		BeforeEach(func() {
+			limit = RateLimit{BytesPerSecond: 10}
+		})
+
+		It("drops lines that would exceed it", func() {
+			fmt.Fprintln(streamer.Stdout(), strings.Repeat("a", 6))
+			fmt.Fprintln(streamer.Stdout(), strings.Repeat("b", 6))
+			fmt.Fprintln(streamer.Stdout(), strings.Repeat("c", 4))
+
+			Ω(messages()).Should(Equal([]string{
+				"aaaaaa",
+				"1 log lines dropped due to rate limit",
+				"cccc",
+			}))
+			Ω(dropReports).Should(Equal([]int{1}))
+		})
+
+		Context("when a line is bigger than the burst", func() {
+			It("lets it through once the bucket is full, emptying it", func() {
+				fmt.Fprintln(streamer.Stdout(), strings.Repeat("a", 20))
+				fmt.Fprintln(streamer.Stdout(), "b")
+
+				timeProvider.Increment(time.Second)
+
+				fmt.Fprintln(streamer.Stdout(), strings.Repeat("c", 20))
+
+				Ω(messages()).Should(Equal([]string{
+					strings.Repeat("a", 20),
+					"1 log lines dropped due to rate limit",
+					strings.Repeat("c", 20),
+				}))
+			})
+		})
+	})
+
+	Context("when no limit is set", func() {
+		It("lets everything through", func() {
+			for i := 0; i < 100; i++ {
+				fmt.Fprintf(streamer.Stdout(), "line %d\n", i)
+			}
+
+			Ω(messages()).Should(HaveLen(100))
+			Ω(dropReports).Should(BeEmpty())
+		})
+	})
+
+	Describe("Enabled", func() {
+		It("is true if either rate is set", func() {
+			Ω(RateLimit{}.Enabled()).Should(BeFalse())
+			Ω(RateLimit{LineBurst: 10}.Enabled()).Should(BeFalse())
+			Ω(RateLimit{LinesPerSecond: 1}.Enabled()).Should(BeTrue())
+			Ω(RateLimit{BytesPerSecond: 1}.Enabled()).Should(BeTrue())
+		})
+	})
+})
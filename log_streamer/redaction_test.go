@@ -0,0 +1,59 @@
+package log_streamer_test
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/cloudfoundry-incubator/executor/log_streamer"
+	"github.com/cloudfoundry-incubator/executor/redaction"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Redaction", func() {
+	var (
+		sink     *lockedSink
+		streamer LogStreamer
+	)
+
+	index := 0
+
+	BeforeEach(func() {
+		sink = &lockedSink{}
+
+		streamer = NewWithOptions("the-guid", "the-source-name", &index, sink, Options{
+			Redactor: redaction.New([]string{"hunter2"}),
+		})
+	})
+
+	It("masks secrets in each line", func() {
+		fmt.Fprintln(streamer.Stdout(), "the password is hunter2")
+
+		Ω(sink.Messages()).Should(Equal([]string{"the password is [REDACTED]"}))
+	})
+
+	It("masks a secret split across writes", func() {
+		fmt.Fprint(streamer.Stdout(), "the password is hun")
+		streamer.Stdout().Write([]byte("ter2 ok\n"))
+
+		Ω(sink.Messages()).Should(Equal([]string{"the password is [REDACTED] ok"}))
+	})
+
+	It("masks a secret that crosses the message size limit", func() {
+		padding := strings.Repeat("x", MAX_MESSAGE_SIZE-3)
+		fmt.Fprintln(streamer.Stdout(), padding+"hunter2")
+
+		Ω(strings.Join(sink.Messages(), "")).Should(Equal(padding + "[REDACTED]"))
+		Ω(strings.Join(sink.Messages(), "")).ShouldNot(ContainSubstring("hun"))
+	})
+
+	It("sends what could have started a secret once flushed", func() {
+		fmt.Fprint(streamer.Stdout(), "the password is hunt")
+		Ω(sink.Messages()).Should(BeEmpty())
+
+		streamer.Flush()
+
+		Ω(sink.Messages()).Should(Equal([]string{"the password is hunt"}))
+	})
+})
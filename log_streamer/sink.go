@@ -0,0 +1,76 @@
+package log_streamer
+
+import (
+	"github.com/cloudfoundry/loggregatorlib/emitter"
+	"github.com/cloudfoundry/loggregatorlib/logmessage"
+)
+
+// Sink receives every line a container logs, along with where in the
+// container's run it came from.
+type Sink interface {
+	Emit(message *logmessage.LogMessage, metadata Metadata)
+}
+
+// ContainerReleaser is a Sink that holds on to something for each container,
+// such as an open file, until told the container is gone.
+type ContainerReleaser interface {
+	ReleaseContainer(containerGuid string)
+}
+
+// ReleaseContainer tells sink the container is gone, if it cares.
+func ReleaseContainer(sink Sink, containerGuid string) {
+	releaser, ok := sink.(ContainerReleaser)
+	if ok {
+		releaser.ReleaseContainer(containerGuid)
+	}
+}
+
+// Metadata is where in a container's run a line was logged. Step is the path
+// of actions leading to the one that logged it, such as
+// "emit_progress/run"; Action is the kind of that action.
+type Metadata struct {
+	ContainerGuid string
+	Handle        string
+	Step          string
+	Action        string
+}
+
+type loggregatorSink struct {
+	emitter emitter.Emitter
+}
+
+func NewLoggregatorSink(loggregatorEmitter emitter.Emitter) Sink {
+	return loggregatorSink{emitter: loggregatorEmitter}
+}
+
+// lines without a log guid have no app to go to in loggregator
+func (sink loggregatorSink) Emit(message *logmessage.LogMessage, metadata Metadata) {
+	if message.GetAppId() == "" {
+		return
+	}
+
+	sink.emitter.EmitLogMessage(message)
+}
+
+type multiSink []Sink
+
+// NewMultiSink emits every line to each of sinks, in order.
+func NewMultiSink(sinks ...Sink) Sink {
+	if len(sinks) == 1 {
+		return sinks[0]
+	}
+
+	return multiSink(sinks)
+}
+
+func (sinks multiSink) Emit(message *logmessage.LogMessage, metadata Metadata) {
+	for _, sink := range sinks {
+		sink.Emit(message, metadata)
+	}
+}
+
+func (sinks multiSink) ReleaseContainer(containerGuid string) {
+	for _, sink := range sinks {
+		ReleaseContainer(sink, containerGuid)
+	}
+}
@@ -0,0 +1,52 @@
+package log_streamer_test
+
+import (
+	"code.google.com/p/goprotobuf/proto"
+	"github.com/cloudfoundry/loggregatorlib/logmessage"
+
+	. "github.com/cloudfoundry-incubator/executor/log_streamer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sinks", func() {
+	var message *logmessage.LogMessage
+
+	BeforeEach(func() {
+		message = &logmessage.LogMessage{
+			AppId:       proto.String("the-guid"),
+			Message:     []byte("hello"),
+			MessageType: logmessage.LogMessage_OUT.Enum(),
+			Timestamp:   proto.Int64(0),
+		}
+	})
+
+	Describe("LoggregatorSink", func() {
+		It("emits to loggregator", func() {
+			loggregatorEmitter := NewFakeLoggregatorEmmitter()
+
+			NewLoggregatorSink(loggregatorEmitter).Emit(message, Metadata{})
+
+			Ω(loggregatorEmitter.Emissions).Should(Equal([]*logmessage.LogMessage{message}))
+		})
+	})
+
+	Describe("MultiSink", func() {
+		It("emits to every sink", func() {
+			first := NewFakeLoggregatorEmmitter()
+			second := NewFakeLoggregatorEmmitter()
+
+			sink := NewMultiSink(NewLoggregatorSink(first), NewLoggregatorSink(second))
+			sink.Emit(message, Metadata{})
+
+			Ω(first.Emissions).Should(Equal([]*logmessage.LogMessage{message}))
+			Ω(second.Emissions).Should(Equal([]*logmessage.LogMessage{message}))
+		})
+
+		Context("with no sinks", func() {
+			It("discards the line", func() {
+				NewMultiSink().Emit(message, Metadata{})
+			})
+		})
+	})
+})
@@ -1,57 +1,162 @@
 package log_streamer
 
 import (
+	"fmt"
+	"sync"
 	"time"
 	"unicode/utf8"
 
 	"code.google.com/p/goprotobuf/proto"
 
-	"github.com/cloudfoundry/loggregatorlib/emitter"
+	"github.com/cloudfoundry-incubator/executor/redaction"
+	"github.com/cloudfoundry/gunk/timeprovider"
 	"github.com/cloudfoundry/loggregatorlib/logmessage"
 )
 
+// room left in each message for the timestamp, which may end in a zone
+// offset
+const timestampSize = len(time.RFC3339) + 1
+
 type streamDestination struct {
-	guid        string
-	sourceName  string
-	sourceId    string
-	messageType logmessage.LogMessage_MessageType
-	emitter     emitter.Emitter
-	buffer      []byte
+	guid         string
+	sourceName   string
+	sourceId     string
+	messageType  logmessage.LogMessage_MessageType
+	sink         Sink
+	limiter      *RateLimiter
+	redactor     *redaction.Redactor
+	metadata     Metadata
+	timeProvider timeprovider.TimeProvider
+	lines        *lineGrouper
+	maxSize      int
+	buffer       []byte
+	startedAt    time.Time
+
+	// the end of what was written that may be the start of a secret
+	held string
+
+	// guards what has been written but not yet sent, as a process that was
+	// left running may still be writing while the streamer is flushed
+	lock sync.Mutex
 }
 
-func newStreamDestination(guid, sourceName, sourceId string, messageType logmessage.LogMessage_MessageType, em emitter.Emitter) *streamDestination {
-	return &streamDestination{
-		guid:        guid,
-		sourceName:  sourceName,
-		sourceId:    sourceId,
-		messageType: messageType,
-		emitter:     em,
-		buffer:      make([]byte, 0, MAX_MESSAGE_SIZE),
+func newStreamDestination(guid, sourceName, sourceId string, messageType logmessage.LogMessage_MessageType, sink Sink, options Options) *streamDestination {
+	destination := &streamDestination{
+		guid:         guid,
+		sourceName:   sourceName,
+		sourceId:     sourceId,
+		messageType:  messageType,
+		sink:         sink,
+		limiter:      options.Limiter,
+		redactor:     options.Redactor,
+		metadata:     options.Metadata,
+		timeProvider: options.Timestamps,
+		maxSize:      MAX_MESSAGE_SIZE,
+		buffer:       make([]byte, 0, MAX_MESSAGE_SIZE),
 	}
+
+	if destination.timeProvider != nil {
+		destination.maxSize -= timestampSize
+	}
+
+	if options.MultiLine != nil {
+		destination.lines = newLineGrouper(options.MultiLine, destination.maxSize, destination.send)
+	}
+
+	return destination
 }
 
+// Write masks secrets before anything is split into messages, so a secret
+// cannot be cut in two. Whatever might be the start of one is held back
+// until the next write, or until the streamer is flushed.
 func (destination *streamDestination) Write(data []byte) (int, error) {
-	destination.processMessage(string(data))
+	destination.lock.Lock()
+	defer destination.lock.Unlock()
+
+	message := string(data)
+
+	if destination.redactor != nil {
+		message = destination.redactor.Redact(destination.held + message)
+
+		held := destination.redactor.UnfinishedSecret(message)
+		destination.held = message[len(message)-held:]
+		message = message[:len(message)-held]
+	}
+
+	destination.processMessage(message)
+
 	return len(data), nil
 }
 
+// flushWritten sends everything written so far, including anything held
+// back in case it began a secret.
+func (destination *streamDestination) flushWritten() {
+	destination.lock.Lock()
+	defer destination.lock.Unlock()
+
+	if destination.held != "" {
+		held := destination.held
+		destination.held = ""
+
+		destination.processMessage(held)
+	}
+
+	destination.flush()
+}
+
 func (destination *streamDestination) flush() {
 	if len(destination.buffer) > 0 {
 		msg := make([]byte, len(destination.buffer))
 		copy(msg, destination.buffer)
 
-		destination.emitter.EmitLogMessage(&logmessage.LogMessage{
-			AppId:       &destination.guid,
-			SourceName:  &destination.sourceName,
-			SourceId:    &destination.sourceId,
-			Message:     msg,
-			MessageType: &destination.messageType,
-			Timestamp:   proto.Int64(time.Now().UnixNano()),
-		})
+		if destination.lines != nil {
+			destination.lines.add(msg, destination.startedAt)
+		} else {
+			destination.send(msg, destination.startedAt)
+		}
+
 		destination.buffer = destination.buffer[:0]
 	}
 }
 
+// flushLines sends any lines still being grouped
+func (destination *streamDestination) flushLines() {
+	if destination.lines != nil {
+		destination.lines.flush()
+	}
+}
+
+func (destination *streamDestination) send(msg []byte, startedAt time.Time) {
+	if destination.timeProvider != nil {
+		prefix := startedAt.Format(time.RFC3339) + " "
+		msg = append([]byte(prefix), msg...)
+	}
+
+	allowed, dropped := destination.limiter.allow(len(msg))
+	if dropped > 0 {
+		destination.emitDropped(dropped)
+	}
+
+	if allowed {
+		destination.emit(msg)
+	}
+}
+
+func (destination *streamDestination) emitDropped(dropped int) {
+	destination.emit([]byte(fmt.Sprintf("%d log lines dropped due to rate limit", dropped)))
+}
+
+func (destination *streamDestination) emit(msg []byte) {
+	destination.sink.Emit(&logmessage.LogMessage{
+		AppId:       &destination.guid,
+		SourceName:  &destination.sourceName,
+		SourceId:    &destination.sourceId,
+		Message:     msg,
+		MessageType: &destination.messageType,
+		Timestamp:   proto.Int64(time.Now().UnixNano()),
+	}, destination.metadata)
+}
+
 func (destination *streamDestination) processMessage(message string) {
 	start := 0
 	for i, rune := range message {
@@ -67,8 +172,12 @@ func (destination *streamDestination) processMessage(message string) {
 }
 
 func (destination *streamDestination) processString(message string, terminates bool) {
-	for len(message)+len(destination.buffer) >= MAX_MESSAGE_SIZE {
-		remainingSpaceInBuffer := MAX_MESSAGE_SIZE - len(destination.buffer)
+	if len(destination.buffer) == 0 && len(message) > 0 && destination.timeProvider != nil {
+		destination.startedAt = destination.timeProvider.Time()
+	}
+
+	for len(message)+len(destination.buffer) >= destination.maxSize {
+		remainingSpaceInBuffer := destination.maxSize - len(destination.buffer)
 		destination.buffer = append(destination.buffer, []byte(message[0:remainingSpaceInBuffer])...)
 
 		r, _ := utf8.DecodeLastRune(destination.buffer)
@@ -80,6 +189,10 @@ func (destination *streamDestination) processString(message string, terminates b
 		}
 
 		destination.flush()
+
+		if len(message) > 0 && destination.timeProvider != nil {
+			destination.startedAt = destination.timeProvider.Time()
+		}
 	}
 
 	destination.buffer = append(destination.buffer, []byte(message)...)
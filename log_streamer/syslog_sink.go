@@ -0,0 +1,205 @@
+package log_streamer
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudfoundry/loggregatorlib/logmessage"
+	"github.com/pivotal-golang/lager"
+)
+
+var ErrUnsupportedSyslogNetwork = errors.New("unsupported syslog network")
+
+const (
+	syslogFacilityUser = 1
+	syslogSeverityErr  = 3
+	syslogSeverityInfo = 6
+
+	syslogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// SyslogOptions bound how long a slow or unreachable syslog server can hold
+// up lines, which it never does to the containers writing them.
+type SyslogOptions struct {
+	// lines waiting to be sent; once full, new lines are dropped
+	QueueSize int
+
+	DialTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// the wait before reconnecting after a failure, doubling with each
+	// failure in a row up to MaxBackoff; lines are dropped meanwhile
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+var DefaultSyslogOptions = SyslogOptions{
+	QueueSize:    1024,
+	DialTimeout:  5 * time.Second,
+	WriteTimeout: 5 * time.Second,
+	MinBackoff:   100 * time.Millisecond,
+	MaxBackoff:   30 * time.Second,
+}
+
+type syslogSink struct {
+	// first, to be aligned for atomic access
+	dropped int64
+
+	network  string
+	address  string
+	hostname string
+	options  SyslogOptions
+	logger   lager.Logger
+
+	queue chan []byte
+
+	// only touched by the goroutine sending the queue
+	conn     net.Conn
+	backoff  time.Duration
+	nextDial time.Time
+}
+
+// NewSyslogSink forwards lines as RFC5424 messages to the syslog server at
+// address, with the DefaultSyslogOptions. Datagram networks ("udp",
+// "unixgram") send a message per datagram; stream networks ("tcp", "unix")
+// frame them by octet counting, as in RFC6587.
+func NewSyslogSink(network, address string, logger lager.Logger) (Sink, error) {
+	return NewSyslogSinkWithOptions(network, address, DefaultSyslogOptions, logger)
+}
+
+// NewSyslogSinkWithOptions is NewSyslogSink with the given options. Lines are
+// sent from a queue in the background, connecting on first use and again
+// after a failure; those that cannot be sent are dropped and counted.
+func NewSyslogSinkWithOptions(network, address string, options SyslogOptions, logger lager.Logger) (Sink, error) {
+	switch network {
+	case "udp", "unixgram", "tcp", "unix":
+	default:
+		return nil, ErrUnsupportedSyslogNetwork
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	if options.QueueSize <= 0 {
+		options.QueueSize = DefaultSyslogOptions.QueueSize
+	}
+
+	sink := &syslogSink{
+		network:  network,
+		address:  address,
+		hostname: hostname,
+		options:  options,
+		logger:   logger.Session("syslog-sink"),
+		queue:    make(chan []byte, options.QueueSize),
+	}
+
+	go sink.sendQueued()
+
+	return sink, nil
+}
+
+func (sink *syslogSink) Emit(message *logmessage.LogMessage, metadata Metadata) {
+	select {
+	case sink.queue <- sink.frame(formatSyslog(message, sink.hostname)):
+	default:
+		atomic.AddInt64(&sink.dropped, 1)
+	}
+}
+
+func (sink *syslogSink) sendQueued() {
+	for msg := range sink.queue {
+		if !sink.send(msg) {
+			atomic.AddInt64(&sink.dropped, 1)
+			continue
+		}
+
+		dropped := atomic.SwapInt64(&sink.dropped, 0)
+		if dropped > 0 {
+			sink.logger.Info("dropped-lines", lager.Data{"count": dropped})
+		}
+	}
+}
+
+func (sink *syslogSink) send(msg []byte) bool {
+	if sink.conn == nil {
+		if time.Now().Before(sink.nextDial) {
+			return false
+		}
+
+		conn, err := net.DialTimeout(sink.network, sink.address, sink.options.DialTimeout)
+		if err != nil {
+			sink.logger.Error("failed-to-connect", err)
+			sink.backOff()
+			return false
+		}
+
+		sink.conn = conn
+	}
+
+	if sink.options.WriteTimeout > 0 {
+		sink.conn.SetWriteDeadline(time.Now().Add(sink.options.WriteTimeout))
+	}
+
+	_, err := sink.conn.Write(msg)
+	if err != nil {
+		sink.logger.Error("failed-to-write", err)
+		sink.conn.Close()
+		sink.conn = nil
+		sink.backOff()
+		return false
+	}
+
+	sink.backoff = 0
+
+	return true
+}
+
+func (sink *syslogSink) backOff() {
+	if sink.backoff == 0 {
+		sink.backoff = sink.options.MinBackoff
+	} else {
+		sink.backoff *= 2
+	}
+
+	if sink.backoff > sink.options.MaxBackoff {
+		sink.backoff = sink.options.MaxBackoff
+	}
+
+	sink.nextDial = time.Now().Add(sink.backoff)
+}
+
+func (sink *syslogSink) frame(msg []byte) []byte {
+	switch sink.network {
+	case "tcp", "unix":
+		return append([]byte(fmt.Sprintf("%d ", len(msg))), msg...)
+	default:
+		return msg
+	}
+}
+
+func formatSyslog(message *logmessage.LogMessage, hostname string) []byte {
+	severity := syslogSeverityInfo
+	if message.GetMessageType() == logmessage.LogMessage_ERR {
+		severity = syslogSeverityErr
+	}
+
+	timestamp := time.Unix(0, message.GetTimestamp()).UTC().Format(syslogTimeFormat)
+
+	header := fmt.Sprintf(
+		"<%d>1 %s %s %s [%s/%s] - - ",
+		syslogFacilityUser*8+severity,
+		timestamp,
+		hostname,
+		message.GetAppId(),
+		message.GetSourceName(),
+		message.GetSourceId(),
+	)
+
+	return append([]byte(header), message.GetMessage()...)
+}
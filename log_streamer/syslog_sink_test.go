@@ -0,0 +1,268 @@
+package log_streamer_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"code.google.com/p/goprotobuf/proto"
+	"github.com/cloudfoundry/loggregatorlib/logmessage"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/cloudfoundry-incubator/executor/log_streamer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SyslogSink", func() {
+	var (
+		logger   *lagertest.TestLogger
+		message  *logmessage.LogMessage
+		hostname string
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+
+		message = &logmessage.LogMessage{
+			AppId:       proto.String("the-guid"),
+			SourceName:  proto.String("App"),
+			SourceId:    proto.String("3"),
+			Message:     []byte("hello"),
+			MessageType: logmessage.LogMessage_OUT.Enum(),
+			Timestamp:   proto.Int64(time.Date(2014, time.July, 4, 12, 30, 0, 1000, time.UTC).UnixNano()),
+		}
+
+		var err error
+		hostname, err = os.Hostname()
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	expected := func(pri int, msg string) string {
+		return fmt.Sprintf("<%d>1 2014-07-04T12:30:00.000001Z %s the-guid [App/3] - - %s", pri, hostname, msg)
+	}
+
+	Context("over udp", func() {
+		var listener net.PacketConn
+
+		BeforeEach(func() {
+			var err error
+			listener, err = net.ListenPacket("udp", "127.0.0.1:0")
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			listener.Close()
+		})
+
+		read := func() string {
+			buf := make([]byte, 1024)
+			listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+			n, _, err := listener.ReadFrom(buf)
+			Ω(err).ShouldNot(HaveOccurred())
+			return string(buf[:n])
+		}
+
+		It("sends an RFC5424 message per datagram", func() {
+			sink, err := NewSyslogSink("udp", listener.LocalAddr().String(), logger)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			sink.Emit(message, Metadata{})
+
+			message.Message = []byte("oops")
+			message.MessageType = logmessage.LogMessage_ERR.Enum()
+			sink.Emit(message, Metadata{})
+
+			Ω(read()).Should(Equal(expected(14, "hello")))
+			Ω(read()).Should(Equal(expected(11, "oops")))
+		})
+	})
+
+	Context("over tcp", func() {
+		var listener net.Listener
+		var received chan string
+
+		BeforeEach(func() {
+			var err error
+			listener, err = net.Listen("tcp", "127.0.0.1:0")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			received = make(chan string, 10)
+
+			go func() {
+				for {
+					conn, err := listener.Accept()
+					if err != nil {
+						return
+					}
+
+					go func() {
+						defer conn.Close()
+
+						reader := bufio.NewReader(conn)
+						for {
+							length, err := reader.ReadString(' ')
+							if err != nil {
+								return
+							}
+
+							n, err := strconv.Atoi(length[:len(length)-1])
+							if err != nil {
+								return
+							}
+
+							msg := make([]byte, n)
+							_, err = io.ReadFull(reader, msg)
+							if err != nil {
+								return
+							}
+
+							received <- string(msg)
+						}
+					}()
+				}
+			}()
+		})
+
+		AfterEach(func() {
+			listener.Close()
+		})
+
+		It("frames messages by octet counting", func() {
+			sink, err := NewSyslogSink("tcp", listener.Addr().String(), logger)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			sink.Emit(message, Metadata{})
+			sink.Emit(message, Metadata{})
+
+			Eventually(received).Should(Receive(Equal(expected(14, "hello"))))
+			Eventually(received).Should(Receive(Equal(expected(14, "hello"))))
+		})
+	})
+
+	Context("when the server cannot be reached", func() {
+		var address string
+
+		BeforeEach(func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			address = listener.Addr().String()
+			listener.Close()
+		})
+
+		It("drops the line and logs", func() {
+			sink, err := NewSyslogSink("tcp", address, logger)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			sink.Emit(message, Metadata{})
+
+			Eventually(logger.TestSink.Buffer).Should(gbytes.Say("test.syslog-sink.failed-to-connect"))
+		})
+
+		It("backs off, and counts the lines dropped once it reconnects", func() {
+			sink, err := NewSyslogSinkWithOptions("tcp", address, SyslogOptions{
+				QueueSize:    10,
+				DialTimeout:  time.Second,
+				WriteTimeout: time.Second,
+				MinBackoff:   10 * time.Millisecond,
+				MaxBackoff:   50 * time.Millisecond,
+			}, logger)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			sink.Emit(message, Metadata{})
+			sink.Emit(message, Metadata{})
+
+			Eventually(logger.TestSink.Buffer).Should(gbytes.Say("test.syslog-sink.failed-to-connect"))
+
+			listener, err := net.Listen("tcp", address)
+			Ω(err).ShouldNot(HaveOccurred())
+			defer listener.Close()
+
+			go func() {
+				conn, err := listener.Accept()
+				if err == nil {
+					io.Copy(ioutil.Discard, conn)
+				}
+			}()
+
+			Eventually(func() *gbytes.Buffer {
+				sink.Emit(message, Metadata{})
+				return logger.TestSink.Buffer
+			}).Should(gbytes.Say(`test.syslog-sink.dropped-lines.*"count":[1-9]`))
+		})
+	})
+
+	Context("when the server stops reading", func() {
+		var listener net.Listener
+		var accepted chan net.Conn
+
+		BeforeEach(func() {
+			var err error
+			listener, err = net.Listen("tcp", "127.0.0.1:0")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			accepted = make(chan net.Conn, 10)
+
+			go func() {
+				defer close(accepted)
+
+				for {
+					conn, err := listener.Accept()
+					if err != nil {
+						return
+					}
+
+					accepted <- conn
+				}
+			}()
+		})
+
+		AfterEach(func() {
+			listener.Close()
+
+			for conn := range accepted {
+				conn.Close()
+			}
+		})
+
+		It("never holds up the lines being emitted", func() {
+			sink, err := NewSyslogSinkWithOptions("tcp", listener.Addr().String(), SyslogOptions{
+				QueueSize:    10,
+				DialTimeout:  time.Second,
+				WriteTimeout: 50 * time.Millisecond,
+				MinBackoff:   10 * time.Millisecond,
+				MaxBackoff:   50 * time.Millisecond,
+			}, logger)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			message.Message = make([]byte, 64*1024)
+
+			startedAt := time.Now()
+			for i := 0; i < 1000; i++ {
+				sink.Emit(message, Metadata{})
+			}
+
+			Ω(time.Since(startedAt)).Should(BeNumerically("<", time.Second))
+
+			// keep lines coming until the socket's buffers are full
+			Eventually(func() *gbytes.Buffer {
+				sink.Emit(message, Metadata{})
+				return logger.TestSink.Buffer
+			}, 5*time.Second).Should(gbytes.Say("test.syslog-sink.failed-to-write"))
+		})
+	})
+
+	Context("with an unsupported network", func() {
+		It("returns an error", func() {
+			_, err := NewSyslogSink("carrier-pigeon", "somewhere", logger)
+			Ω(err).Should(Equal(ErrUnsupportedSyslogNetwork))
+		})
+	})
+})
@@ -0,0 +1,115 @@
+package log_streamer_test
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/executor/log_streamer"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Timestamps", func() {
+	var (
+		sink         *lockedSink
+		timeProvider *faketimeprovider.FakeTimeProvider
+		multiLine    *MultiLine
+		streamer     LogStreamer
+	)
+
+	index := 0
+
+	BeforeEach(func() {
+		sink = &lockedSink{}
+		timeProvider = faketimeprovider.New(time.Date(2014, time.July, 4, 12, 30, 0, 0, time.UTC))
+		multiLine = nil
+	})
+
+	JustBeforeEach(func() {
+		streamer = NewWithOptions("the-guid", "the-source-name", &index, sink, Options{
+			MultiLine:  multiLine,
+			Timestamps: timeProvider,
+		})
+	})
+
+	It("prefixes each line with the time it was started", func() {
+		fmt.Fprint(streamer.Stdout(), "hello\nwor")
+
+		timeProvider.Increment(time.Second)
+		fmt.Fprint(streamer.Stdout(), "ld\n")
+
+		timeProvider.Increment(time.Second)
+		fmt.Fprint(streamer.Stdout(), "goodbye\n")
+
+		Ω(sink.Messages()).Should(Equal([]string{
+			"2014-07-04T12:30:00Z hello",
+			"2014-07-04T12:30:00Z world",
+			"2014-07-04T12:30:02Z goodbye",
+		}))
+	})
+
+	It("prefixes stderr separately", func() {
+		fmt.Fprint(streamer.Stdout(), "out")
+
+		timeProvider.Increment(time.Second)
+		fmt.Fprint(streamer.Stderr(), "err\n")
+		streamer.Flush()
+
+		Ω(sink.Messages()).Should(Equal([]string{
+			"2014-07-04T12:30:01Z err",
+			"2014-07-04T12:30:00Z out",
+		}))
+	})
+
+	It("ends a line at a carriage return, as without timestamps", func() {
+		fmt.Fprint(streamer.Stdout(), "10%\r")
+
+		timeProvider.Increment(time.Second)
+		fmt.Fprint(streamer.Stdout(), "20%\r\n")
+
+		Ω(sink.Messages()).Should(Equal([]string{
+			"2014-07-04T12:30:00Z 10%",
+			"2014-07-04T12:30:01Z 20%",
+		}))
+	})
+
+	It("leaves room for the timestamp in long lines", func() {
+		fmt.Fprint(streamer.Stdout(), strings.Repeat("x", MAX_MESSAGE_SIZE)+"\n")
+
+		messages := sink.Messages()
+		Ω(messages).Should(HaveLen(2))
+
+		for _, message := range messages {
+			Ω(len(message)).Should(BeNumerically("<=", MAX_MESSAGE_SIZE))
+			Ω(message).Should(MatchRegexp(`^2014-07-04T12:30:00Z x`))
+		}
+	})
+
+	Context("with multi-line grouping", func() {
+		BeforeEach(func() {
+			multiLine = &MultiLine{
+				MaxSize:      MAX_MESSAGE_SIZE,
+				FlushTimeout: time.Hour,
+			}
+		})
+
+		It("prefixes each group once, with the time its first line was started", func() {
+			fmt.Fprint(streamer.Stdout(), "Exception in thread main\n")
+
+			timeProvider.Increment(time.Second)
+			fmt.Fprint(streamer.Stdout(), "\tat Foo.bar\n\tat Foo.main\n")
+
+			timeProvider.Increment(time.Second)
+			fmt.Fprint(streamer.Stdout(), "next line\n")
+			streamer.Flush()
+
+			Ω(sink.Messages()).Should(Equal([]string{
+				"2014-07-04T12:30:00Z Exception in thread main\n\tat Foo.bar\n\tat Foo.main",
+				"2014-07-04T12:30:02Z next line",
+			}))
+		})
+	})
+})
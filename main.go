@@ -3,11 +3,15 @@ package main
 import (
 	"flag"
 	"fmt"
+	"net/url"
 	"os"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/cloudfoundry-incubator/cf-lager"
+	"github.com/cloudfoundry-incubator/executor/api"
+	"github.com/cloudfoundry-incubator/executor/artifacts"
 	"github.com/cloudfoundry-incubator/executor/depot"
 	"github.com/cloudfoundry-incubator/executor/registry"
 	WardenClient "github.com/cloudfoundry-incubator/garden/client"
@@ -21,11 +25,18 @@ import (
 
 	cf_debug_server "github.com/cloudfoundry-incubator/cf-debug-server"
 	"github.com/cloudfoundry-incubator/executor/configuration"
+	"github.com/cloudfoundry-incubator/executor/downloader"
+	"github.com/cloudfoundry-incubator/executor/log_streamer"
+	"github.com/cloudfoundry-incubator/executor/metrics"
+	"github.com/cloudfoundry-incubator/executor/object_store"
 	"github.com/cloudfoundry-incubator/executor/server"
+	"github.com/cloudfoundry-incubator/executor/steps/download_step"
+	"github.com/cloudfoundry-incubator/executor/steps/fetch_result_step"
+	"github.com/cloudfoundry-incubator/executor/steps/monitor_step"
+	"github.com/cloudfoundry-incubator/executor/steps/run_step"
 	Transformer "github.com/cloudfoundry-incubator/executor/transformer"
 	"github.com/cloudfoundry-incubator/executor/uploader"
 	"github.com/pivotal-golang/archiver/compressor"
-	"github.com/pivotal-golang/archiver/extractor"
 	"github.com/pivotal-golang/cacheddownloader"
 	"github.com/pivotal-golang/lager"
 )
@@ -101,12 +112,48 @@ var containerInodeLimit = flag.Uint(
 	"max number of inodes per container",
 )
 
+var defaultEgressRules = flag.String(
+	"defaultEgressRules",
+	"",
+	"comma-separated CIDRs, each with an optional ':port', that every container may reach",
+)
+
+var defaultBandwidthRate = flag.Uint64(
+	"defaultBandwidthRate",
+	0,
+	"bandwidth, in bytes per second, of containers that don't ask for any; 0 is unlimited",
+)
+
+var defaultBandwidthBurst = flag.Uint64(
+	"defaultBandwidthBurst",
+	0,
+	"burst bandwidth, in bytes per second, of containers that don't ask for any; 0 is the rate",
+)
+
+var maxBandwidthRate = flag.Uint64(
+	"maxBandwidthRate",
+	0,
+	"most bandwidth, in bytes per second, a container may ask for; 0 is unlimited",
+)
+
+var maxBandwidthBurst = flag.Uint64(
+	"maxBandwidthBurst",
+	0,
+	"most burst bandwidth, in bytes per second, a container may ask for; 0 is unlimited",
+)
+
 var containerMaxCpuShares = flag.Int(
 	"containerMaxCpuShares",
 	0,
 	"cpu shares allocatable to a container",
 )
 
+var fileTransferRoot = flag.String(
+	"fileTransferRoot",
+	"",
+	"directory that file:// downloads and uploads are confined to; file:// URLs are rejected if empty",
+)
+
 var cachePath = flag.String(
 	"cachePath",
 	"/tmp/cache",
@@ -119,6 +166,162 @@ var maxCacheSizeInBytes = flag.Int64(
 	"maximum size of the cache (in bytes) - you should include a healthy amount of overhead",
 )
 
+var uploadChunkSizeInBytes = flag.Int64(
+	"uploadChunkSizeInBytes",
+	uploader.DefaultChunkSize,
+	"size of the chunks uploads are split into when the destination takes ranged chunks; smaller uploads are sent, and retried, in one request",
+)
+
+var uploadMaxAttempts = flag.Int(
+	"uploadMaxAttempts",
+	uploader.DefaultRetryPolicy.MaxAttempts,
+	"number of times each upload request is attempted before giving up",
+)
+
+var uploadRetryBaseDelay = flag.Duration(
+	"uploadRetryBaseDelay",
+	uploader.DefaultRetryPolicy.BaseDelay,
+	"delay before the first upload retry; doubled for every further attempt",
+)
+
+var uploadRetryMaxDelay = flag.Duration(
+	"uploadRetryMaxDelay",
+	uploader.DefaultRetryPolicy.MaxDelay,
+	"maximum delay between upload attempts, including delays requested via Retry-After",
+)
+
+var transferProgressInterval = flag.Duration(
+	"transferProgressInterval",
+	10*time.Second,
+	"how often to log the progress of downloads and uploads to the container's log stream; 0 disables it",
+)
+
+var eventPollInterval = flag.Duration(
+	"eventPollInterval",
+	run_step.EventPollInterval,
+	"how often to look at a running process's container events, such as running out of memory",
+)
+
+var stopWaitTimeout = flag.Duration(
+	"stopWaitTimeout",
+	run_step.StopWaitTimeout,
+	"how long a process stopped by a cancelled run has to exit before the run stops waiting for it",
+)
+
+var hookRetryInterval = flag.Duration(
+	"hookRetryInterval",
+	monitor_step.HookRetryInterval,
+	"delay before retrying a failed health hook; doubled for every further attempt",
+)
+
+var maxSpoolSizeInBytes = flag.Int64(
+	"maxSpoolSizeInBytes",
+	download_step.DefaultMaxSpoolSize,
+	"maximum size of a download spooled into the temp dir; 0 disables the limit",
+)
+
+var maxResultSizeInBytes = flag.Int64(
+	"maxResultSizeInBytes",
+	fetch_result_step.DefaultMaxResultSize,
+	"maximum size of a file read by a fetch_result action; actions may ask for a smaller limit",
+)
+
+var logRateLimitLinesPerSecond = flag.Int(
+	"logRateLimitLinesPerSecond",
+	0,
+	"maximum log lines per second a container may emit; excess lines are dropped. 0 disables the limit",
+)
+
+var logRateLimitBytesPerSecond = flag.Int(
+	"logRateLimitBytesPerSecond",
+	0,
+	"maximum log bytes per second a container may emit; excess lines are dropped. 0 disables the limit",
+)
+
+var logRateLimitLineBurst = flag.Int(
+	"logRateLimitLineBurst",
+	0,
+	"log lines a container may emit at once before logRateLimitLinesPerSecond applies; defaults to one second's worth",
+)
+
+var logRateLimitByteBurst = flag.Int(
+	"logRateLimitByteBurst",
+	0,
+	"log bytes a container may emit at once before logRateLimitBytesPerSecond applies; defaults to one second's worth",
+)
+
+var logSinks = flag.String(
+	"logSinks",
+	"loggregator",
+	"comma-separated list of where container logs go: any of loggregator, syslog, file and json; empty discards them",
+)
+
+var syslogNetwork = flag.String(
+	"syslogNetwork",
+	"udp",
+	"network of the syslog server for the syslog log sink: udp, tcp, unix or unixgram",
+)
+
+var syslogAddress = flag.String(
+	"syslogAddress",
+	"127.0.0.1:514",
+	"address of the syslog server for the syslog log sink",
+)
+
+var logDirectory = flag.String(
+	"logDirectory",
+	"",
+	"directory the file log sink writes each container's logs to",
+)
+
+var logFileMaxSizeInBytes = flag.Int64(
+	"logFileMaxSizeInBytes",
+	10*1024*1024,
+	"size at which the file log sink rotates a container's log file; 0 never rotates",
+)
+
+var logFileMaxFiles = flag.Int(
+	"logFileMaxFiles",
+	5,
+	"how many rotated log files the file log sink keeps per container",
+)
+
+var jsonLogPath = flag.String(
+	"jsonLogPath",
+	"",
+	"file the json log sink appends a JSON record per container log line to, with the step it came from",
+)
+
+var containerMetricsInterval = flag.Duration(
+	"containerMetricsInterval",
+	30*time.Second,
+	"how often to sample what each container is using; 0 disables sampling",
+)
+
+var emitContainerMetrics = flag.Bool(
+	"emitContainerMetrics",
+	false,
+	"send each container's usage samples to its loggregator log stream",
+)
+
+var objectStoreEndpoint = flag.String(
+	"objectStoreEndpoint",
+	"",
+	"URL of the S3-compatible object store that s3://bucket/key downloads and uploads go to; s3:// URLs are rejected if empty",
+)
+
+var objectStoreAccessKey = flag.String(
+	"objectStoreAccessKey",
+	"",
+	"access key used to sign object store requests",
+)
+
+var objectStoreSecretKey = flag.String(
+	"objectStoreSecretKey",
+	"",
+	"secret key used to sign object store requests",
+)
+
 func main() {
 	defer func() {
 		msg := recover()
@@ -137,8 +340,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	egressRules := initializeEgressRules(logger)
+	defaultBandwidth, maxBandwidth := initializeBandwidth(logger)
+
 	wardenClient, capacity := initializeWardenClient(logger)
-	transformer := initializeTransformer(logger)
+	uploader := initializeUploader(logger)
+	transformer := initializeTransformer(logger, uploader)
 	reg := registry.New(capacity, timeprovider.NewTimeProvider())
 
 	logger.Info("executor.starting")
@@ -149,9 +356,14 @@ func main() {
 		*containerOwnerName,
 		uint64(*containerMaxCpuShares),
 		uint64(*containerInodeLimit),
+		egressRules,
+		defaultBandwidth,
+		maxBandwidth,
 		wardenClient,
 		reg,
 		transformer,
+		artifacts.New(uploader, logger),
+		timeprovider.NewTimeProvider(),
 		logger,
 	)
 
@@ -168,6 +380,17 @@ func main() {
 		"api-server":      apiServer,
 	}
 
+	if *containerMetricsInterval > 0 {
+		group["metrics-sampler"] = metrics.NewSampler(
+			wardenClient,
+			reg,
+			initializeMetricsEmitter(),
+			timeprovider.NewTimeProvider(),
+			*containerMetricsInterval,
+			logger,
+		)
+	}
+
 	cf_debug_server.Run()
 
 	processGroup := grouper.EnvokeGroup(group)
@@ -215,12 +438,129 @@ func initializeWardenClient(logger lager.Logger) (WardenClient.Client, registry.
 	return wardenClient, capacity
 }
 
-func initializeTransformer(logger lager.Logger) *Transformer.Transformer {
-	cache := cacheddownloader.New(*cachePath, *tempDir, *maxCacheSizeInBytes, 10*time.Minute)
-	uploader := uploader.New(10 * time.Minute)
-	extractor := extractor.NewDetectable()
+func initializeUploader(logger lager.Logger) uploader.Uploader {
+	retryPolicy := uploader.RetryPolicy{
+		MaxAttempts: *uploadMaxAttempts,
+		BaseDelay:   *uploadRetryBaseDelay,
+		MaxDelay:    *uploadRetryMaxDelay,
+	}
+
+	httpUploader := uploader.New(10*time.Minute, *uploadChunkSizeInBytes, retryPolicy)
+
+	uploaders := map[string]uploader.Uploader{
+		"http":  httpUploader,
+		"https": httpUploader,
+	}
+
+	if *fileTransferRoot != "" {
+		uploaders["file"] = uploader.NewFileUploader(*fileTransferRoot)
+	}
+
+	store := initializeObjectStore(logger)
+	if store != nil {
+		uploaders[object_store.Scheme] = uploader.NewObjectStoreUploader(store, 10*time.Minute, *uploadChunkSizeInBytes, retryPolicy)
+	}
+
+	return uploader.NewSchemeUploader(uploaders)
+}
+
+func initializeObjectStore(logger lager.Logger) *object_store.ObjectStore {
+	if *objectStoreEndpoint == "" {
+		return nil
+	}
+
+	endpoint, err := url.Parse(*objectStoreEndpoint)
+	if err != nil {
+		logger.Error("invalid-object-store-endpoint", err)
+		os.Exit(1)
+	}
+
+	return object_store.New(endpoint, *objectStoreAccessKey, *objectStoreSecretKey)
+}
+
+func initializeTransformer(logger lager.Logger, uploader uploader.Uploader) *Transformer.Transformer {
+	httpDownloader := cacheddownloader.NewDownloader(10 * time.Minute)
+
+	downloaders := map[string]cacheddownloader.RemoteDownloader{
+		"http":  httpDownloader,
+		"https": httpDownloader,
+	}
+
+	if *fileTransferRoot != "" {
+		downloaders["file"] = downloader.NewFileDownloader(*fileTransferRoot)
+	}
+
+	store := initializeObjectStore(logger)
+	if store != nil {
+		downloaders[object_store.Scheme] = cacheddownloader.NewPreparingDownloader(10*time.Minute, store.Prepare)
+	}
+
+	cache := cacheddownloader.NewWithDownloader(*cachePath, *tempDir, *maxCacheSizeInBytes, downloader.New(downloaders))
 	compressor := compressor.NewTgz()
 
+	return Transformer.NewTransformer(
+		initializeLogSink(logger),
+		cache,
+		uploader,
+		compressor,
+		logger,
+		*tempDir,
+		*maxSpoolSizeInBytes,
+		*transferProgressInterval,
+		*maxResultSizeInBytes,
+		log_streamer.RateLimit{
+			LinesPerSecond: *logRateLimitLinesPerSecond,
+			BytesPerSecond: *logRateLimitBytesPerSecond,
+			LineBurst:      *logRateLimitLineBurst,
+			ByteBurst:      *logRateLimitByteBurst,
+		},
+		Transformer.Timings{
+			EventPollInterval: *eventPollInterval,
+			StopWaitTimeout:   *stopWaitTimeout,
+			HookRetryInterval: *hookRetryInterval,
+		},
+	)
+}
+
+func initializeEgressRules(logger lager.Logger) []api.EgressRule {
+	rules, err := configuration.ParseEgressRules(*defaultEgressRules)
+	if err != nil {
+		logger.Error("invalid-default-egress-rules", err)
+		os.Exit(1)
+	}
+
+	return rules
+}
+
+func initializeBandwidth(logger lager.Logger) (warden.BandwidthLimits, warden.BandwidthLimits) {
+	defaultLimits := warden.BandwidthLimits{
+		RateInBytesPerSecond:      *defaultBandwidthRate,
+		BurstRateInBytesPerSecond: *defaultBandwidthBurst,
+	}
+
+	maxLimits := warden.BandwidthLimits{
+		RateInBytesPerSecond:      *maxBandwidthRate,
+		BurstRateInBytesPerSecond: *maxBandwidthBurst,
+	}
+
+	err := configuration.ValidateBandwidth(defaultLimits, maxLimits)
+	if err != nil {
+		logger.Error("invalid-bandwidth-limits", err)
+		os.Exit(1)
+	}
+
+	return defaultLimits, maxLimits
+}
+
+func initializeMetricsEmitter() metrics.Emitter {
+	if !*emitContainerMetrics {
+		return nil
+	}
+
+	return metrics.NewLoggregatorEmitter(initializeLoggregatorEmitter())
+}
+
+func initializeLoggregatorEmitter() emitter.Emitter {
 	logEmitter, _ := emitter.NewEmitter(
 		*loggregatorServer,
 		"",
@@ -229,15 +569,63 @@ func initializeTransformer(logger lager.Logger) *Transformer.Transformer {
 		nil,
 	)
 
-	return Transformer.NewTransformer(
-		logEmitter,
-		cache,
-		uploader,
-		extractor,
-		compressor,
-		logger,
-		*tempDir,
-	)
+	return logEmitter
+}
+
+func initializeLogSink(logger lager.Logger) log_streamer.Sink {
+	sinks := []log_streamer.Sink{}
+
+	for _, name := range strings.Split(*logSinks, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+
+		case "loggregator":
+			sinks = append(sinks, log_streamer.NewLoggregatorSink(initializeLoggregatorEmitter()))
+
+		case "syslog":
+			sink, err := log_streamer.NewSyslogSink(*syslogNetwork, *syslogAddress, logger)
+			if err != nil {
+				logger.Error("invalid-syslog-network", err, lager.Data{"network": *syslogNetwork})
+				os.Exit(1)
+			}
+
+			sinks = append(sinks, sink)
+
+		case "file":
+			if *logDirectory == "" {
+				logger.Error("log-directory-missing", nil)
+				os.Exit(1)
+			}
+
+			err := os.MkdirAll(*logDirectory, 0755)
+			if err != nil {
+				logger.Error("failed-to-create-log-directory", err)
+				os.Exit(1)
+			}
+
+			sinks = append(sinks, log_streamer.NewFileSink(*logDirectory, *logFileMaxSizeInBytes, *logFileMaxFiles, timeprovider.NewTimeProvider(), logger))
+
+		case "json":
+			if *jsonLogPath == "" {
+				logger.Error("json-log-path-missing", nil)
+				os.Exit(1)
+			}
+
+			file, err := os.OpenFile(*jsonLogPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+			if err != nil {
+				logger.Error("failed-to-open-json-log", err)
+				os.Exit(1)
+			}
+
+			sinks = append(sinks, log_streamer.NewJSONSink(file, logger))
+
+		default:
+			logger.Error("unknown-log-sink", nil, lager.Data{"sink": name})
+			os.Exit(1)
+		}
+	}
+
+	return log_streamer.NewMultiSink(sinks...)
 }
 
 func destroyContainers(wardenClient warden.Client, logger lager.Logger) {
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"code.google.com/p/goprotobuf/proto"
+	"github.com/cloudfoundry-incubator/executor/api"
+	"github.com/cloudfoundry/loggregatorlib/emitter"
+	"github.com/cloudfoundry/loggregatorlib/logmessage"
+)
+
+// MetricsSourceName is the source of the messages carrying samples.
+const MetricsSourceName = "METRICS"
+
+type loggregatorEmitter struct {
+	emitter emitter.Emitter
+}
+
+// NewLoggregatorEmitter sends each sample to the container's log stream as
+// a JSON-encoded message from MetricsSourceName; the loggregator protocol
+// this speaks has no message type for metrics. Containers without a log
+// guid are skipped.
+func NewLoggregatorEmitter(logEmitter emitter.Emitter) Emitter {
+	return &loggregatorEmitter{
+		emitter: logEmitter,
+	}
+}
+
+func (e *loggregatorEmitter) EmitUsage(container api.Container, sample api.UsageSample) {
+	if container.Log.Guid == "" {
+		return
+	}
+
+	payload, err := json.Marshal(sample)
+	if err != nil {
+		return
+	}
+
+	sourceId := "0"
+	if container.Log.Index != nil {
+		sourceId = strconv.Itoa(*container.Log.Index)
+	}
+
+	e.emitter.EmitLogMessage(&logmessage.LogMessage{
+		AppId:       proto.String(container.Log.Guid),
+		SourceName:  proto.String(MetricsSourceName),
+		SourceId:    proto.String(sourceId),
+		Message:     payload,
+		MessageType: logmessage.LogMessage_OUT.Enum(),
+		Timestamp:   proto.Int64(sample.Time),
+	})
+}
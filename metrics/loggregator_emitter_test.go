@@ -0,0 +1,64 @@
+package metrics_test
+
+import (
+	"encoding/json"
+
+	"github.com/cloudfoundry-incubator/executor/api"
+	"github.com/cloudfoundry/loggregatorlib/logmessage"
+
+	. "github.com/cloudfoundry-incubator/executor/metrics"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeLoggregatorEmitter struct {
+	emissions []*logmessage.LogMessage
+}
+
+func (e *fakeLoggregatorEmitter) Emit(appid, message string)      { panic("not expected") }
+func (e *fakeLoggregatorEmitter) EmitError(appid, message string) { panic("not expected") }
+
+func (e *fakeLoggregatorEmitter) EmitLogMessage(msg *logmessage.LogMessage) {
+	e.emissions = append(e.emissions, msg)
+}
+
+var _ = Describe("LoggregatorEmitter", func() {
+	var logEmitter *fakeLoggregatorEmitter
+	var emitter Emitter
+
+	BeforeEach(func() {
+		logEmitter = &fakeLoggregatorEmitter{}
+		emitter = NewLoggregatorEmitter(logEmitter)
+	})
+
+	It("sends the sample to the container's log stream", func() {
+		index := 3
+		sample := api.UsageSample{Time: 1234, MemoryBytes: 2048, CPUPercent: 12.5}
+
+		emitter.EmitUsage(api.Container{
+			Guid: "container-guid",
+			Log:  api.LogConfig{Guid: "log-guid", SourceName: "App", Index: &index},
+		}, sample)
+
+		Ω(logEmitter.emissions).Should(HaveLen(1))
+
+		message := logEmitter.emissions[0]
+		Ω(message.GetAppId()).Should(Equal("log-guid"))
+		Ω(message.GetSourceName()).Should(Equal(MetricsSourceName))
+		Ω(message.GetSourceId()).Should(Equal("3"))
+		Ω(message.GetTimestamp()).Should(BeEquivalentTo(1234))
+
+		var emitted api.UsageSample
+		err := json.Unmarshal(message.GetMessage(), &emitted)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(emitted).Should(Equal(sample))
+	})
+
+	Context("when the container has no log guid", func() {
+		It("sends nothing", func() {
+			emitter.EmitUsage(api.Container{Guid: "container-guid"}, api.UsageSample{})
+
+			Ω(logEmitter.emissions).Should(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,135 @@
+// Package metrics samples what each container is using, keeping a short
+// history of it on the registry.
+package metrics
+
+import (
+	"os"
+	"time"
+
+	"github.com/cloudfoundry-incubator/executor/api"
+	"github.com/cloudfoundry-incubator/executor/registry"
+	"github.com/cloudfoundry-incubator/garden/warden"
+	"github.com/cloudfoundry/gunk/timeprovider"
+	"github.com/pivotal-golang/lager"
+)
+
+// Emitter is told of every sample taken.
+type Emitter interface {
+	EmitUsage(container api.Container, sample api.UsageSample)
+}
+
+type Sampler struct {
+	wardenClient warden.Client
+	registry     registry.Registry
+	emitter      Emitter
+	timeProvider timeprovider.TimeProvider
+	interval     time.Duration
+	logger       lager.Logger
+}
+
+// NewSampler samples every created container each interval. emitter may be
+// nil.
+func NewSampler(
+	wardenClient warden.Client,
+	registry registry.Registry,
+	emitter Emitter,
+	timeProvider timeprovider.TimeProvider,
+	interval time.Duration,
+	logger lager.Logger,
+) *Sampler {
+	return &Sampler{
+		wardenClient: wardenClient,
+		registry:     registry,
+		emitter:      emitter,
+		timeProvider: timeProvider,
+		interval:     interval,
+		logger:       logger.Session("metrics-sampler"),
+	}
+}
+
+func (s *Sampler) Run(sigChan <-chan os.Signal, readyChan chan<- struct{}) error {
+	ticker := s.timeProvider.NewTickerChannel("metrics-sampler", s.interval)
+	close(readyChan)
+
+	for {
+		select {
+		case <-ticker:
+			s.sample()
+		case <-sigChan:
+			return nil
+		}
+	}
+}
+
+func (s *Sampler) sample() {
+	for _, container := range s.registry.GetAllContainers() {
+		if container.State != api.StateCreated && container.State != api.StateCompleted {
+			continue
+		}
+
+		sampleLog := s.logger.Session("sample", lager.Data{
+			"guid":   container.Guid,
+			"handle": container.ContainerHandle,
+		})
+
+		wardenContainer, err := s.wardenClient.Lookup(container.ContainerHandle)
+		if err != nil {
+			sampleLog.Error("lookup-failed", err)
+			continue
+		}
+
+		info, err := wardenContainer.Info()
+		if err != nil {
+			sampleLog.Error("info-failed", err)
+			continue
+		}
+
+		sample := usageSample(info, s.timeProvider.Time(), container.Usage)
+
+		err = s.registry.RecordUsage(container.Guid, sample)
+		if err != nil {
+			sampleLog.Error("failed-to-record-usage", err)
+			continue
+		}
+
+		if s.emitter != nil {
+			s.emitter.EmitUsage(container, sample)
+		}
+	}
+}
+
+func usageSample(info warden.ContainerInfo, now time.Time, history []api.UsageSample) api.UsageSample {
+	sample := api.UsageSample{
+		Time:        now.UnixNano(),
+		MemoryBytes: memoryInUse(info.MemoryStat),
+		DiskBytes:   info.DiskStat.BytesUsed,
+		DiskInodes:  info.DiskStat.InodesUsed,
+		CPUUsage:    info.CPUStat.Usage,
+
+		BandwidthInRate:   info.BandwidthStat.InRate,
+		BandwidthInBurst:  info.BandwidthStat.InBurst,
+		BandwidthOutRate:  info.BandwidthStat.OutRate,
+		BandwidthOutBurst: info.BandwidthStat.OutBurst,
+	}
+
+	if len(history) > 0 {
+		previous := history[len(history)-1]
+
+		elapsed := sample.Time - previous.Time
+		if elapsed > 0 && sample.CPUUsage >= previous.CPUUsage {
+			sample.CPUPercent = 100 * float64(sample.CPUUsage-previous.CPUUsage) / float64(elapsed)
+		}
+	}
+
+	return sample
+}
+
+// the page cache the container could give back doesn't count
+func memoryInUse(stat warden.ContainerMemoryStat) uint64 {
+	used := stat.TotalRss + stat.TotalCache
+	if stat.TotalInactiveFile > used {
+		return 0
+	}
+
+	return used - stat.TotalInactiveFile
+}
@@ -0,0 +1,188 @@
+package metrics_test
+
+import (
+	"errors"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry-incubator/executor/api"
+	"github.com/cloudfoundry-incubator/executor/registry"
+	"github.com/cloudfoundry-incubator/garden/warden"
+	"github.com/cloudfoundry-incubator/garden/warden/fakes"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	"github.com/pivotal-golang/lager/lagertest"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/cloudfoundry-incubator/executor/metrics"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeEmitter struct {
+	lock    sync.Mutex
+	samples map[string][]api.UsageSample
+}
+
+func (e *fakeEmitter) EmitUsage(container api.Container, sample api.UsageSample) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.samples[container.Guid] = append(e.samples[container.Guid], sample)
+}
+
+func (e *fakeEmitter) Samples(guid string) []api.UsageSample {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return e.samples[guid]
+}
+
+var _ = Describe("Sampler", func() {
+	var (
+		timeProvider *faketimeprovider.FakeTimeProvider
+		wardenClient *fakes.FakeBackend
+		reg          registry.Registry
+		emitter      *fakeEmitter
+		container    *fakes.FakeContainer
+		process      ifrit.Process
+		interval     time.Duration
+		usage        func() []api.UsageSample
+		tick         func()
+	)
+
+	BeforeEach(func() {
+		timeProvider = faketimeprovider.New(time.Now())
+		timeProvider.ProvideFakeChannels = true
+
+		reg = registry.New(registry.Capacity{
+			MemoryMB:   1024,
+			DiskMB:     2048,
+			Containers: 5,
+		}, timeProvider)
+
+		container = new(fakes.FakeContainer)
+		container.InfoReturns(warden.ContainerInfo{
+			MemoryStat: warden.ContainerMemoryStat{
+				TotalRss:          1000,
+				TotalCache:        500,
+				TotalInactiveFile: 200,
+			},
+			CPUStat:  warden.ContainerCPUStat{Usage: 1000000},
+			DiskStat: warden.ContainerDiskStat{BytesUsed: 4096, InodesUsed: 12},
+			BandwidthStat: warden.ContainerBandwidthStat{
+				InRate:   100,
+				InBurst:  200,
+				OutRate:  300,
+				OutBurst: 400,
+			},
+		}, nil)
+
+		wardenClient = new(fakes.FakeBackend)
+		wardenClient.LookupStub = func(handle string) (warden.Container, error) {
+			if handle == "the-handle" {
+				return container, nil
+			}
+
+			return nil, errors.New("no such container")
+		}
+
+		emitter = &fakeEmitter{samples: map[string][]api.UsageSample{}}
+		interval = 10 * time.Second
+
+		usage = func() []api.UsageSample {
+			c, err := reg.FindByGuid("created-guid")
+			Ω(err).ShouldNot(HaveOccurred())
+			return c.Usage
+		}
+
+		tick = func() {
+			timeProvider.TickerChannelFor("metrics-sampler") <- timeProvider.Time()
+		}
+
+		_, err := reg.Reserve("created-guid", api.ContainerAllocationRequest{MemoryMB: 64, DiskMB: 32})
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = reg.Initialize("created-guid")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = reg.Create("created-guid", "the-handle", api.ContainerInitializationRequest{})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, err = reg.Reserve("reserved-guid", api.ContainerAllocationRequest{MemoryMB: 64, DiskMB: 32})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		process = ifrit.Envoke(NewSampler(wardenClient, reg, emitter, timeProvider, interval, lagertest.NewTestLogger("test")))
+	})
+
+	AfterEach(func() {
+		process.Signal(syscall.SIGTERM)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	It("records what each created container is using", func() {
+		tick()
+
+		Eventually(usage).Should(HaveLen(1))
+		Ω(usage()[0]).Should(Equal(api.UsageSample{
+			Time:        timeProvider.Time().UnixNano(),
+			MemoryBytes: 1300,
+			DiskBytes:   4096,
+			DiskInodes:  12,
+			CPUUsage:    1000000,
+
+			BandwidthInRate:   100,
+			BandwidthInBurst:  200,
+			BandwidthOutRate:  300,
+			BandwidthOutBurst: 400,
+		}))
+
+		Ω(wardenClient.LookupCallCount()).Should(Equal(1))
+	})
+
+	It("records the bandwidth warden shapes the container to", func() {
+		tick()
+
+		Eventually(usage).Should(HaveLen(1))
+
+		sample := usage()[0]
+		Ω(sample.BandwidthInRate).Should(Equal(uint64(100)))
+		Ω(sample.BandwidthInBurst).Should(Equal(uint64(200)))
+		Ω(sample.BandwidthOutRate).Should(Equal(uint64(300)))
+		Ω(sample.BandwidthOutBurst).Should(Equal(uint64(400)))
+	})
+
+	It("emits each sample", func() {
+		tick()
+
+		Eventually(func() []api.UsageSample { return emitter.Samples("created-guid") }).Should(HaveLen(1))
+		Ω(emitter.Samples("reserved-guid")).Should(BeEmpty())
+	})
+
+	It("works out CPU use since the last sample", func() {
+		tick()
+		Eventually(usage).Should(HaveLen(1))
+
+		timeProvider.Increment(time.Second)
+		container.InfoReturns(warden.ContainerInfo{
+			CPUStat: warden.ContainerCPUStat{Usage: 1000000 + uint64(time.Second/2)},
+		}, nil)
+
+		tick()
+		Eventually(usage).Should(HaveLen(2))
+
+		Ω(usage()[1].CPUPercent).Should(BeNumerically("~", 50, 0.001))
+	})
+
+	Context("when getting the container's info fails", func() {
+		BeforeEach(func() {
+			container.InfoReturns(warden.ContainerInfo{}, errors.New("oh no"))
+		})
+
+		It("records nothing", func() {
+			tick()
+			tick()
+
+			Ω(usage()).Should(BeEmpty())
+			Ω(emitter.Samples("created-guid")).Should(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,152 @@
+package object_store
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Objects are addressed as s3://bucket/key and stored at a path-style URL
+// under the configured endpoint, so any S3-compatible store will do.
+const Scheme = "s3"
+
+var ErrMissingBucket = errors.New("object store URL has no bucket")
+
+var signedSubresources = map[string]bool{
+	"acl":        true,
+	"location":   true,
+	"logging":    true,
+	"partNumber": true,
+	"policy":     true,
+	"torrent":    true,
+	"uploadId":   true,
+	"uploads":    true,
+	"versionId":  true,
+	"versioning": true,
+	"versions":   true,
+}
+
+type ObjectStore struct {
+	endpoint  *url.URL
+	accessKey string
+	secretKey string
+}
+
+func New(endpoint *url.URL, accessKey string, secretKey string) *ObjectStore {
+	return &ObjectStore{
+		endpoint:  endpoint,
+		accessKey: accessKey,
+		secretKey: secretKey,
+	}
+}
+
+func (store *ObjectStore) Resolve(objectURL *url.URL) (*url.URL, error) {
+	if objectURL.Host == "" {
+		return nil, ErrMissingBucket
+	}
+
+	resolved := *store.endpoint
+	resolved.Path = strings.TrimSuffix(store.endpoint.Path, "/") + "/" + objectURL.Host + objectURL.Path
+	resolved.RawQuery = objectURL.RawQuery
+
+	return &resolved, nil
+}
+
+// Prepare points a request made against an s3:// URL at the endpoint and
+// signs it. Requests that already target the endpoint are only signed.
+func (store *ObjectStore) Prepare(request *http.Request) error {
+	if request.URL.Scheme == Scheme {
+		resolved, err := store.Resolve(request.URL)
+		if err != nil {
+			return err
+		}
+
+		request.URL = resolved
+		request.Host = resolved.Host
+	}
+
+	store.Sign(request)
+
+	return nil
+}
+
+// Sign adds an AWS signature (version 2) to the request, setting its Date
+// header first if it does not have one.
+func (store *ObjectStore) Sign(request *http.Request) {
+	if request.Header.Get("Date") == "" {
+		request.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+
+	mac := hmac.New(sha1.New, []byte(store.secretKey))
+	mac.Write([]byte(store.stringToSign(request)))
+
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS %s:%s", store.accessKey, signature))
+}
+
+func (store *ObjectStore) stringToSign(request *http.Request) string {
+	return strings.Join([]string{
+		request.Method,
+		request.Header.Get("Content-MD5"),
+		request.Header.Get("Content-Type"),
+		request.Header.Get("Date"),
+	}, "\n") + "\n" + canonicalAmzHeaders(request.Header) + store.canonicalResource(request.URL)
+}
+
+func canonicalAmzHeaders(header http.Header) string {
+	names := []string{}
+	for name := range header {
+		lowered := strings.ToLower(name)
+		if strings.HasPrefix(lowered, "x-amz-") {
+			names = append(names, lowered)
+		}
+	}
+
+	sort.Strings(names)
+
+	canonical := ""
+	for _, name := range names {
+		canonical += name + ":" + strings.Join(header[http.CanonicalHeaderKey(name)], ",") + "\n"
+	}
+
+	return canonical
+}
+
+func (store *ObjectStore) canonicalResource(requestURL *url.URL) string {
+	resource := requestURL.EscapedPath()
+
+	query := requestURL.Query()
+
+	names := []string{}
+	for name := range query {
+		if signedSubresources[name] {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return resource
+	}
+
+	sort.Strings(names)
+
+	subresources := []string{}
+	for _, name := range names {
+		value := query.Get(name)
+		if value == "" {
+			subresources = append(subresources, name)
+		} else {
+			subresources = append(subresources, name+"="+value)
+		}
+	}
+
+	return resource + "?" + strings.Join(subresources, "&")
+}
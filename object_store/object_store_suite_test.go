@@ -0,0 +1,13 @@
+package object_store_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestObjectStore(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Object Store Suite")
+}
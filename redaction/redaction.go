@@ -0,0 +1,121 @@
+// Package redaction masks the values of secret environment variables in
+// anything that leaves the executor: log lines, results and errors.
+package redaction
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/executor/api"
+)
+
+const Mask = "[REDACTED]"
+
+type Redactor struct {
+	secrets  []string
+	replacer *strings.Replacer
+}
+
+// New masks every occurrence of secrets. Where secrets overlap the longest
+// one wins.
+func New(secrets []string) *Redactor {
+	values := []string{}
+	for _, secret := range secrets {
+		if secret != "" {
+			values = append(values, secret)
+		}
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	sort.Sort(byLength(values))
+
+	oldnew := make([]string, 0, 2*len(values))
+	for _, value := range values {
+		oldnew = append(oldnew, value, Mask)
+	}
+
+	return &Redactor{
+		secrets:  values,
+		replacer: strings.NewReplacer(oldnew...),
+	}
+}
+
+// ForEnv masks the values of the secret variables in env. It is nil, and
+// masks nothing, if there are none.
+func ForEnv(env []api.EnvironmentVariable) *Redactor {
+	secrets := []string{}
+	for _, e := range env {
+		if e.Secret {
+			secrets = append(secrets, e.Value)
+		}
+	}
+
+	return New(secrets)
+}
+
+func (redactor *Redactor) Redact(s string) string {
+	if redactor == nil {
+		return s
+	}
+
+	return redactor.replacer.Replace(s)
+}
+
+func (redactor *Redactor) RedactBytes(b []byte) []byte {
+	if redactor == nil {
+		return b
+	}
+
+	return []byte(redactor.replacer.Replace(string(b)))
+}
+
+// UnfinishedSecret says how many bytes at the end of s could be the start
+// of a secret that carries on past it. A stream redacted a chunk at a time
+// holds those bytes back until it sees what follows.
+func (redactor *Redactor) UnfinishedSecret(s string) int {
+	if redactor == nil {
+		return 0
+	}
+
+	longest := 0
+
+	for _, secret := range redactor.secrets {
+		n := len(secret) - 1
+		if n > len(s) {
+			n = len(s)
+		}
+
+		for ; n > longest; n-- {
+			if strings.HasSuffix(s, secret[:n]) {
+				longest = n
+				break
+			}
+		}
+	}
+
+	return longest
+}
+
+func (redactor *Redactor) RedactSteps(steps []api.StepProgress) []api.StepProgress {
+	if redactor == nil || steps == nil {
+		return steps
+	}
+
+	redacted := make([]api.StepProgress, len(steps))
+	for i, step := range steps {
+		step.Error = redactor.Redact(step.Error)
+		step.Steps = redactor.RedactSteps(step.Steps)
+		redacted[i] = step
+	}
+
+	return redacted
+}
+
+type byLength []string
+
+func (s byLength) Len() int           { return len(s) }
+func (s byLength) Less(i, j int) bool { return len(s[i]) > len(s[j]) }
+func (s byLength) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
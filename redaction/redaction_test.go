@@ -0,0 +1,108 @@
+package redaction_test
+
+import (
+	"github.com/cloudfoundry-incubator/executor/api"
+
+	. "github.com/cloudfoundry-incubator/executor/redaction"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Redactor", func() {
+	Describe("Redact", func() {
+		It("masks every occurrence of each secret", func() {
+			redactor := New([]string{"hunter2", "s3cr3t"})
+
+			Ω(redactor.Redact("password=hunter2 token=s3cr3t again=hunter2")).Should(Equal(
+				"password=[REDACTED] token=[REDACTED] again=[REDACTED]",
+			))
+		})
+
+		It("prefers the longest of overlapping secrets", func() {
+			redactor := New([]string{"abc", "abcdef"})
+
+			Ω(redactor.Redact("xabcdefx")).Should(Equal("x[REDACTED]x"))
+		})
+
+		It("ignores empty secrets", func() {
+			Ω(New([]string{""})).Should(BeNil())
+		})
+	})
+
+	Describe("RedactBytes", func() {
+		It("masks secrets", func() {
+			redactor := New([]string{"hunter2"})
+
+			Ω(string(redactor.RedactBytes([]byte("pw: hunter2")))).Should(Equal("pw: [REDACTED]"))
+		})
+	})
+
+	Describe("UnfinishedSecret", func() {
+		redactor := New([]string{"hunter2", "swordfish"})
+
+		It("is the longest end of the string that starts a secret", func() {
+			Ω(redactor.UnfinishedSecret("the password is hunt")).Should(Equal(4))
+			Ω(redactor.UnfinishedSecret("the password is swordf")).Should(Equal(6))
+		})
+
+		It("is zero when the string ends with a whole secret or none at all", func() {
+			Ω(redactor.UnfinishedSecret("the password is hunter2")).Should(Equal(0))
+			Ω(redactor.UnfinishedSecret("the password is secret")).Should(Equal(0))
+		})
+
+		It("is zero for a nil redactor", func() {
+			var nilRedactor *Redactor
+			Ω(nilRedactor.UnfinishedSecret("hunt")).Should(Equal(0))
+		})
+	})
+
+	Describe("RedactSteps", func() {
+		It("masks the errors of every step", func() {
+			redactor := New([]string{"hunter2"})
+
+			steps := []api.StepProgress{
+				{
+					Action: "run",
+					Error:  "bad hunter2",
+					Steps: []api.StepProgress{
+						{Action: "download", Error: "hunter2 not found"},
+					},
+				},
+			}
+
+			Ω(redactor.RedactSteps(steps)).Should(Equal([]api.StepProgress{
+				{
+					Action: "run",
+					Error:  "bad [REDACTED]",
+					Steps: []api.StepProgress{
+						{Action: "download", Error: "[REDACTED] not found"},
+					},
+				},
+			}))
+
+			Ω(steps[0].Error).Should(Equal("bad hunter2"))
+		})
+	})
+
+	Describe("ForEnv", func() {
+		It("masks only the secret variables", func() {
+			redactor := ForEnv([]api.EnvironmentVariable{
+				{Name: "PASSWORD", Value: "hunter2", Secret: true},
+				{Name: "HOME", Value: "/home/vcap"},
+			})
+
+			Ω(redactor.Redact("hunter2 at /home/vcap")).Should(Equal("[REDACTED] at /home/vcap"))
+		})
+
+		Context("when there are no secrets", func() {
+			It("masks nothing", func() {
+				redactor := ForEnv([]api.EnvironmentVariable{
+					{Name: "HOME", Value: "/home/vcap"},
+				})
+
+				Ω(redactor).Should(BeNil())
+				Ω(redactor.Redact("/home/vcap")).Should(Equal("/home/vcap"))
+			})
+		})
+	})
+})
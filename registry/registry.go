@@ -24,8 +24,14 @@ type Registry interface {
 	Reserve(guid string, req api.ContainerAllocationRequest) (api.Container, error)
 	Initialize(guid string) (api.Container, error)
 	Create(guid, containerHandle string, req api.ContainerInitializationRequest) (api.Container, error)
-	Start(guid string, process ifrit.Process) error
+	Start(guid string, env []api.EnvironmentVariable, process ifrit.Process) error
 	Complete(guid string, result api.ContainerRunResult) error
+	RecordTransfer(guid string, transferred api.TransferProgress) error
+	RecordHealth(guid string, health api.Health) error
+	RecordLogsDropped(guid string, lines int) error
+	RecordUsage(guid string, sample api.UsageSample) error
+	RecordEvents(guid string, events []string) error
+	RecordSteps(guid string, steps []api.StepProgress) error
 	MarkForDelete(guid string) (api.Container, error)
 	Delete(guid string) error
 }
@@ -148,12 +154,15 @@ func (r *registry) Create(guid, containerHandle string, req api.ContainerInitial
 	res.Ports = req.Ports
 	res.Log = req.Log
 	res.RootFSPath = req.RootFSPath
+	res.EgressRules = req.EgressRules
+	res.BandwidthRate = req.BandwidthRate
+	res.BandwidthBurst = req.BandwidthBurst
 
 	r.registeredContainers[guid] = res
 	return res, nil
 }
 
-func (r *registry) Start(guid string, process ifrit.Process) error {
+func (r *registry) Start(guid string, env []api.EnvironmentVariable, process ifrit.Process) error {
 	r.containersMutex.Lock()
 	defer r.containersMutex.Unlock()
 
@@ -162,6 +171,7 @@ func (r *registry) Start(guid string, process ifrit.Process) error {
 		return ErrContainerNotFound
 	}
 
+	res.Env = env
 	res.Process = process
 
 	r.registeredContainers[guid] = res
@@ -184,6 +194,160 @@ func (r *registry) Complete(guid string, result api.ContainerRunResult) error {
 	return nil
 }
 
+func (r *registry) RecordTransfer(guid string, transferred api.TransferProgress) error {
+	r.containersMutex.Lock()
+	defer r.containersMutex.Unlock()
+
+	res, ok := r.registeredContainers[guid]
+	if !ok {
+		return ErrContainerNotFound
+	}
+
+	res.Transfers.BytesDownloaded += transferred.BytesDownloaded
+	res.Transfers.BytesUploaded += transferred.BytesUploaded
+
+	r.registeredContainers[guid] = res
+	return nil
+}
+
+func (r *registry) RecordLogsDropped(guid string, lines int) error {
+	r.containersMutex.Lock()
+	defer r.containersMutex.Unlock()
+
+	res, ok := r.registeredContainers[guid]
+	if !ok {
+		return ErrContainerNotFound
+	}
+
+	res.LogLinesDropped += int64(lines)
+
+	r.registeredContainers[guid] = res
+	return nil
+}
+
+// RecordUsage adds sample to the container's usage history, forgetting the
+// oldest once it holds api.MaxUsageSamples.
+func (r *registry) RecordUsage(guid string, sample api.UsageSample) error {
+	r.containersMutex.Lock()
+	defer r.containersMutex.Unlock()
+
+	res, ok := r.registeredContainers[guid]
+	if !ok {
+		return ErrContainerNotFound
+	}
+
+	usage := append([]api.UsageSample{}, res.Usage...)
+	usage = append(usage, sample)
+	if len(usage) > api.MaxUsageSamples {
+		usage = usage[len(usage)-api.MaxUsageSamples:]
+	}
+
+	res.Usage = usage
+
+	r.registeredContainers[guid] = res
+	return nil
+}
+
+// RecordEvents adds the warden events not already seen to the container's,
+// marking it OOMKilled if it ran out of memory.
+func (r *registry) RecordEvents(guid string, events []string) error {
+	r.containersMutex.Lock()
+	defer r.containersMutex.Unlock()
+
+	res, ok := r.registeredContainers[guid]
+	if !ok {
+		return ErrContainerNotFound
+	}
+
+	seen := map[string]bool{}
+	for _, ev := range res.Events {
+		seen[ev] = true
+	}
+
+	recorded := append([]string{}, res.Events...)
+	for _, ev := range events {
+		if seen[ev] {
+			continue
+		}
+
+		seen[ev] = true
+		recorded = append(recorded, ev)
+
+		if ev == api.OutOfMemoryEvent {
+			res.OOMKilled = true
+		}
+	}
+
+	res.Events = recorded
+
+	r.registeredContainers[guid] = res
+	return nil
+}
+
+// RecordHealth replaces the container's health with the latest report,
+// noting when its status changed. The transitions in health are ignored;
+// the registry keeps those itself, along with the last errors seen.
+func (r *registry) RecordHealth(guid string, health api.Health) error {
+	r.containersMutex.Lock()
+	defer r.containersMutex.Unlock()
+
+	res, ok := r.registeredContainers[guid]
+	if !ok {
+		return ErrContainerNotFound
+	}
+
+	previous := api.Health{Status: api.HealthUnknown}
+	if res.Health != nil {
+		previous = *res.Health
+	}
+
+	if health.LastCheckError == "" {
+		health.LastCheckError = previous.LastCheckError
+	}
+
+	if health.LastHookError == "" {
+		health.LastHookError = previous.LastHookError
+	}
+
+	health.LastTransitionAt = previous.LastTransitionAt
+	health.Transitions = append([]api.HealthTransition{}, previous.Transitions...)
+
+	if health.Status != previous.Status {
+		now := r.timeProvider.Time().UnixNano()
+
+		health.LastTransitionAt = now
+		health.Transitions = append(health.Transitions, api.HealthTransition{
+			From: previous.Status,
+			To:   health.Status,
+			At:   now,
+		})
+
+		if len(health.Transitions) > api.MaxHealthTransitions {
+			health.Transitions = health.Transitions[len(health.Transitions)-api.MaxHealthTransitions:]
+		}
+	}
+
+	res.Health = &health
+
+	r.registeredContainers[guid] = res
+	return nil
+}
+
+func (r *registry) RecordSteps(guid string, steps []api.StepProgress) error {
+	r.containersMutex.Lock()
+	defer r.containersMutex.Unlock()
+
+	res, ok := r.registeredContainers[guid]
+	if !ok {
+		return ErrContainerNotFound
+	}
+
+	res.Steps = steps
+
+	r.registeredContainers[guid] = res
+	return nil
+}
+
 func (r *registry) MarkForDelete(guid string) (api.Container, error) {
 	r.containersMutex.Lock()
 	defer r.containersMutex.Unlock()
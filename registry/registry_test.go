@@ -293,6 +293,319 @@ var _ = Describe("Registry", func() {
 		})
 	})
 
+	Describe("starting a container", func() {
+		Context("when the container exists", func() {
+			BeforeEach(func() {
+				_, err := registry.Reserve("a-container", api.ContainerAllocationRequest{
+					MemoryMB: 50,
+					DiskMB:   100,
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+
+			It("records the environment it was run with", func() {
+				env := []api.EnvironmentVariable{
+					{Name: "PASSWORD", Value: "hunter2", Secret: true},
+					{Name: "HOME", Value: "/home/vcap"},
+				}
+
+				err := registry.Start("a-container", env, nil)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				container, err := registry.FindByGuid("a-container")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(container.Env).Should(Equal(env))
+			})
+		})
+
+		Context("when the container does not exist", func() {
+			It("should return an ErrContainerNotFound", func() {
+				err := registry.Start("a-container", nil, nil)
+				Ω(err).Should(MatchError(ErrContainerNotFound))
+			})
+		})
+	})
+
+	Describe("recording transfers", func() {
+		Context("when the container exists", func() {
+			BeforeEach(func() {
+				_, err := registry.Reserve("a-container", api.ContainerAllocationRequest{
+					MemoryMB: 50,
+					DiskMB:   100,
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+
+			It("adds up the bytes transferred", func() {
+				err := registry.RecordTransfer("a-container", api.TransferProgress{BytesDownloaded: 10})
+				Ω(err).ShouldNot(HaveOccurred())
+
+				err = registry.RecordTransfer("a-container", api.TransferProgress{BytesDownloaded: 5, BytesUploaded: 7})
+				Ω(err).ShouldNot(HaveOccurred())
+
+				container, err := registry.FindByGuid("a-container")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(container.Transfers).Should(Equal(api.TransferProgress{
+					BytesDownloaded: 15,
+					BytesUploaded:   7,
+				}))
+			})
+		})
+
+		Context("when the container does not exist", func() {
+			It("should return an ErrContainerNotFound", func() {
+				err := registry.RecordTransfer("a-container", api.TransferProgress{BytesDownloaded: 10})
+				Ω(err).Should(MatchError(ErrContainerNotFound))
+			})
+		})
+	})
+
+	Describe("recording dropped log lines", func() {
+		Context("when the container exists", func() {
+			BeforeEach(func() {
+				_, err := registry.Reserve("a-container", api.ContainerAllocationRequest{
+					MemoryMB: 50,
+					DiskMB:   100,
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+
+			It("adds up the lines dropped", func() {
+				err := registry.RecordLogsDropped("a-container", 10)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				err = registry.RecordLogsDropped("a-container", 5)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				container, err := registry.FindByGuid("a-container")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(container.LogLinesDropped).Should(BeEquivalentTo(15))
+			})
+		})
+
+		Context("when the container does not exist", func() {
+			It("should return an ErrContainerNotFound", func() {
+				err := registry.RecordLogsDropped("a-container", 10)
+				Ω(err).Should(MatchError(ErrContainerNotFound))
+			})
+		})
+	})
+
+	Describe("recording usage", func() {
+		Context("when the container exists", func() {
+			BeforeEach(func() {
+				_, err := registry.Reserve("a-container", api.ContainerAllocationRequest{
+					MemoryMB: 50,
+					DiskMB:   100,
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+
+			It("keeps the most recent samples, oldest first", func() {
+				for i := 0; i < api.MaxUsageSamples+2; i++ {
+					err := registry.RecordUsage("a-container", api.UsageSample{Time: int64(i)})
+					Ω(err).ShouldNot(HaveOccurred())
+				}
+
+				container, err := registry.FindByGuid("a-container")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(container.Usage).Should(HaveLen(api.MaxUsageSamples))
+				Ω(container.Usage[0].Time).Should(BeEquivalentTo(2))
+				Ω(container.Usage[api.MaxUsageSamples-1].Time).Should(BeEquivalentTo(api.MaxUsageSamples + 1))
+			})
+		})
+
+		Context("when the container does not exist", func() {
+			It("should return an ErrContainerNotFound", func() {
+				err := registry.RecordUsage("a-container", api.UsageSample{})
+				Ω(err).Should(MatchError(ErrContainerNotFound))
+			})
+		})
+	})
+
+	Describe("recording events", func() {
+		Context("when the container exists", func() {
+			BeforeEach(func() {
+				_, err := registry.Reserve("a-container", api.ContainerAllocationRequest{
+					MemoryMB: 50,
+					DiskMB:   100,
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+
+			It("keeps each event once, in the order they were seen", func() {
+				err := registry.RecordEvents("a-container", []string{"happy land"})
+				Ω(err).ShouldNot(HaveOccurred())
+
+				err = registry.RecordEvents("a-container", []string{"happy land", "another event"})
+				Ω(err).ShouldNot(HaveOccurred())
+
+				container, err := registry.FindByGuid("a-container")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(container.Events).Should(Equal([]string{"happy land", "another event"}))
+				Ω(container.OOMKilled).Should(BeFalse())
+			})
+
+			It("marks the container as killed when it ran out of memory", func() {
+				err := registry.RecordEvents("a-container", []string{api.OutOfMemoryEvent})
+				Ω(err).ShouldNot(HaveOccurred())
+
+				container, err := registry.FindByGuid("a-container")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(container.OOMKilled).Should(BeTrue())
+			})
+		})
+
+		Context("when the container does not exist", func() {
+			It("should return an ErrContainerNotFound", func() {
+				err := registry.RecordEvents("a-container", []string{api.OutOfMemoryEvent})
+				Ω(err).Should(MatchError(ErrContainerNotFound))
+			})
+		})
+	})
+
+	Describe("recording health", func() {
+		Context("when the container exists", func() {
+			BeforeEach(func() {
+				_, err := registry.Reserve("a-container", api.ContainerAllocationRequest{
+					MemoryMB: 50,
+					DiskMB:   100,
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+
+			It("has no health until some is recorded", func() {
+				container, err := registry.FindByGuid("a-container")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(container.Health).Should(BeNil())
+			})
+
+			It("records the latest health and when it last changed", func() {
+				firstTransition := timeProvider.Time().UnixNano()
+
+				err := registry.RecordHealth("a-container", api.Health{
+					Status:       api.HealthHealthy,
+					HealthyCount: 1,
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+
+				timeProvider.Increment(time.Second)
+
+				err = registry.RecordHealth("a-container", api.Health{
+					Status:       api.HealthHealthy,
+					HealthyCount: 2,
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+
+				timeProvider.Increment(time.Second)
+				secondTransition := timeProvider.Time().UnixNano()
+
+				err = registry.RecordHealth("a-container", api.Health{
+					Status:         api.HealthUnhealthy,
+					UnhealthyCount: 1,
+					LastCheckError: "nope",
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+
+				container, err := registry.FindByGuid("a-container")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(*container.Health).Should(Equal(api.Health{
+					Status:           api.HealthUnhealthy,
+					UnhealthyCount:   1,
+					LastCheckError:   "nope",
+					LastTransitionAt: secondTransition,
+					Transitions: []api.HealthTransition{
+						{From: api.HealthUnknown, To: api.HealthHealthy, At: firstTransition},
+						{From: api.HealthHealthy, To: api.HealthUnhealthy, At: secondTransition},
+					},
+				}))
+			})
+
+			It("keeps the last errors once the container recovers", func() {
+				err := registry.RecordHealth("a-container", api.Health{
+					Status:         api.HealthUnhealthy,
+					UnhealthyCount: 1,
+					LastCheckError: "nope",
+					LastHookError:  "hook failed",
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+
+				err = registry.RecordHealth("a-container", api.Health{
+					Status:       api.HealthHealthy,
+					HealthyCount: 1,
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+
+				container, err := registry.FindByGuid("a-container")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(container.Health.Status).Should(Equal(api.HealthHealthy))
+				Ω(container.Health.LastCheckError).Should(Equal("nope"))
+				Ω(container.Health.LastHookError).Should(Equal("hook failed"))
+			})
+
+			It("keeps only the most recent transitions", func() {
+				for i := 0; i < api.MaxHealthTransitions+5; i++ {
+					status := api.HealthHealthy
+					if i%2 == 1 {
+						status = api.HealthUnhealthy
+					}
+
+					err := registry.RecordHealth("a-container", api.Health{Status: status})
+					Ω(err).ShouldNot(HaveOccurred())
+				}
+
+				container, err := registry.FindByGuid("a-container")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(container.Health.Transitions).Should(HaveLen(api.MaxHealthTransitions))
+				Ω(container.Health.Transitions[api.MaxHealthTransitions-1].To).Should(Equal(api.HealthHealthy))
+			})
+		})
+
+		Context("when the container does not exist", func() {
+			It("should return an ErrContainerNotFound", func() {
+				err := registry.RecordHealth("a-container", api.Health{Status: api.HealthHealthy})
+				Ω(err).Should(MatchError(ErrContainerNotFound))
+			})
+		})
+	})
+
+	Describe("recording steps", func() {
+		Context("when the container exists", func() {
+			BeforeEach(func() {
+				_, err := registry.Reserve("a-container", api.ContainerAllocationRequest{
+					MemoryMB: 50,
+					DiskMB:   100,
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+
+			It("replaces the steps with the latest ones", func() {
+				err := registry.RecordSteps("a-container", []api.StepProgress{
+					{Action: "run", Status: api.StepRunning},
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+
+				err = registry.RecordSteps("a-container", []api.StepProgress{
+					{Action: "run", Status: api.StepSucceeded},
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+
+				container, err := registry.FindByGuid("a-container")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(container.Steps).Should(Equal([]api.StepProgress{
+					{Action: "run", Status: api.StepSucceeded},
+				}))
+			})
+		})
+
+		Context("when the container does not exist", func() {
+			It("should return an ErrContainerNotFound", func() {
+				err := registry.RecordSteps("a-container", nil)
+				Ω(err).Should(MatchError(ErrContainerNotFound))
+			})
+		})
+	})
+
 	Describe("deleting a container", func() {
 		var deleteErr error
 
@@ -0,0 +1,57 @@
+package sequence
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CleanupError is every failure met cleaning up a group of steps.
+type CleanupError struct {
+	Errors []error
+}
+
+func (err *CleanupError) Error() string {
+	messages := make([]string, len(err.Errors))
+	for i, cleanupErr := range err.Errors {
+		messages[i] = cleanupErr.Error()
+	}
+
+	return fmt.Sprintf("%d cleanup(s) failed: %s", len(err.Errors), strings.Join(messages, "; "))
+}
+
+// StepCleanupError is a failure to clean up one step, along with the action
+// the step was built from.
+type StepCleanupError struct {
+	Action string
+	Err    error
+}
+
+func (err *StepCleanupError) Error() string {
+	if err.Action == "" {
+		return fmt.Sprintf("cleaning up: %s", err.Err.Error())
+	}
+
+	return fmt.Sprintf("cleaning up %s: %s", err.Action, err.Err.Error())
+}
+
+// Cleanups aggregates errs into a single CleanupError, flattening any that
+// are already aggregated. It is nil if there were no errors.
+func Cleanups(errs ...error) error {
+	flattened := []error{}
+
+	for _, err := range errs {
+		switch cleanupErr := err.(type) {
+		case nil:
+		case *CleanupError:
+			flattened = append(flattened, cleanupErr.Errors...)
+		default:
+			flattened = append(flattened, err)
+		}
+	}
+
+	if len(flattened) == 0 {
+		return nil
+	}
+
+	return &CleanupError{Errors: flattened}
+}
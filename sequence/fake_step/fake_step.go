@@ -1,29 +1,36 @@
 // This file was generated by counterfeiter
 package fake_step
 
-import "sync"
+import (
+	"context"
+	"sync"
+)
 
 type FakeStep struct {
-	PerformStub        func() error
+	PerformStub        func(ctx context.Context) error
 	performMutex       sync.RWMutex
-	performArgsForCall []struct{}
-	performReturns     struct {
+	performArgsForCall []struct {
+		ctx context.Context
+	}
+	performReturns struct {
 		result1 error
 	}
-	CancelStub         func()
-	cancelMutex        sync.RWMutex
-	cancelArgsForCall  []struct{}
-	CleanupStub        func()
+	CleanupStub        func() error
 	cleanupMutex       sync.RWMutex
 	cleanupArgsForCall []struct{}
+	cleanupReturns     struct {
+		result1 error
+	}
 }
 
-func (fake *FakeStep) Perform() error {
+func (fake *FakeStep) Perform(ctx context.Context) error {
 	fake.performMutex.Lock()
-	defer fake.performMutex.Unlock()
-	fake.performArgsForCall = append(fake.performArgsForCall, struct{}{})
+	fake.performArgsForCall = append(fake.performArgsForCall, struct {
+		ctx context.Context
+	}{ctx})
+	fake.performMutex.Unlock()
 	if fake.PerformStub != nil {
-		return fake.PerformStub()
+		return fake.PerformStub(ctx)
 	} else {
 		return fake.performReturns.result1
 	}
@@ -35,33 +42,26 @@ func (fake *FakeStep) PerformCallCount() int {
 	return len(fake.performArgsForCall)
 }
 
+func (fake *FakeStep) PerformArgsForCall(i int) context.Context {
+	fake.performMutex.RLock()
+	defer fake.performMutex.RUnlock()
+	return fake.performArgsForCall[i].ctx
+}
+
 func (fake *FakeStep) PerformReturns(result1 error) {
 	fake.performReturns = struct {
 		result1 error
 	}{result1}
 }
 
-func (fake *FakeStep) Cancel() {
-	fake.cancelMutex.Lock()
-	defer fake.cancelMutex.Unlock()
-	fake.cancelArgsForCall = append(fake.cancelArgsForCall, struct{}{})
-	if fake.CancelStub != nil {
-		fake.CancelStub()
-	}
-}
-
-func (fake *FakeStep) CancelCallCount() int {
-	fake.cancelMutex.RLock()
-	defer fake.cancelMutex.RUnlock()
-	return len(fake.cancelArgsForCall)
-}
-
-func (fake *FakeStep) Cleanup() {
+func (fake *FakeStep) Cleanup() error {
 	fake.cleanupMutex.Lock()
 	defer fake.cleanupMutex.Unlock()
 	fake.cleanupArgsForCall = append(fake.cleanupArgsForCall, struct{}{})
 	if fake.CleanupStub != nil {
-		fake.CleanupStub()
+		return fake.CleanupStub()
+	} else {
+		return fake.cleanupReturns.result1
 	}
 }
 
@@ -70,3 +70,9 @@ func (fake *FakeStep) CleanupCallCount() int {
 	defer fake.cleanupMutex.RUnlock()
 	return len(fake.cleanupArgsForCall)
 }
+
+func (fake *FakeStep) CleanupReturns(result1 error) {
+	fake.cleanupReturns = struct {
+		result1 error
+	}{result1}
+}
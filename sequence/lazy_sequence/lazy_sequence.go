@@ -1,6 +1,7 @@
 package lazy_sequence
 
 import (
+	"context"
 	"sync"
 
 	"github.com/cloudfoundry-incubator/executor/sequence"
@@ -11,8 +12,6 @@ type StepGenerator func() []sequence.Step
 type LazySequence struct {
 	stepGenerator StepGenerator
 
-	cancelled bool
-
 	sequence *sequence.Sequence
 
 	actionMutex *sync.Mutex
@@ -26,37 +25,27 @@ func New(stepGenerator StepGenerator) sequence.Step {
 	}
 }
 
-func (lazySequence *LazySequence) Perform() error {
-	lazySequence.actionMutex.Lock()
-
-	if lazySequence.cancelled {
-		lazySequence.actionMutex.Unlock()
+func (lazySequence *LazySequence) Perform(ctx context.Context) error {
+	if ctx.Err() != nil {
 		return sequence.CancelledError
 	}
 
-	lazySequence.sequence = sequence.New(lazySequence.stepGenerator())
-	lazySequence.actionMutex.Unlock()
-
-	return lazySequence.sequence.Perform()
-}
-
-func (lazySequence *LazySequence) Cancel() {
 	lazySequence.actionMutex.Lock()
+	lazySequence.sequence = sequence.New(lazySequence.stepGenerator())
 	action := lazySequence.sequence
-	lazySequence.cancelled = true
 	lazySequence.actionMutex.Unlock()
 
-	if action != nil {
-		action.Cancel()
-	}
+	return action.Perform(ctx)
 }
 
-func (lazySequence *LazySequence) Cleanup() {
+func (lazySequence *LazySequence) Cleanup() error {
 	lazySequence.actionMutex.Lock()
 	action := lazySequence.sequence
 	lazySequence.actionMutex.Unlock()
 
-	if action != nil {
-		action.Cleanup()
+	if action == nil {
+		return nil
 	}
+
+	return action.Cleanup()
 }
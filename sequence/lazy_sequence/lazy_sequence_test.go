@@ -1,6 +1,8 @@
 package lazy_sequence_test
 
 import (
+	"context"
+
 	"github.com/cloudfoundry-incubator/executor/sequence"
 	"github.com/cloudfoundry-incubator/executor/sequence/fake_step"
 	. "github.com/cloudfoundry-incubator/executor/sequence/lazy_sequence"
@@ -34,7 +36,7 @@ var _ = Describe("LazySequence", func() {
 
 			generatedSteps = []sequence.Step{
 				&fake_step.FakeStep{
-					PerformStub: func() error {
+					PerformStub: func(context.Context) error {
 						performed = true
 						return nil
 					},
@@ -43,7 +45,7 @@ var _ = Describe("LazySequence", func() {
 		})
 
 		It("invokes the generator and performs its steps", func() {
-			err := lazySequence.Perform()
+			err := lazySequence.Perform(context.Background())
 			Ω(err).ShouldNot(HaveOccurred())
 
 			Ω(invokedGenerator).Should(BeTrue())
@@ -51,26 +53,18 @@ var _ = Describe("LazySequence", func() {
 		})
 	})
 
-	Describe("Cancel", func() {
+	Describe("cancelling", func() {
 		var performing chan bool
-		var cancelled chan bool
 
 		BeforeEach(func() {
-			canceling := make(chan bool)
-
 			performing = make(chan bool)
-			cancelled = make(chan bool)
 
 			generatedSteps = []sequence.Step{
 				&fake_step.FakeStep{
-					PerformStub: func() error {
+					PerformStub: func(ctx context.Context) error {
 						performing <- true
-						<-canceling
-						return nil
-					},
-					CancelStub: func() {
-						canceling <- true
-						cancelled <- true
+						<-ctx.Done()
+						return ctx.Err()
 					},
 				},
 			}
@@ -78,17 +72,18 @@ var _ = Describe("LazySequence", func() {
 
 		Context("when the step is running", func() {
 			It("cancels it", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
 				errs := make(chan error)
 
 				go func() {
-					errs <- lazySequence.Perform()
+					errs <- lazySequence.Perform(ctx)
 				}()
 
 				Eventually(performing).Should(Receive())
 
-				lazySequence.Cancel()
-
-				Eventually(cancelled).Should(Receive())
+				cancel()
 
 				var err error
 				Eventually(errs).Should(Receive(&err))
@@ -96,12 +91,15 @@ var _ = Describe("LazySequence", func() {
 			})
 		})
 
-		Context("when the step is not yet running", func() {
-			It("prevents it from running", func() {
-				lazySequence.Cancel()
+		Context("when the context is already done", func() {
+			It("does not generate the steps", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
 
-				err := lazySequence.Perform()
+				err := lazySequence.Perform(ctx)
 				Ω(err).Should(Equal(sequence.CancelledError))
+
+				Ω(invokedGenerator).Should(BeFalse())
 			})
 		})
 	})
@@ -114,8 +112,9 @@ var _ = Describe("LazySequence", func() {
 
 			generatedSteps = []sequence.Step{
 				&fake_step.FakeStep{
-					CleanupStub: func() {
+					CleanupStub: func() error {
 						cleanedUp = true
+						return nil
 					},
 				},
 			}
@@ -123,10 +122,10 @@ var _ = Describe("LazySequence", func() {
 
 		Context("when the step has performed", func() {
 			It("cleans it up", func() {
-				err := lazySequence.Perform()
+				err := lazySequence.Perform(context.Background())
 				Ω(err).ShouldNot(HaveOccurred())
 
-				lazySequence.Cleanup()
+				Ω(lazySequence.Cleanup()).ShouldNot(HaveOccurred())
 
 				Ω(cleanedUp).Should(BeTrue())
 			})
@@ -134,7 +133,7 @@ var _ = Describe("LazySequence", func() {
 
 		Context("when the step has not yet performed", func() {
 			It("does nothing, successfully", func() {
-				lazySequence.Cleanup()
+				Ω(lazySequence.Cleanup()).ShouldNot(HaveOccurred())
 			})
 		})
 	})
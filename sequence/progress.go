@@ -0,0 +1,192 @@
+package sequence
+
+import (
+	"context"
+	"path"
+	"sync"
+
+	"github.com/cloudfoundry-incubator/executor/api"
+	"github.com/cloudfoundry/gunk/timeprovider"
+)
+
+// Progress is a tree recording when each step of a run starts and ends and
+// how it went. The root stands for the whole run; its children, and theirs,
+// are added to mirror the nesting of the actions.
+//
+// Every change is passed to onChange as a fresh snapshot of the steps, in
+// order, so whoever is watching never sees a half-updated tree.
+type Progress struct {
+	root         *Progress
+	lock         *sync.Mutex
+	timeProvider timeprovider.TimeProvider
+	onChange     func([]api.StepProgress)
+
+	path     string
+	step     api.StepProgress
+	children []*Progress
+}
+
+func NewProgress(timeProvider timeprovider.TimeProvider, onChange func([]api.StepProgress)) *Progress {
+	progress := &Progress{
+		lock:         new(sync.Mutex),
+		timeProvider: timeProvider,
+		onChange:     onChange,
+	}
+
+	progress.root = progress
+
+	return progress
+}
+
+// Child adds a pending step for action underneath this one.
+func (progress *Progress) Child(action string) *Progress {
+	progress.lock.Lock()
+	defer progress.lock.Unlock()
+
+	child := &Progress{
+		root:         progress.root,
+		lock:         progress.lock,
+		timeProvider: progress.timeProvider,
+		path:         path.Join(progress.path, action),
+		step: api.StepProgress{
+			Action: action,
+			Status: api.StepPending,
+		},
+	}
+
+	progress.children = append(progress.children, child)
+
+	return child
+}
+
+// Detached is like Child, but leaves the step out of the tree, so nothing
+// done underneath it is ever reported.
+func (progress *Progress) Detached(action string) *Progress {
+	detached := &Progress{
+		lock:         new(sync.Mutex),
+		timeProvider: progress.timeProvider,
+		path:         path.Join(progress.path, action),
+		step: api.StepProgress{
+			Action: action,
+			Status: api.StepPending,
+		},
+	}
+
+	detached.root = detached
+
+	return detached
+}
+
+// Path names the actions from the root down to this step, such as
+// "emit_progress/run". The root's is empty.
+func (progress *Progress) Path() string {
+	return progress.path
+}
+
+// Steps is a snapshot of the steps underneath this one.
+func (progress *Progress) Steps() []api.StepProgress {
+	progress.lock.Lock()
+	defer progress.lock.Unlock()
+
+	return progress.snapshot()
+}
+
+func (progress *Progress) action() string {
+	progress.lock.Lock()
+	defer progress.lock.Unlock()
+
+	return progress.step.Action
+}
+
+func (progress *Progress) start() {
+	progress.update(func(step *api.StepProgress) {
+		step.Status = api.StepRunning
+		step.StartedAt = progress.timeProvider.Time().UnixNano()
+		step.EndedAt = 0
+		step.Error = ""
+	})
+}
+
+func (progress *Progress) finish(status string, err error) {
+	progress.update(func(step *api.StepProgress) {
+		step.Status = status
+		step.EndedAt = progress.timeProvider.Time().UnixNano()
+
+		if err != nil {
+			step.Error = err.Error()
+		}
+	})
+}
+
+// update changes the step under the lock and reports the change while still
+// holding it, so snapshots reach onChange in the order they were taken.
+func (progress *Progress) update(change func(*api.StepProgress)) {
+	progress.lock.Lock()
+	defer progress.lock.Unlock()
+
+	change(&progress.step)
+
+	if progress.root.onChange != nil {
+		progress.root.onChange(progress.root.snapshot())
+	}
+}
+
+func (progress *Progress) snapshot() []api.StepProgress {
+	if len(progress.children) == 0 {
+		return nil
+	}
+
+	steps := make([]api.StepProgress, len(progress.children))
+	for i, child := range progress.children {
+		steps[i] = child.step
+		steps[i].Steps = child.snapshot()
+	}
+
+	return steps
+}
+
+type trackedStep struct {
+	step     Step
+	progress *Progress
+}
+
+// Track records the step's progress as it is performed.
+func Track(step Step, progress *Progress) Step {
+	return &trackedStep{
+		step:     step,
+		progress: progress,
+	}
+}
+
+func (tracked *trackedStep) Perform(ctx context.Context) error {
+	tracked.progress.start()
+
+	err := tracked.step.Perform(ctx)
+
+	switch {
+	case ctx.Err() != nil || err == CancelledError:
+		tracked.progress.finish(api.StepCancelled, err)
+	case err != nil:
+		tracked.progress.finish(api.StepFailed, err)
+	default:
+		tracked.progress.finish(api.StepSucceeded, nil)
+	}
+
+	return err
+}
+
+// Cleanup names the action that failed to clean up, unless the step was
+// made up of others that already did.
+func (tracked *trackedStep) Cleanup() error {
+	err := tracked.step.Cleanup()
+
+	switch err.(type) {
+	case nil, *CleanupError, *StepCleanupError:
+		return err
+	}
+
+	return &StepCleanupError{
+		Action: tracked.progress.action(),
+		Err:    err,
+	}
+}
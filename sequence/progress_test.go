@@ -0,0 +1,193 @@
+package sequence_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cloudfoundry-incubator/executor/api"
+	. "github.com/cloudfoundry-incubator/executor/sequence"
+	"github.com/cloudfoundry-incubator/executor/sequence/fake_step"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Progress", func() {
+	var (
+		timeProvider *faketimeprovider.FakeTimeProvider
+		snapshots    [][]api.StepProgress
+		progress     *Progress
+		startTime    int64
+	)
+
+	BeforeEach(func() {
+		timeProvider = faketimeprovider.New(time.Now())
+		startTime = timeProvider.Time().UnixNano()
+
+		snapshots = nil
+		progress = NewProgress(timeProvider, func(steps []api.StepProgress) {
+			snapshots = append(snapshots, steps)
+		})
+	})
+
+	It("starts out with every step pending", func() {
+		parallel := progress.Child("parallel")
+		parallel.Child("run")
+		progress.Child("upload")
+
+		Ω(progress.Steps()).Should(Equal([]api.StepProgress{
+			{
+				Action: "parallel",
+				Status: api.StepPending,
+				Steps: []api.StepProgress{
+					{Action: "run", Status: api.StepPending},
+				},
+			},
+			{Action: "upload", Status: api.StepPending},
+		}))
+
+		Ω(snapshots).Should(BeEmpty())
+	})
+
+	It("knows the path to each step", func() {
+		parallel := progress.Child("parallel")
+		run := parallel.Child("run")
+
+		Ω(progress.Path()).Should(BeEmpty())
+		Ω(parallel.Path()).Should(Equal("parallel"))
+		Ω(run.Path()).Should(Equal("parallel/run"))
+	})
+
+	Describe("a detached step", func() {
+		It("is left out of the tree, along with what is done underneath it", func() {
+			monitor := progress.Child("monitor")
+			check := monitor.Detached("run")
+
+			Ω(check.Path()).Should(Equal("monitor/run"))
+
+			err := Track(new(fake_step.FakeStep), check.Child("try")).Perform(context.Background())
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(progress.Steps()).Should(Equal([]api.StepProgress{
+				{Action: "monitor", Status: api.StepPending},
+			}))
+
+			Ω(snapshots).Should(BeEmpty())
+		})
+	})
+
+	Describe("tracking a step", func() {
+		var (
+			step     *fake_step.FakeStep
+			tracked  Step
+			duration time.Duration
+		)
+
+		BeforeEach(func() {
+			duration = 3 * time.Second
+
+			step = new(fake_step.FakeStep)
+			tracked = Track(step, progress.Child("run"))
+		})
+
+		Context("when the step succeeds", func() {
+			BeforeEach(func() {
+				step.PerformStub = func(context.Context) error {
+					timeProvider.Increment(duration)
+					return nil
+				}
+			})
+
+			It("records when it ran and reports every change", func() {
+				Ω(tracked.Perform(context.Background())).ShouldNot(HaveOccurred())
+
+				Ω(snapshots).Should(Equal([][]api.StepProgress{
+					{{Action: "run", Status: api.StepRunning, StartedAt: startTime}},
+					{{Action: "run", Status: api.StepSucceeded, StartedAt: startTime, EndedAt: startTime + int64(duration)}},
+				}))
+			})
+		})
+
+		Context("when the step fails", func() {
+			disaster := errors.New("oh no")
+
+			BeforeEach(func() {
+				step.PerformReturns(disaster)
+			})
+
+			It("records the error", func() {
+				Ω(tracked.Perform(context.Background())).Should(Equal(disaster))
+
+				Ω(progress.Steps()).Should(Equal([]api.StepProgress{
+					{Action: "run", Status: api.StepFailed, StartedAt: startTime, EndedAt: startTime, Error: "oh no"},
+				}))
+			})
+		})
+
+		Context("when the step is cancelled", func() {
+			BeforeEach(func() {
+				step.PerformStub = func(ctx context.Context) error {
+					<-ctx.Done()
+					return nil
+				}
+			})
+
+			It("records it as cancelled", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				result := make(chan error)
+				go func() { result <- tracked.Perform(ctx) }()
+
+				Eventually(func() string {
+					return progress.Steps()[0].Status
+				}).Should(Equal(api.StepRunning))
+
+				cancel()
+				Eventually(result).Should(Receive(BeNil()))
+
+				Ω(progress.Steps()[0].Status).Should(Equal(api.StepCancelled))
+			})
+		})
+
+		Context("when cleaning up the step fails", func() {
+			It("names the action", func() {
+				disaster := errors.New("oh no")
+				step.CleanupReturns(disaster)
+
+				err := tracked.Cleanup()
+				Ω(err).Should(Equal(&StepCleanupError{Action: "run", Err: disaster}))
+				Ω(err.Error()).Should(Equal("cleaning up run: oh no"))
+			})
+
+			Context("and the step is made up of others", func() {
+				It("passes their errors along as they are", func() {
+					aggregated := &CleanupError{Errors: []error{
+						&StepCleanupError{Action: "download", Err: errors.New("oh no")},
+					}}
+
+					step.CleanupReturns(aggregated)
+
+					Ω(tracked.Cleanup()).Should(Equal(aggregated))
+				})
+			})
+		})
+
+		Context("when the step is performed again", func() {
+			It("records the latest run", func() {
+				step.PerformReturns(errors.New("oh no"))
+				tracked.Perform(context.Background())
+
+				timeProvider.Increment(duration)
+				step.PerformReturns(nil)
+				tracked.Perform(context.Background())
+
+				Ω(progress.Steps()).Should(Equal([]api.StepProgress{
+					{Action: "run", Status: api.StepSucceeded, StartedAt: startTime + int64(duration), EndedAt: startTime + int64(duration)},
+				}))
+			})
+		})
+	})
+})
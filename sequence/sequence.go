@@ -1,12 +1,14 @@
 package sequence
 
 import (
+	"context"
 	"errors"
 )
 
 type Sequence struct {
-	steps  []Step
-	cancel chan struct{}
+	steps []Step
+
+	cleanupErr error
 }
 
 var CancelledError = errors.New("steps cancelled")
@@ -14,49 +16,50 @@ var CancelledError = errors.New("steps cancelled")
 func New(steps []Step) *Sequence {
 	return &Sequence{
 		steps: steps,
-
-		cancel: make(chan struct{}),
 	}
 }
 
-func (runner *Sequence) Perform() error {
+// Perform performs the steps in order, stopping at the first to fail. Once
+// ctx is done no further steps are started, and the sequence fails with
+// CancelledError. Steps that succeeded are cleaned up in reverse order; a
+// failure to clean one up does not stop the rest, nor fail the sequence,
+// but is reported by Cleanup.
+func (runner *Sequence) Perform(ctx context.Context) error {
 	var performResult error
 
-	cleanups := []func(){}
+	cleanups := []func() error{}
 
-steps:
 	for _, action := range runner.steps {
-		subactionResult := make(chan error, 1)
-
-		go func() {
-			subactionResult <- action.Perform()
-		}()
-
-		select {
-		case err := <-subactionResult:
-			if err != nil {
-				performResult = err
-				break steps
-			} else {
-				cleanups = append(cleanups, action.Cleanup)
+		if ctx.Err() != nil {
+			performResult = CancelledError
+			break
+		}
+
+		err := action.Perform(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				err = CancelledError
 			}
 
-		case <-runner.cancel:
-			action.Cancel()
-			performResult = CancelledError
-			break steps
+			performResult = err
+			break
 		}
+
+		cleanups = append(cleanups, action.Cleanup)
 	}
 
+	cleanupErrs := []error{}
 	for i := len(cleanups) - 1; i >= 0; i-- {
-		cleanups[i]()
+		cleanupErrs = append(cleanupErrs, cleanups[i]())
 	}
 
+	runner.cleanupErr = Cleanups(cleanupErrs...)
+
 	return performResult
 }
 
-func (runner *Sequence) Cancel() {
-	close(runner.cancel)
+// Cleanup has nothing left to do, as Perform already cleaned up the steps;
+// it returns every failure to do so, as a CleanupError.
+func (runner *Sequence) Cleanup() error {
+	return runner.cleanupErr
 }
-
-func (runner *Sequence) Cleanup() {}
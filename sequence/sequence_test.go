@@ -1,6 +1,7 @@
 package sequence_test
 
 import (
+	"context"
 	"errors"
 
 	. "github.com/onsi/ginkgo"
@@ -18,19 +19,19 @@ var _ = Describe("Sequence", func() {
 
 		sequence := New([]Step{
 			&fake_step.FakeStep{
-				PerformStub: func() error {
+				PerformStub: func(context.Context) error {
 					seq <- 1
 					return nil
 				},
 			},
 			&fake_step.FakeStep{
-				PerformStub: func() error {
+				PerformStub: func(context.Context) error {
 					seq <- 2
 					return nil
 				},
 			},
 			&fake_step.FakeStep{
-				PerformStub: func() error {
+				PerformStub: func(context.Context) error {
 					seq <- 3
 					return nil
 				},
@@ -38,7 +39,7 @@ var _ = Describe("Sequence", func() {
 		})
 
 		result := make(chan error)
-		go func() { result <- sequence.Perform() }()
+		go func() { result <- sequence.Perform(context.Background()) }()
 
 		Ω(<-seq).Should(Equal(1))
 		Ω(<-seq).Should(Equal(2))
@@ -54,24 +55,27 @@ var _ = Describe("Sequence", func() {
 
 		sequence := New([]Step{
 			&fake_step.FakeStep{
-				CleanupStub: func() {
+				CleanupStub: func() error {
 					cleanup <- 1
+					return nil
 				},
 			},
 			&fake_step.FakeStep{
-				CleanupStub: func() {
+				CleanupStub: func() error {
 					cleanup <- 2
+					return nil
 				},
 			},
 			&fake_step.FakeStep{
-				CleanupStub: func() {
+				CleanupStub: func() error {
 					cleanup <- 3
+					return nil
 				},
 			},
 		})
 
 		result := make(chan error)
-		go func() { result <- sequence.Perform() }()
+		go func() { result <- sequence.Perform(context.Background()) }()
 
 		Ω(<-cleanup).Should(Equal(3))
 		Ω(<-cleanup).Should(Equal(2))
@@ -80,6 +84,59 @@ var _ = Describe("Sequence", func() {
 		Ω(<-result).Should(BeNil())
 	})
 
+	Context("when cleaning up steps fails", func() {
+		It("cleans up the rest, succeeds, and reports every failure from Cleanup", func() {
+			disaster1 := errors.New("leaked one")
+			disaster2 := errors.New("leaked two")
+
+			step1 := &fake_step.FakeStep{}
+			step1.CleanupReturns(disaster1)
+
+			step2 := &fake_step.FakeStep{}
+
+			step3 := &fake_step.FakeStep{}
+			step3.CleanupReturns(disaster2)
+
+			sequence := New([]Step{step1, step2, step3})
+
+			Ω(sequence.Perform(context.Background())).Should(BeNil())
+
+			Ω(step1.CleanupCallCount()).Should(Equal(1))
+			Ω(step2.CleanupCallCount()).Should(Equal(1))
+			Ω(step3.CleanupCallCount()).Should(Equal(1))
+
+			Ω(sequence.Cleanup()).Should(Equal(&CleanupError{
+				Errors: []error{disaster2, disaster1},
+			}))
+		})
+	})
+
+	Context("when every step cleans up", func() {
+		It("reports nothing from Cleanup", func() {
+			sequence := New([]Step{&fake_step.FakeStep{}})
+
+			Ω(sequence.Perform(context.Background())).Should(BeNil())
+			Ω(sequence.Cleanup()).Should(BeNil())
+		})
+	})
+
+	Describe("Cleanups", func() {
+		It("is nil when there are no errors", func() {
+			Ω(Cleanups()).Should(BeNil())
+			Ω(Cleanups(nil, nil)).Should(BeNil())
+		})
+
+		It("flattens errors that are already aggregated", func() {
+			disaster1 := errors.New("one")
+			disaster2 := errors.New("two")
+			disaster3 := errors.New("three")
+
+			err := Cleanups(disaster1, nil, &CleanupError{Errors: []error{disaster2, disaster3}})
+			Ω(err).Should(Equal(&CleanupError{Errors: []error{disaster1, disaster2, disaster3}}))
+			Ω(err.Error()).Should(Equal("3 cleanup(s) failed: one; two; three"))
+		})
+	})
+
 	Context("when an step fails in the middle", func() {
 		It("sends back the error and does not continue performing, and cleans up completed steps", func(done Done) {
 			defer close(done)
@@ -91,35 +148,38 @@ var _ = Describe("Sequence", func() {
 
 			sequence := New([]Step{
 				&fake_step.FakeStep{
-					PerformStub: func() error {
+					PerformStub: func(context.Context) error {
 						seq <- 1
 						return nil
 					},
-					CleanupStub: func() {
+					CleanupStub: func() error {
 						cleanup <- 1
+						return nil
 					},
 				},
 				&fake_step.FakeStep{
-					PerformStub: func() error {
+					PerformStub: func(context.Context) error {
 						return disaster
 					},
-					CleanupStub: func() {
+					CleanupStub: func() error {
 						cleanup <- 2
+						return nil
 					},
 				},
 				&fake_step.FakeStep{
-					PerformStub: func() error {
+					PerformStub: func(context.Context) error {
 						seq <- 3
 						return nil
 					},
-					CleanupStub: func() {
+					CleanupStub: func() error {
 						cleanup <- 3
+						return nil
 					},
 				},
 			})
 
 			result := make(chan error)
-			go func() { result <- sequence.Perform() }()
+			go func() { result <- sequence.Perform(context.Background()) }()
 
 			Ω(<-seq).Should(Equal(1))
 			Ω(<-cleanup).Should(Equal(1))
@@ -131,71 +191,64 @@ var _ = Describe("Sequence", func() {
 		})
 	})
 
-	Context("when the sequence is canceled in the middle", func() {
-		It("cancels the running step and waits for completed steps to be cleaned up", func(done Done) {
+	Context("when the context is cancelled in the middle", func() {
+		It("interrupts the running step, does not continue, and cleans up completed steps", func(done Done) {
 			defer close(done)
 
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
 			seq := make(chan int, 3)
 			cleanup := make(chan int, 3)
 
 			waitingForInterrupt := make(chan bool)
-			interrupt := make(chan bool)
-			interrupted := make(chan bool)
-
-			startCleanup := make(chan bool)
 
 			sequence := New([]Step{
 				&fake_step.FakeStep{
-					PerformStub: func() error {
+					PerformStub: func(context.Context) error {
 						seq <- 1
 						return nil
 					},
-					CleanupStub: func() {
-						<-startCleanup
+					CleanupStub: func() error {
 						cleanup <- 1
+						return nil
 					},
 				},
 				&fake_step.FakeStep{
-					PerformStub: func() error {
+					PerformStub: func(ctx context.Context) error {
 						seq <- 2
 
 						waitingForInterrupt <- true
-						<-interrupt
-						interrupted <- true
+						<-ctx.Done()
 
-						return nil
+						return ctx.Err()
 					},
-					CancelStub: func() {
-						interrupt <- true
-					},
-					CleanupStub: func() {
+					CleanupStub: func() error {
 						cleanup <- 2
+						return nil
 					},
 				},
 				&fake_step.FakeStep{
-					PerformStub: func() error {
+					PerformStub: func(context.Context) error {
 						seq <- 3
 						return nil
 					},
-					CleanupStub: func() {
+					CleanupStub: func() error {
 						cleanup <- 3
+						return nil
 					},
 				},
 			})
 
 			result := make(chan error)
-			go func() { result <- sequence.Perform() }()
+			go func() { result <- sequence.Perform(ctx) }()
 
 			Ω(<-seq).Should(Equal(1))
 			Ω(<-seq).Should(Equal(2))
 
 			<-waitingForInterrupt
 
-			sequence.Cancel()
-
-			<-interrupted
-
-			startCleanup <- true
+			cancel()
 
 			Ω(<-cleanup).Should(Equal(1))
 
@@ -205,4 +258,16 @@ var _ = Describe("Sequence", func() {
 			Consistently(cleanup).ShouldNot(Receive())
 		})
 	})
+
+	Context("when the context is already done", func() {
+		It("performs nothing and sends back CancelledError", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			step := &fake_step.FakeStep{}
+
+			Ω(New([]Step{step}).Perform(ctx)).Should(Equal(CancelledError))
+			Ω(step.PerformCallCount()).Should(Equal(0))
+		})
+	})
 })
@@ -1,7 +1,11 @@
 package sequence
 
+import "context"
+
+// Step is one part of a run. Perform should give up promptly once ctx is
+// done; Cleanup undoes whatever a successful Perform left behind, and says
+// if it could not.
 type Step interface {
-	Perform() error
-	Cancel()
-	Cleanup()
+	Perform(ctx context.Context) error
+	Cleanup() error
 }
@@ -36,7 +36,7 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	w.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(w).Encode(resource)
+	err = json.NewEncoder(w).Encode(resource.WithoutSecrets())
 	if err != nil {
 		getLog.Error("failed-to-marshal-response", err)
 		return
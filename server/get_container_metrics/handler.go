@@ -0,0 +1,44 @@
+package get_container_metrics
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/cloudfoundry-incubator/executor/api"
+	"github.com/cloudfoundry-incubator/executor/server/error_headers"
+	"github.com/pivotal-golang/lager"
+)
+
+type handler struct {
+	depotClient api.Client
+	logger      lager.Logger
+}
+
+func New(depotClient api.Client, logger lager.Logger) http.Handler {
+	return &handler{
+		depotClient: depotClient,
+		logger:      logger,
+	}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	guid := r.FormValue(":guid")
+
+	getLog := h.logger.Session("get-metrics-handler")
+
+	metrics, err := h.depotClient.GetContainerMetrics(guid)
+	if err != nil {
+		getLog.Error("failed-to-get-container-metrics", err)
+		error_headers.Write(err, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(metrics)
+	if err != nil {
+		getLog.Error("failed-to-marshal-response", err)
+		return
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/cloudfoundry-incubator/executor/server/allocate_container"
 	"github.com/cloudfoundry-incubator/executor/server/delete_container"
 	"github.com/cloudfoundry-incubator/executor/server/get_container"
+	"github.com/cloudfoundry-incubator/executor/server/get_container_metrics"
 	"github.com/cloudfoundry-incubator/executor/server/initialize_container"
 	"github.com/cloudfoundry-incubator/executor/server/list_containers"
 	"github.com/cloudfoundry-incubator/executor/server/ping"
@@ -62,6 +63,7 @@ func (s *Server) NewHandlers() rata.Handlers {
 	return rata.Handlers{
 		api.AllocateContainer:     allocate_container.New(s.DepotClient, s.Logger),
 		api.GetContainer:          get_container.New(s.DepotClient, s.Logger),
+		api.GetContainerMetrics:   get_container_metrics.New(s.DepotClient, s.Logger),
 		api.ListContainers:        list_containers.New(s.DepotClient, s.Logger),
 		api.DeleteContainer:       delete_container.New(s.DepotClient, s.Logger),
 		api.GetRemainingResources: remaining_resources.New(s.DepotClient, s.Logger),
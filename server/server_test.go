@@ -109,6 +109,27 @@ var _ = Describe("Api", func() {
 
 				Ω(container).Should(Equal(expectedContainer))
 			})
+
+			Context("when the container has secret environment variables", func() {
+				BeforeEach(func() {
+					expectedContainer.Env = []api.EnvironmentVariable{
+						{Name: "PASSWORD", Value: "hunter2", Secret: true},
+						{Name: "HOME", Value: "/home/vcap"},
+					}
+					depotClient.GetContainerReturns(expectedContainer, nil)
+				})
+
+				It("leaves them out", func() {
+					container := api.Container{}
+
+					err := json.NewDecoder(getResponse.Body).Decode(&container)
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(container.Env).Should(Equal([]api.EnvironmentVariable{
+						{Name: "HOME", Value: "/home/vcap"},
+					}))
+				})
+			})
 		})
 
 		Context("when the container does not exist", func() {
@@ -132,6 +153,58 @@ var _ = Describe("Api", func() {
 		})
 	})
 
+	Describe("GET /containers/:guid/metrics", func() {
+		var getResponse *http.Response
+
+		JustBeforeEach(func() {
+			getResponse = DoRequest(generator.CreateRequest(
+				api.GetContainerMetrics,
+				rata.Params{"guid": containerGuid},
+				nil,
+			))
+		})
+
+		Context("when the container exists", func() {
+			var expectedMetrics api.ContainerMetrics
+
+			BeforeEach(func() {
+				expectedMetrics = api.ContainerMetrics{
+					Guid:    containerGuid,
+					Current: &api.UsageSample{Time: 2, MemoryBytes: 2048},
+					History: []api.UsageSample{
+						{Time: 1, MemoryBytes: 1024},
+						{Time: 2, MemoryBytes: 2048},
+					},
+				}
+				depotClient.GetContainerMetricsReturns(expectedMetrics, nil)
+			})
+
+			It("returns 200 OK", func() {
+				Ω(getResponse.StatusCode).Should(Equal(http.StatusOK))
+			})
+
+			It("returns the container's metrics", func() {
+				metrics := api.ContainerMetrics{}
+
+				err := json.NewDecoder(getResponse.Body).Decode(&metrics)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(metrics).Should(Equal(expectedMetrics))
+				Ω(depotClient.GetContainerMetricsArgsForCall(0)).Should(Equal(containerGuid))
+			})
+		})
+
+		Context("when the container does not exist", func() {
+			BeforeEach(func() {
+				depotClient.GetContainerMetricsReturns(api.ContainerMetrics{}, api.ErrContainerNotFound)
+			})
+
+			It("returns 404 Not Found", func() {
+				Ω(getResponse.StatusCode).Should(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
 	Describe("POST /containers/:guid", func() {
 		var reserveRequestBody io.Reader
 		var reserveResponse *http.Response
@@ -445,6 +518,39 @@ var _ = Describe("Api", func() {
 			})
 		})
 
+		Context("when containers have secret environment variables", func() {
+			var listResponse *http.Response
+
+			BeforeEach(func() {
+				depotClient.ListContainersReturns([]api.Container{
+					api.Container{
+						Guid: "first-container",
+						Env: []api.EnvironmentVariable{
+							{Name: "PASSWORD", Value: "hunter2", Secret: true},
+							{Name: "HOME", Value: "/home/vcap"},
+						},
+					},
+				}, nil)
+
+				listResponse = DoRequest(generator.CreateRequest(
+					api.ListContainers,
+					nil,
+					nil,
+				))
+			})
+
+			It("leaves them out", func() {
+				containers := []api.Container{}
+				err := json.NewDecoder(listResponse.Body).Decode(&containers)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(containers).Should(HaveLen(1))
+				Ω(containers[0].Env).Should(Equal([]api.EnvironmentVariable{
+					{Name: "HOME", Value: "/home/vcap"},
+				}))
+			})
+		})
+
 		Context("when we cannot get containers", func() {
 			var listResponse *http.Response
 
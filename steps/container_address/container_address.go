@@ -0,0 +1,49 @@
+package container_address
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry-incubator/garden/warden"
+)
+
+var ErrNoContainerIP = errors.New("container has no IP")
+var ErrInvalidPort = errors.New("port must be between 1 and 65535")
+
+// IP looks up a container's IP for the steps that reach it from the
+// executor. It keeps asking until the container has one; a container keeps
+// its IP for as long as it lives.
+type IP struct {
+	container warden.Container
+	ip        string
+}
+
+func NewIP(container warden.Container) *IP {
+	return &IP{container: container}
+}
+
+func (ip *IP) Get() (string, error) {
+	if ip.ip != "" {
+		return ip.ip, nil
+	}
+
+	info, err := ip.container.Info()
+	if err != nil {
+		return "", err
+	}
+
+	if info.ContainerIP == "" {
+		return "", ErrNoContainerIP
+	}
+
+	ip.ip = info.ContainerIP
+
+	return ip.ip, nil
+}
+
+func ValidatePort(port uint32) error {
+	if port < 1 || port > 65535 {
+		return ErrInvalidPort
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package container_address_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestContainerAddress(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Container Address Suite")
+}
@@ -0,0 +1,94 @@
+package container_address_test
+
+import (
+	"errors"
+	"strconv"
+
+	. "github.com/cloudfoundry-incubator/executor/steps/container_address"
+	"github.com/cloudfoundry-incubator/garden/warden"
+	"github.com/cloudfoundry-incubator/garden/warden/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IP", func() {
+	var (
+		container *fakes.FakeContainer
+		ip        *IP
+	)
+
+	BeforeEach(func() {
+		container = new(fakes.FakeContainer)
+		container.InfoReturns(warden.ContainerInfo{ContainerIP: "10.0.0.1"}, nil)
+
+		ip = NewIP(container)
+	})
+
+	It("returns the container's IP, looking it up only once", func() {
+		Ω(ip.Get()).Should(Equal("10.0.0.1"))
+		Ω(ip.Get()).Should(Equal("10.0.0.1"))
+
+		Ω(container.InfoCallCount()).Should(Equal(1))
+	})
+
+	Context("when the container has no IP yet", func() {
+		BeforeEach(func() {
+			container.InfoReturns(warden.ContainerInfo{}, nil)
+		})
+
+		It("fails, and looks it up again the next time", func() {
+			_, err := ip.Get()
+			Ω(err).Should(Equal(ErrNoContainerIP))
+
+			container.InfoReturns(warden.ContainerInfo{ContainerIP: "10.0.0.1"}, nil)
+
+			Ω(ip.Get()).Should(Equal("10.0.0.1"))
+			Ω(container.InfoCallCount()).Should(Equal(2))
+		})
+	})
+
+	Context("when getting the container's info fails", func() {
+		disaster := errors.New("oh no")
+
+		BeforeEach(func() {
+			container.InfoReturns(warden.ContainerInfo{}, disaster)
+		})
+
+		It("returns the error", func() {
+			_, err := ip.Get()
+			Ω(err).Should(Equal(disaster))
+		})
+	})
+})
+
+var _ = Describe("ValidatePort", func() {
+	ports := []struct {
+		port  uint32
+		valid bool
+	}{
+		{0, false},
+		{1, true},
+		{8080, true},
+		{65535, true},
+		{65536, false},
+	}
+
+	for _, p := range ports {
+		p := p
+
+		if p.valid {
+			It("accepts port "+portString(p.port), func() {
+				Ω(ValidatePort(p.port)).ShouldNot(HaveOccurred())
+			})
+		} else {
+			It("rejects port "+portString(p.port), func() {
+				Ω(ValidatePort(p.port)).Should(Equal(ErrInvalidPort))
+			})
+		}
+	}
+})
+
+func portString(port uint32) string {
+	return strconv.FormatUint(uint64(port), 10)
+}
@@ -0,0 +1,145 @@
+package download_step
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+var ErrUnsupportedArchiveType = errors.New("unsupported archive type")
+
+func writeFile(tarWriter *tar.Writer, name string, source io.Reader, size int64) error {
+	err := tarWriter.WriteHeader(&tar.Header{
+		Name:       name,
+		Size:       size,
+		Mode:       0644,
+		AccessTime: time.Now(),
+		ChangeTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.CopyN(tarWriter, source, size)
+	return err
+}
+
+func writeRootDirectory(tarWriter *tar.Writer) error {
+	return tarWriter.WriteHeader(&tar.Header{
+		Name:     "./",
+		Typeflag: tar.TypeDir,
+		Mode:     0755,
+		ModTime:  time.Now(),
+	})
+}
+
+func writeTgzEntries(tarWriter *tar.Writer, source io.Reader) error {
+	gzipReader, err := gzip.NewReader(source)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	err = writeRootDirectory(tarWriter)
+	if err != nil {
+		return err
+	}
+
+	tarReader := tar.NewReader(gzipReader)
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if isRoot(header.Name) {
+			continue
+		}
+
+		err = tarWriter.WriteHeader(header)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(tarWriter, tarReader)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func writeZipEntries(tarWriter *tar.Writer, zipReader *zip.Reader) error {
+	err := writeRootDirectory(tarWriter)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range zipReader.File {
+		if isRoot(file.Name) {
+			continue
+		}
+
+		err := writeZipEntry(tarWriter, file)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeZipEntry(tarWriter *tar.Writer, file *zip.File) error {
+	contents, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer contents.Close()
+
+	fileInfo := file.FileInfo()
+
+	link := ""
+	if fileInfo.Mode()&os.ModeSymlink != 0 {
+		target, err := ioutil.ReadAll(contents)
+		if err != nil {
+			return err
+		}
+
+		link = string(target)
+	}
+
+	header, err := tar.FileInfoHeader(fileInfo, link)
+	if err != nil {
+		return err
+	}
+
+	header.Name = file.Name
+
+	err = tarWriter.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+
+	if header.Typeflag == tar.TypeReg {
+		_, err = io.Copy(tarWriter, contents)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isRoot(name string) bool {
+	return strings.TrimSuffix(name, "/") == "."
+}
@@ -2,54 +2,101 @@ package download_step
 
 import (
 	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
-	"time"
+	"strings"
 
+	"github.com/cloudfoundry-incubator/executor/cancellable"
 	"github.com/cloudfoundry-incubator/executor/steps/emittable_error"
+	"github.com/cloudfoundry-incubator/executor/steps/transfer_progress"
 	"github.com/cloudfoundry-incubator/garden/warden"
 	"github.com/cloudfoundry-incubator/runtime-schema/models"
-	"github.com/pivotal-golang/archiver/compressor"
-	"github.com/pivotal-golang/archiver/extractor"
 	"github.com/pivotal-golang/cacheddownloader"
 	"github.com/pivotal-golang/lager"
 )
 
+// DefaultMaxSpoolSize bounds how much of a download is spooled into tempDir.
+const DefaultMaxSpoolSize = 1024 * 1024 * 1024
+
+var ErrUnsupportedChecksumAlgorithm = errors.New("unsupported checksum algorithm")
+var ErrIncompleteChecksum = errors.New("checksum algorithm and value must be given together")
+
+type SpoolTooLargeError struct {
+	Limit int64
+}
+
+func (err SpoolTooLargeError) Error() string {
+	return fmt.Sprintf("download exceeds the spool limit of %d bytes", err.Limit)
+}
+
+// randomAccessFile is what the cached downloader hands back for anything it
+// has on disk; payloads that arrive as plain readers are spooled into one.
+type randomAccessFile interface {
+	io.ReadSeeker
+	io.ReaderAt
+	io.Closer
+}
+
 type DownloadStep struct {
 	container        warden.Container
 	model            models.DownloadAction
 	cachedDownloader cacheddownloader.CachedDownloader
-	extractor        extractor.Extractor
 	tempDir          string
+	maxSpoolSize     int64
+	progress         *transfer_progress.Reporter
 	logger           lager.Logger
+
+	spooled    []string
+	cleanupErr error
 }
 
 func New(
 	container warden.Container,
 	model models.DownloadAction,
 	cachedDownloader cacheddownloader.CachedDownloader,
-	extractor extractor.Extractor,
 	tempDir string,
+	maxSpoolSize int64,
+	progress *transfer_progress.Reporter,
 	logger lager.Logger,
 ) *DownloadStep {
 	return &DownloadStep{
 		container:        container,
 		model:            model,
 		cachedDownloader: cachedDownloader,
-		extractor:        extractor,
 		tempDir:          tempDir,
+		maxSpoolSize:     maxSpoolSize,
+		progress:         progress,
 		logger:           logger,
 	}
 }
 
-func (step *DownloadStep) Perform() error {
+// Perform fetches the payload through the cached downloader, which always
+// lands it in a file first: the cache or the downloader's temp dir keeps the
+// whole download so it can be retried and checked against its ETag. From
+// there it is piped into the container without being copied again, except
+// that a zip which does not come back as a file is spooled into tempDir,
+// since its directory is at the end. A spool may not grow past maxSpoolSize.
+func (step *DownloadStep) Perform(ctx context.Context) error {
 	step.logger.Info("download")
 
-	//Stream this to the extractor + container when we have streaming support!
-	downloadedPath, err := step.download()
+	step.cleanupErr = nil
+	defer step.removeSpooled()
+
+	fetched, err := step.fetch(ctx)
 	if err != nil {
 		step.logger.Error("failed-to-download", err, lager.Data{
 			"from": step.model.From,
@@ -58,138 +105,330 @@ func (step *DownloadStep) Perform() error {
 
 		return err
 	}
+	defer fetched.Close()
 
-	defer os.Remove(downloadedPath)
+	var source io.Reader = fetched
 
-	if step.model.Extract {
-		extractionDir, err := step.extract(downloadedPath)
+	// verifying a checksum before anything reaches the container, and writing
+	// the tar header of a single file, both need to know the whole payload
+	if step.model.ChecksumAlgorithm != "" || !step.model.Extract {
+		file, err := step.randomAccess(ctx, fetched)
 		if err != nil {
-			return emittable_error.New(err, "Extraction failed")
+			return spoolFailed(err, "Copying into the container failed")
 		}
+		defer file.Close()
 
-		defer os.RemoveAll(extractionDir)
-
-		err = step.copyExtractedFiles(extractionDir, step.model.To)
+		err = step.verifyChecksum(file)
 		if err != nil {
-			return emittable_error.New(err, "Copying into the container failed")
-		}
-
-		return err
-	} else {
-		reader, writer := io.Pipe()
+			step.logger.Error("checksum-mismatch", err, lager.Data{
+				"from":      step.model.From,
+				"cache-key": step.model.CacheKey,
+			})
 
-		go writeTarTo(filepath.Base(step.model.To), downloadedPath, writer)
+			if step.model.CacheKey != "" {
+				step.cachedDownloader.Remove(step.model.CacheKey)
+			}
 
-		err = step.container.StreamIn(filepath.Dir(step.model.To), reader)
-		if err != nil {
-			return emittable_error.New(err, "Copying into the container failed")
+			return emittable_error.New(err, "Checksum mismatch")
 		}
 
-		return nil
+		source = file
+	}
+
+	if step.model.Extract {
+		return step.streamExtractedFiles(ctx, source)
 	}
+
+	return step.streamFile(ctx, source.(randomAccessFile))
 }
 
-func (step *DownloadStep) download() (string, error) {
+// Cleanup reports a failure to remove a file Perform spooled into tempDir.
+func (step *DownloadStep) Cleanup() error {
+	return step.cleanupErr
+}
+
+func (step *DownloadStep) fetch(ctx context.Context) (io.ReadCloser, error) {
 	url, err := url.ParseRequestURI(step.model.From)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
+	step.progress.Start()
+	defer step.progress.Stop()
+
+	return step.cachedDownloader.Fetch(ctx, url, step.model.CacheKey, step.progress.Progress)
+}
+
+func (step *DownloadStep) randomAccess(ctx context.Context, source io.ReadCloser) (randomAccessFile, error) {
+	file, ok := source.(randomAccessFile)
+	if ok {
+		return file, nil
+	}
+
+	return step.spool(ctx, source)
+}
+
+// spool copies source into tempDir, failing once it passes maxSpoolSize. The
+// file is removed as soon as Perform is done with it, however the step ends.
+func (step *DownloadStep) spool(ctx context.Context, source io.Reader) (*os.File, error) {
 	tempFile, err := ioutil.TempFile(step.tempDir, "downloaded")
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	step.spooled = append(step.spooled, tempFile.Name())
+
+	source = cancellable.Reader(ctx, source)
+	if step.maxSpoolSize > 0 {
+		source = io.LimitReader(source, step.maxSpoolSize+1)
 	}
-	defer tempFile.Close()
 
-	downloadedFile, err := step.cachedDownloader.Fetch(url, step.model.CacheKey)
+	n, err := io.Copy(tempFile, source)
 	if err != nil {
-		return "", err
+		tempFile.Close()
+		return nil, err
 	}
-	defer downloadedFile.Close()
 
-	_, err = io.Copy(tempFile, downloadedFile)
+	if step.maxSpoolSize > 0 && n > step.maxSpoolSize {
+		tempFile.Close()
+		return nil, SpoolTooLargeError{Limit: step.maxSpoolSize}
+	}
+
+	_, err = tempFile.Seek(0, 0)
 	if err != nil {
-		return "", err
+		tempFile.Close()
+		return nil, err
 	}
 
-	return tempFile.Name(), nil
+	return tempFile, nil
 }
 
-func (step *DownloadStep) extract(downloadedPath string) (string, error) {
-	extractionDir, err := ioutil.TempDir(step.tempDir, "extracted")
+func (step *DownloadStep) removeSpooled() {
+	for _, path := range step.spooled {
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			step.logger.Error("failed-to-remove-spooled-file", err, lager.Data{
+				"path": path,
+			})
+
+			if step.cleanupErr == nil {
+				step.cleanupErr = err
+			}
+		}
+	}
+
+	step.spooled = nil
+}
+
+func (step *DownloadStep) verifyChecksum(file io.ReadSeeker) error {
+	if step.model.ChecksumAlgorithm == "" {
+		return nil
+	}
+
+	checksum, err := newChecksum(step.model.ChecksumAlgorithm)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	err = step.extractor.Extract(downloadedPath, extractionDir)
+	_, err = io.Copy(checksum, file)
 	if err != nil {
-		step.logger.Error("failed-to-extract", err, lager.Data{
-			"url": step.model.From,
-		})
+		return err
+	}
+
+	_, err = file.Seek(0, 0)
+	if err != nil {
+		return err
+	}
+
+	actual := hex.EncodeToString(checksum.Sum(nil))
+	if !strings.EqualFold(step.model.ChecksumValue, actual) {
+		return fmt.Errorf(
+			"expected %s checksum %s, got %s",
+			step.model.ChecksumAlgorithm,
+			step.model.ChecksumValue,
+			actual,
+		)
+	}
+
+	return nil
+}
+
+func (step *DownloadStep) streamFile(ctx context.Context, file io.ReadSeeker) error {
+	size, err := file.Seek(0, 2)
+	if err != nil {
+		return emittable_error.New(err, "Copying into the container failed")
+	}
 
-		return "", err
+	_, err = file.Seek(0, 0)
+	if err != nil {
+		return emittable_error.New(err, "Copying into the container failed")
 	}
 
-	return extractionDir, nil
+	return step.streamIn(ctx, filepath.Dir(step.model.To), func(tarWriter *tar.Writer) error {
+		return writeFile(tarWriter, filepath.Base(step.model.To), file, size)
+	})
 }
 
-func (step *DownloadStep) Cancel() {}
+func (step *DownloadStep) streamExtractedFiles(ctx context.Context, source io.Reader) error {
+	archiveType, source, err := detectArchiveType(source)
+	if err != nil {
+		return emittable_error.New(err, "Extraction failed")
+	}
+
+	switch archiveType {
+	case "application/x-gzip":
+		return step.streamIn(ctx, step.model.To, func(tarWriter *tar.Writer) error {
+			return writeTgzEntries(tarWriter, source)
+		})
+
+	case "application/zip":
+		// zip's central directory is at the end, so it needs random access
+		file, ok := source.(randomAccessFile)
+		if !ok {
+			spooled, err := step.spool(ctx, source)
+			if err != nil {
+				return spoolFailed(err, "Extraction failed")
+			}
+			defer spooled.Close()
+
+			file = spooled
+		}
+
+		size, err := file.Seek(0, 2)
+		if err != nil {
+			return emittable_error.New(err, "Extraction failed")
+		}
+
+		zipReader, err := zip.NewReader(file, size)
+		if err != nil {
+			return emittable_error.New(err, "Extraction failed")
+		}
+
+		return step.streamIn(ctx, step.model.To, func(tarWriter *tar.Writer) error {
+			return writeZipEntries(tarWriter, zipReader)
+		})
+	}
+
+	step.logger.Error("failed-to-extract", ErrUnsupportedArchiveType, lager.Data{
+		"url":  step.model.From,
+		"type": archiveType,
+	})
 
-func (step *DownloadStep) Cleanup() {}
+	return emittable_error.New(ErrUnsupportedArchiveType, "Extraction failed")
+}
 
-func (step *DownloadStep) copyExtractedFiles(source string, destination string) error {
+// streamIn pipes whatever writeTar produces straight into the container, so
+// neither the extracted files nor the tar are written to disk on the way in.
+// Once ctx is done the pipe is broken, so the stream ends early and fails.
+func (step *DownloadStep) streamIn(ctx context.Context, destination string, writeTar func(*tar.Writer) error) error {
 	reader, writer := io.Pipe()
 
+	stopBreaking := cancellable.CloseOnDone(ctx, brokenPipe{writer, ctx})
+	defer stopBreaking()
+
+	tarResult := make(chan error, 1)
+
 	go func() {
-		err := compressor.WriteTar(source+string(filepath.Separator), writer)
+		tarWriter := tar.NewWriter(writer)
+
+		err := writeTar(tarWriter)
 		if err == nil {
-			writer.Close()
-		} else {
-			writer.CloseWithError(err)
+			err = tarWriter.Close()
 		}
+
+		writer.CloseWithError(err)
+		tarResult <- err
 	}()
 
-	return step.container.StreamIn(destination, reader)
+	streamErr := step.container.StreamIn(destination, reader)
+
+	reader.Close()
+
+	tarErr := <-tarResult
+	if tarErr != nil && tarErr != io.ErrClosedPipe {
+		step.logger.Error("failed-to-write-tar", tarErr, lager.Data{
+			"url": step.model.From,
+		})
+
+		if step.model.Extract {
+			return emittable_error.New(tarErr, "Extraction failed")
+		}
+
+		return emittable_error.New(tarErr, "Copying into the container failed")
+	}
+
+	if streamErr != nil {
+		return emittable_error.New(streamErr, "Copying into the container failed")
+	}
+
+	return nil
 }
 
-func writeTarTo(name string, sourcePath string, destination *io.PipeWriter) {
-	source, err := os.Open(sourcePath)
-	if err != nil {
-		destination.CloseWithError(err)
-		return
+// brokenPipe closes the write end of a pipe with ctx's error, so the reader
+// sees why it ended.
+type brokenPipe struct {
+	writer *io.PipeWriter
+	ctx    context.Context
+}
+
+func (pipe brokenPipe) Close() error {
+	return pipe.writer.CloseWithError(pipe.ctx.Err())
+}
+
+// detectArchiveType sniffs the start of source the way the archiver's
+// extractors do. The returned reader starts at the beginning of the payload.
+func detectArchiveType(source io.Reader) (string, io.Reader, error) {
+	if file, ok := source.(randomAccessFile); ok {
+		header := make([]byte, 512)
+
+		n, err := file.ReadAt(header, 0)
+		if err != nil && err != io.EOF {
+			return "", nil, err
+		}
+
+		return http.DetectContentType(header[:n]), file, nil
 	}
-	defer source.Close()
 
-	tarWriter := tar.NewWriter(destination)
+	buffered := bufio.NewReader(source)
 
-	fileInfo, err := source.Stat()
-	if err != nil {
-		destination.CloseWithError(err)
-		return
+	header, err := buffered.Peek(512)
+	if err != nil && err != io.EOF {
+		return "", nil, err
 	}
 
-	err = tarWriter.WriteHeader(&tar.Header{
-		Name:       name,
-		Size:       fileInfo.Size(),
-		Mode:       0644,
-		AccessTime: time.Now(),
-		ChangeTime: time.Now(),
-	})
-	if err != nil {
-		destination.CloseWithError(err)
-		return
+	return http.DetectContentType(header), buffered, nil
+}
+
+func spoolFailed(err error, message string) error {
+	if _, ok := err.(SpoolTooLargeError); ok {
+		return emittable_error.New(err, "Download too large")
 	}
 
-	_, err = io.Copy(tarWriter, source)
-	if err != nil {
-		destination.CloseWithError(err)
-		return
+	return emittable_error.New(err, message)
+}
+
+func ValidateChecksum(model models.DownloadAction) error {
+	if (model.ChecksumAlgorithm == "") != (model.ChecksumValue == "") {
+		return ErrIncompleteChecksum
 	}
 
-	if err := tarWriter.Flush(); err != nil {
-		destination.CloseWithError(err)
-		return
+	if model.ChecksumAlgorithm == "" {
+		return nil
+	}
+
+	_, err := newChecksum(model.ChecksumAlgorithm)
+	return err
+}
+
+func newChecksum(algorithm string) (hash.Hash, error) {
+	switch strings.ToLower(algorithm) {
+	case "md5":
+		return md5.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
 	}
 
-	destination.Close()
+	return nil, ErrUnsupportedChecksumAlgorithm
 }
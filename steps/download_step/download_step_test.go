@@ -3,9 +3,14 @@ package download_step_test
 import (
 	"archive/tar"
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"io"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/pivotal-golang/cacheddownloader/fakecacheddownloader"
@@ -16,45 +21,57 @@ import (
 	"github.com/cloudfoundry-incubator/runtime-schema/models"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 
 	"github.com/cloudfoundry-incubator/executor/sequence"
 	. "github.com/cloudfoundry-incubator/executor/steps/download_step"
 	"github.com/cloudfoundry-incubator/executor/steps/emittable_error"
-	"github.com/pivotal-golang/archiver/extractor"
+	"github.com/cloudfoundry-incubator/executor/steps/transfer_progress"
 	archiveHelper "github.com/pivotal-golang/archiver/extractor/test_helper"
 )
 
 var _ = Describe("DownloadAction", func() {
 	var step sequence.Step
-	var result chan error
 
 	var downloadAction models.DownloadAction
 	var cache *fakecacheddownloader.FakeCachedDownloader
 	var tempDir string
+	var maxSpoolSize int64
 	var wardenClient *fake_warden_client.FakeClient
 	var logger *lagertest.TestLogger
+	var downloaded int64
 
 	handle := "some-container-handle"
 
 	BeforeEach(func() {
 		var err error
 
-		result = make(chan error)
-
 		cache = &fakecacheddownloader.FakeCachedDownloader{}
 
 		tempDir, err = ioutil.TempDir("", "download-action-tmpdir")
 		Ω(err).ShouldNot(HaveOccurred())
 
+		maxSpoolSize = 0
+
 		wardenClient = fake_warden_client.New()
 
 		logger = lagertest.NewTestLogger("test")
+
+		downloaded = 0
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
 	})
 
 	Describe("Perform", func() {
 		var stepErr error
+		var ctx context.Context
+		var cancel context.CancelFunc
 
 		BeforeEach(func() {
+			ctx, cancel = context.WithCancel(context.Background())
+
 			downloadAction = models.DownloadAction{
 				From:     "http://mr_jones",
 				To:       "/tmp/Antarctica",
@@ -73,17 +90,52 @@ var _ = Describe("DownloadAction", func() {
 				container,
 				downloadAction,
 				cache,
-				extractor.NewZip(),
 				tempDir,
+				maxSpoolSize,
+				transfer_progress.New("Downloaded", ioutil.Discard, 0, func(transferred int64) {
+					downloaded += transferred
+				}),
 				logger,
 			)
 
-			stepErr = step.Perform()
+			stepErr = step.Perform(ctx)
 		})
 
 		Context("when extract is false", func() {
 			var tarReader *tar.Reader
 
+			Context("when the download is larger than the spool limit", func() {
+				BeforeEach(func() {
+					maxSpoolSize = 1023
+					cache.FetchedContent = []byte(strings.Repeat("7", 1024))
+				})
+
+				It("returns an emittable error", func() {
+					Ω(stepErr).Should(HaveOccurred())
+					Ω(stepErr.(*emittable_error.EmittableError).EmittableError()).Should(Equal("Download too large"))
+					Ω(stepErr.Error()).Should(ContainSubstring(SpoolTooLargeError{Limit: 1023}.Error()))
+				})
+
+				It("does not copy anything into the container", func() {
+					Ω(wardenClient.Connection.StreamInCallCount()).Should(Equal(0))
+				})
+
+				It("removes the partial spool file", func() {
+					Ω(ioutil.ReadDir(tempDir)).Should(BeEmpty())
+				})
+			})
+
+			Context("when the download is exactly the spool limit", func() {
+				BeforeEach(func() {
+					maxSpoolSize = 1024
+					cache.FetchedContent = []byte(strings.Repeat("7", 1024))
+				})
+
+				It("does not return an error", func() {
+					Ω(stepErr).ShouldNot(HaveOccurred())
+				})
+			})
+
 			Context("when streaming in succeeds", func() {
 				BeforeEach(func() {
 					cache.FetchedContent = []byte(strings.Repeat("7", 1024))
@@ -104,6 +156,17 @@ var _ = Describe("DownloadAction", func() {
 				It("asks the cache for the file", func() {
 					Ω(cache.FetchedURL.Host).Should(ContainSubstring("mr_jones"))
 					Ω(cache.FetchedCacheKey).Should(Equal("the-cache-key"))
+					Ω(cache.FetchedContext).Should(Equal(ctx))
+				})
+
+				Context("when the cache has to download the file", func() {
+					BeforeEach(func() {
+						cache.FetchProgress = []int64{512, 1024}
+					})
+
+					It("records the bytes downloaded", func() {
+						Ω(downloaded).Should(Equal(int64(1024)))
+					})
 				})
 
 				It("places the file in the container", func() {
@@ -125,6 +188,123 @@ var _ = Describe("DownloadAction", func() {
 				It("does not return an error", func() {
 					Ω(stepErr).ShouldNot(HaveOccurred())
 				})
+
+				It("leaves nothing behind in the temp dir", func() {
+					Ω(ioutil.ReadDir(tempDir)).Should(BeEmpty())
+				})
+
+				It("cleans up without error", func() {
+					Ω(step.Cleanup()).ShouldNot(HaveOccurred())
+				})
+			})
+
+			Context("when the spooled file cannot be removed", func() {
+				BeforeEach(func() {
+					cache.FetchedContent = []byte(strings.Repeat("7", 1024))
+
+					wardenClient.Connection.StreamInStub = func(handle string, dest string, tarStream io.Reader) error {
+						_, err := io.Copy(ioutil.Discard, tarStream)
+						Ω(err).ShouldNot(HaveOccurred())
+
+						// swap the spooled file for a directory with something in it
+						spooled, err := ioutil.ReadDir(tempDir)
+						Ω(err).ShouldNot(HaveOccurred())
+						Ω(spooled).Should(HaveLen(1))
+
+						path := filepath.Join(tempDir, spooled[0].Name())
+						Ω(os.Remove(path)).ShouldNot(HaveOccurred())
+						Ω(os.MkdirAll(filepath.Join(path, "in-the-way"), 0755)).ShouldNot(HaveOccurred())
+
+						return nil
+					}
+				})
+
+				It("still succeeds", func() {
+					Ω(stepErr).ShouldNot(HaveOccurred())
+				})
+
+				It("reports the failure when cleaned up", func() {
+					err := step.Cleanup()
+					Ω(err).Should(HaveOccurred())
+					Ω(err.Error()).Should(ContainSubstring(tempDir))
+				})
+
+				It("logs the failure", func() {
+					Ω(logger.TestSink.Buffer).Should(gbytes.Say("failed-to-remove-spooled-file"))
+				})
+			})
+
+			Context("when a checksum is given", func() {
+				BeforeEach(func() {
+					cache.FetchedContent = []byte("some-content")
+
+					downloadAction.ChecksumAlgorithm = "sha256"
+					wardenClient.Connection.StreamInStub = func(handle string, dest string, tarStream io.Reader) error {
+						_, err := io.Copy(ioutil.Discard, tarStream)
+						Ω(err).ShouldNot(HaveOccurred())
+						return nil
+					}
+				})
+
+				Context("and it matches the downloaded content", func() {
+					BeforeEach(func() {
+						sum := sha256.Sum256(cache.FetchedContent)
+						downloadAction.ChecksumValue = hex.EncodeToString(sum[:])
+					})
+
+					It("places the file in the container", func() {
+						Ω(stepErr).ShouldNot(HaveOccurred())
+						Ω(wardenClient.Connection.StreamInCallCount()).Should(Equal(1))
+					})
+
+					It("does not evict the cache entry", func() {
+						Ω(cache.RemovedCacheKey).Should(BeEmpty())
+					})
+				})
+
+				Context("and it does not match the downloaded content", func() {
+					BeforeEach(func() {
+						downloadAction.ChecksumValue = "bogus"
+					})
+
+					It("returns an emittable checksum mismatch error", func() {
+						Ω(stepErr).Should(BeAssignableToTypeOf(&emittable_error.EmittableError{}))
+						Ω(stepErr.(*emittable_error.EmittableError).EmittableError()).Should(Equal("Checksum mismatch"))
+					})
+
+					It("does not copy anything into the container", func() {
+						Ω(wardenClient.Connection.StreamInCallCount()).Should(BeZero())
+					})
+
+					It("evicts the cache entry", func() {
+						Ω(cache.RemovedCacheKey).Should(Equal("the-cache-key"))
+					})
+				})
+			})
+
+			Context("when the context is cancelled while streaming in", func() {
+				var streamErr error
+
+				BeforeEach(func() {
+					cache.FetchedContent = []byte(strings.Repeat("7", 1024))
+
+					wardenClient.Connection.StreamInStub = func(handle string, dest string, tarStream io.Reader) error {
+						cancel()
+
+						buf := make([]byte, 512)
+						Eventually(func() error {
+							_, streamErr = tarStream.Read(buf)
+							return streamErr
+						}).Should(HaveOccurred())
+
+						return streamErr
+					}
+				})
+
+				It("breaks off the stream with the context's error", func() {
+					Ω(streamErr).Should(Equal(context.Canceled))
+					Ω(stepErr).Should(MatchError(emittable_error.New(context.Canceled, "Copying into the container failed")))
+				})
 			})
 
 			Context("when there is an error parsing the download url", func() {
@@ -214,6 +394,10 @@ var _ = Describe("DownloadAction", func() {
 						Ω(err).ShouldNot(HaveOccurred())
 						Ω(header.Name).Should(Equal("file1"))
 					})
+
+					It("removes the zip it spooled into the temp dir", func() {
+						Ω(ioutil.ReadDir(tempDir)).Should(BeEmpty())
+					})
 				})
 
 				Context("when there is an error copying the extracted files into the container", func() {
@@ -229,6 +413,59 @@ var _ = Describe("DownloadAction", func() {
 				})
 			})
 
+			Context("and the fetched bits are a valid gzipped tarball", func() {
+				BeforeEach(func() {
+					tmpFile, err := ioutil.TempFile("", "some-tgz")
+					Ω(err).ShouldNot(HaveOccurred())
+
+					archiveHelper.CreateTarGZArchive(tmpFile.Name(), []archiveHelper.ArchiveFile{
+						{
+							Name: "file1",
+							Body: "some-body",
+						},
+					})
+
+					fetchedContent, err := ioutil.ReadFile(tmpFile.Name())
+					Ω(err).ShouldNot(HaveOccurred())
+
+					cache.FetchedContent = fetchedContent
+
+					buffer := &bytes.Buffer{}
+					tarReader = tar.NewReader(buffer)
+
+					wardenClient.Connection.StreamInStub = func(handle string, dest string, tarStream io.Reader) error {
+						Ω(dest).Should(Equal("/tmp/Antarctica"))
+
+						_, err := io.Copy(buffer, tarStream)
+						Ω(err).ShouldNot(HaveOccurred())
+
+						return nil
+					}
+				})
+
+				It("does not return an error", func() {
+					Ω(stepErr).ShouldNot(HaveOccurred())
+				})
+
+				It("streams the extracted files into the container", func() {
+					header, err := tarReader.Next()
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(header.Name).Should(Equal("./"))
+
+					header, err = tarReader.Next()
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(header.Name).Should(Equal("file1"))
+
+					body, err := ioutil.ReadAll(tarReader)
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(string(body)).Should(Equal("some-body"))
+				})
+
+				It("does not spool anything into the temp dir", func() {
+					Ω(ioutil.ReadDir(tempDir)).Should(BeEmpty())
+				})
+			})
+
 			Context("when there is an error extracting the file", func() {
 				BeforeEach(func() {
 					cache.FetchedContent = []byte("not-a-tgz")
@@ -240,4 +477,32 @@ var _ = Describe("DownloadAction", func() {
 			})
 		})
 	})
+
+	Describe("ValidateChecksum", func() {
+		It("accepts actions without a checksum", func() {
+			Ω(ValidateChecksum(models.DownloadAction{})).ShouldNot(HaveOccurred())
+		})
+
+		It("accepts md5, sha1 and sha256", func() {
+			for _, algorithm := range []string{"md5", "sha1", "sha256"} {
+				Ω(ValidateChecksum(models.DownloadAction{
+					ChecksumAlgorithm: algorithm,
+					ChecksumValue:     "abc",
+				})).ShouldNot(HaveOccurred())
+			}
+		})
+
+		It("rejects unknown algorithms", func() {
+			Ω(ValidateChecksum(models.DownloadAction{
+				ChecksumAlgorithm: "crc32",
+				ChecksumValue:     "abc",
+			})).Should(Equal(ErrUnsupportedChecksumAlgorithm))
+		})
+
+		It("rejects an algorithm without a value", func() {
+			Ω(ValidateChecksum(models.DownloadAction{
+				ChecksumAlgorithm: "sha256",
+			})).Should(Equal(ErrIncompleteChecksum))
+		})
+	})
 })
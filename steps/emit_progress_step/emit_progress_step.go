@@ -1,41 +1,85 @@
 package emit_progress_step
 
 import (
+	"bytes"
+	"context"
+	"text/template"
+	"time"
+
 	"github.com/cloudfoundry-incubator/executor/log_streamer"
 	"github.com/cloudfoundry-incubator/executor/sequence"
 	"github.com/cloudfoundry-incubator/executor/steps/emittable_error"
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/pivotal-golang/bytefmt"
 	"github.com/pivotal-golang/lager"
 )
 
+// MessageData is what templated messages are rendered with. Elapsed is zero
+// in the start message, and Error is only set in the failure message.
+type MessageData struct {
+	Step             string
+	Elapsed          time.Duration
+	ExitStatus       int
+	BytesDownloaded  int64
+	BytesUploaded    int64
+	BytesTransferred string
+	Error            string
+}
+
 type EmitProgressStep struct {
 	substep        sequence.Step
 	logger         lager.Logger
+	stepName       string
 	startMessage   string
 	successMessage string
 	failureMessage string
+	templated      bool
+	stats          *Stats
 	streamer       log_streamer.LogStreamer
 }
 
-func New(substep sequence.Step, startMessage, successMessage, failureMessage string, streamer log_streamer.LogStreamer, logger lager.Logger) *EmitProgressStep {
+func New(substep sequence.Step, model models.EmitProgressAction, stats *Stats, streamer log_streamer.LogStreamer, logger lager.Logger) *EmitProgressStep {
 	return &EmitProgressStep{
 		substep:        substep,
 		logger:         logger,
-		startMessage:   startMessage,
-		successMessage: successMessage,
-		failureMessage: failureMessage,
+		stepName:       model.Action.Name(),
+		startMessage:   model.StartMessage,
+		successMessage: model.SuccessMessage,
+		failureMessage: model.FailureMessage,
+		templated:      model.Templated,
+		stats:          stats,
 		streamer:       streamer,
 	}
 }
 
-func (step *EmitProgressStep) Perform() error {
+func ValidateMessages(model models.EmitProgressAction) error {
+	if !model.Templated {
+		return nil
+	}
+
+	for _, message := range []string{model.StartMessage, model.SuccessMessage, model.FailureMessage} {
+		_, err := template.New("message").Parse(message)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (step *EmitProgressStep) Perform(ctx context.Context) error {
+	startedAt := time.Now()
+
 	if step.startMessage != "" {
-		step.streamer.Stdout().Write([]byte(step.startMessage + "\n"))
+		step.streamer.Stdout().Write([]byte(step.render(step.startMessage, 0, nil) + "\n"))
 	}
 
-	err := step.substep.Perform()
+	err := step.substep.Perform(ctx)
+	elapsed := time.Since(startedAt)
+
 	if err != nil {
 		if step.failureMessage != "" {
-			step.streamer.Stderr().Write([]byte(step.failureMessage + "\n"))
+			step.streamer.Stderr().Write([]byte(step.render(step.failureMessage, elapsed, err) + "\n"))
 			emittableError, ok := err.(*emittable_error.EmittableError)
 			if ok {
 				step.streamer.Stderr().Write([]byte(emittableError.EmittableError() + "\n"))
@@ -43,17 +87,55 @@ func (step *EmitProgressStep) Perform() error {
 		}
 	} else {
 		if step.successMessage != "" {
-			step.streamer.Stdout().Write([]byte(step.successMessage + "\n"))
+			step.streamer.Stdout().Write([]byte(step.render(step.successMessage, elapsed, nil) + "\n"))
 		}
 	}
 
 	return err
 }
 
-func (step *EmitProgressStep) Cancel() {
-	step.substep.Cancel()
+// render fills in a templated message. If that fails the message is emitted
+// as it is, rather than not at all.
+func (step *EmitProgressStep) render(message string, elapsed time.Duration, stepErr error) string {
+	if !step.templated {
+		return message
+	}
+
+	exitStatus, downloaded, uploaded := step.stats.snapshot()
+
+	data := MessageData{
+		Step:             step.stepName,
+		Elapsed:          elapsed - elapsed%time.Millisecond,
+		ExitStatus:       exitStatus,
+		BytesDownloaded:  downloaded,
+		BytesUploaded:    uploaded,
+		BytesTransferred: bytefmt.ByteSize(uint64(downloaded + uploaded)),
+	}
+
+	if stepErr != nil {
+		data.Error = stepErr.Error()
+		if emittableError, ok := stepErr.(*emittable_error.EmittableError); ok {
+			data.Error = emittableError.EmittableError()
+		}
+	}
+
+	tmpl, err := template.New("message").Parse(message)
+	if err != nil {
+		step.logger.Error("failed-to-parse-message", err)
+		return message
+	}
+
+	rendered := new(bytes.Buffer)
+
+	err = tmpl.Execute(rendered, data)
+	if err != nil {
+		step.logger.Error("failed-to-render-message", err)
+		return message
+	}
+
+	return rendered.String()
 }
 
-func (step *EmitProgressStep) Cleanup() {
-	step.substep.Cleanup()
+func (step *EmitProgressStep) Cleanup() error {
+	return step.substep.Cleanup()
 }
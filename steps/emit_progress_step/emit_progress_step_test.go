@@ -2,9 +2,11 @@ package emit_progress_step_test
 
 import (
 	"bytes"
+	"context"
 	"errors"
 
 	"github.com/cloudfoundry-incubator/executor/steps/emittable_error"
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
 	"github.com/pivotal-golang/lager/lagertest"
 
 	"github.com/cloudfoundry-incubator/executor/log_streamer/fake_log_streamer"
@@ -20,10 +22,11 @@ var _ = Describe("EmitProgressStep", func() {
 	var step sequence.Step
 	var subStep sequence.Step
 	var cleanedUp bool
-	var cancelled bool
 	var errorToReturn error
 	var fakeStreamer *fake_log_streamer.FakeLogStreamer
 	var startMessage, successMessage, failureMessage string
+	var templated bool
+	var stats *Stats
 	var logger *lagertest.TestLogger
 	var stderrBuffer *bytes.Buffer
 	var stdoutBuffer *bytes.Buffer
@@ -33,22 +36,22 @@ var _ = Describe("EmitProgressStep", func() {
 		stdoutBuffer = new(bytes.Buffer)
 		errorToReturn = nil
 		startMessage, successMessage, failureMessage = "", "", ""
-		cleanedUp, cancelled = false, false
+		templated = false
+		stats = NewStats()
+		cleanedUp = false
 		fakeStreamer = new(fake_log_streamer.FakeLogStreamer)
 
 		fakeStreamer.StderrReturns(stderrBuffer)
 		fakeStreamer.StdoutReturns(stdoutBuffer)
 
 		subStep = &fake_step.FakeStep{
-			PerformStub: func() error {
+			PerformStub: func(context.Context) error {
 				fakeStreamer.Stdout().Write([]byte("RUNNING\n"))
 				return errorToReturn
 			},
-			CleanupStub: func() {
+			CleanupStub: func() error {
 				cleanedUp = true
-			},
-			CancelStub: func() {
-				cancelled = true
+				return nil
 			},
 		}
 
@@ -56,7 +59,13 @@ var _ = Describe("EmitProgressStep", func() {
 	})
 
 	JustBeforeEach(func() {
-		step = New(subStep, startMessage, successMessage, failureMessage, fakeStreamer, logger)
+		step = New(subStep, models.EmitProgressAction{
+			Action:         models.ExecutorAction{Action: models.RunAction{Path: "ls"}},
+			StartMessage:   startMessage,
+			SuccessMessage: successMessage,
+			FailureMessage: failureMessage,
+			Templated:      templated,
+		}, stats, fakeStreamer, logger)
 	})
 
 	Context("running", func() {
@@ -66,7 +75,7 @@ var _ = Describe("EmitProgressStep", func() {
 			})
 
 			It("should emit the start message before performing", func() {
-				err := step.Perform()
+				err := step.Perform(context.Background())
 				Ω(err).ShouldNot(HaveOccurred())
 				Ω(stdoutBuffer.String()).Should(Equal("STARTING\nRUNNING\n"))
 			})
@@ -74,7 +83,7 @@ var _ = Describe("EmitProgressStep", func() {
 
 		Context("when there is no start or success message", func() {
 			It("should not emit the start message (i.e. a newline) before performing", func() {
-				err := step.Perform()
+				err := step.Perform(context.Background())
 				Ω(err).ShouldNot(HaveOccurred())
 				Ω(stdoutBuffer.String()).Should(Equal("RUNNING\n"))
 			})
@@ -86,7 +95,7 @@ var _ = Describe("EmitProgressStep", func() {
 			})
 
 			It("should emit the sucess message", func() {
-				err := step.Perform()
+				err := step.Perform(context.Background())
 				Ω(err).ShouldNot(HaveOccurred())
 				Ω(stdoutBuffer.String()).Should(Equal("RUNNING\nSUCCESS\n"))
 			})
@@ -98,7 +107,7 @@ var _ = Describe("EmitProgressStep", func() {
 			})
 
 			It("should pass the error along", func() {
-				err := step.Perform()
+				err := step.Perform(context.Background())
 				Ω(err).Should(MatchError(errorToReturn))
 			})
 
@@ -108,7 +117,7 @@ var _ = Describe("EmitProgressStep", func() {
 				})
 
 				It("should emit the failure message", func() {
-					step.Perform()
+					step.Perform(context.Background())
 
 					Ω(stdoutBuffer.String()).Should(Equal("RUNNING\n"))
 					Ω(stderrBuffer.String()).Should(Equal("FAIL\n"))
@@ -120,7 +129,7 @@ var _ = Describe("EmitProgressStep", func() {
 					})
 
 					It("should print out the emittable error", func() {
-						step.Perform()
+						step.Perform(context.Background())
 
 						Ω(stdoutBuffer.String()).Should(Equal("RUNNING\n"))
 						Ω(stderrBuffer.String()).Should(Equal("FAIL\nFailed to reticulate\n"))
@@ -134,7 +143,7 @@ var _ = Describe("EmitProgressStep", func() {
 				})
 
 				It("should not emit the failure message or error, even with an emittable error", func() {
-					step.Perform()
+					step.Perform(context.Background())
 
 					Ω(stdoutBuffer.String()).Should(Equal("RUNNING\n"))
 					Ω(stderrBuffer.String()).Should(BeEmpty())
@@ -143,19 +152,134 @@ var _ = Describe("EmitProgressStep", func() {
 		})
 	})
 
+	Context("when the messages are templated", func() {
+		BeforeEach(func() {
+			templated = true
+
+			subStep = &fake_step.FakeStep{
+				PerformStub: func(context.Context) error {
+					stats.RecordTransfer(1024, 0)
+					stats.RecordTransfer(0, 512)
+					stats.RecordExitStatus(3)
+					return errorToReturn
+				},
+			}
+		})
+
+		Context("with a start message", func() {
+			BeforeEach(func() {
+				startMessage = "starting {{.Step}} after {{.Elapsed}} having moved {{.BytesTransferred}}"
+			})
+
+			It("renders it before the step has done anything", func() {
+				step.Perform(context.Background())
+
+				Ω(stdoutBuffer.String()).Should(Equal("starting run after 0s having moved 0\n"))
+			})
+		})
+
+		Context("with a success message", func() {
+			BeforeEach(func() {
+				successMessage = "{{.Step}} exited {{.ExitStatus}} after {{.Elapsed}}, down {{.BytesDownloaded}} up {{.BytesUploaded}} total {{.BytesTransferred}}"
+			})
+
+			It("renders it with what the step did", func() {
+				err := step.Perform(context.Background())
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(stdoutBuffer.String()).Should(MatchRegexp(`^run exited 3 after [0-9.]+[mµn]?s, down 1024 up 512 total 1.5K\n$`))
+			})
+		})
+
+		Context("with a failure message", func() {
+			BeforeEach(func() {
+				errorToReturn = emittable_error.New(errors.New("bam!"), "Failed to reticulate")
+				failureMessage = "{{.Step}} failed: {{.Error}}"
+			})
+
+			It("renders it with the error", func() {
+				step.Perform(context.Background())
+
+				Ω(stderrBuffer.String()).Should(Equal("run failed: Failed to reticulate\nFailed to reticulate\n"))
+			})
+		})
+
+		Context("when a message cannot be rendered", func() {
+			BeforeEach(func() {
+				successMessage = "{{.Nope}}"
+			})
+
+			It("emits it as it is", func() {
+				step.Perform(context.Background())
+
+				Ω(stdoutBuffer.String()).Should(Equal("{{.Nope}}\n"))
+			})
+		})
+	})
+
+	Context("when the messages are not templated", func() {
+		BeforeEach(func() {
+			successMessage = "{{.Step}}"
+		})
+
+		It("emits them as they are", func() {
+			step.Perform(context.Background())
+
+			Ω(stdoutBuffer.String()).Should(Equal("RUNNING\n{{.Step}}\n"))
+		})
+	})
+
+	Describe("ValidateMessages", func() {
+		It("accepts messages that parse as templates", func() {
+			Ω(ValidateMessages(models.EmitProgressAction{
+				StartMessage: "{{.Step}}",
+				Templated:    true,
+			})).ShouldNot(HaveOccurred())
+		})
+
+		It("rejects messages that do not", func() {
+			Ω(ValidateMessages(models.EmitProgressAction{
+				FailureMessage: "{{.Step",
+				Templated:      true,
+			})).Should(HaveOccurred())
+		})
+
+		It("accepts anything when not templated", func() {
+			Ω(ValidateMessages(models.EmitProgressAction{
+				FailureMessage: "{{.Step",
+			})).ShouldNot(HaveOccurred())
+		})
+	})
+
 	Context("when told to clean up", func() {
 		It("passes the message along", func() {
 			Ω(cleanedUp).Should(BeFalse())
-			step.Cleanup()
+			Ω(step.Cleanup()).ShouldNot(HaveOccurred())
 			Ω(cleanedUp).Should(BeTrue())
 		})
+
+		Context("when the substep fails to clean up", func() {
+			disaster := errors.New("leaked")
+
+			BeforeEach(func() {
+				subStep = &fake_step.FakeStep{}
+				subStep.(*fake_step.FakeStep).CleanupReturns(disaster)
+			})
+
+			It("returns its error", func() {
+				Ω(step.Cleanup()).Should(Equal(disaster))
+			})
+		})
 	})
 
-	Context("when told to cancel", func() {
-		It("passes the message along", func() {
-			Ω(cancelled).Should(BeFalse())
-			step.Cancel()
-			Ω(cancelled).Should(BeTrue())
+	Context("when the context is cancelled", func() {
+		It("passes it along to the substep", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			step.Perform(ctx)
+
+			Ω(subStep.(*fake_step.FakeStep).PerformArgsForCall(0)).Should(Equal(ctx))
 		})
 	})
 })
@@ -0,0 +1,43 @@
+package emit_progress_step
+
+import "sync"
+
+// Stats is what the actions inside an emit_progress step got up to, for its
+// messages to report on.
+type Stats struct {
+	lock            sync.Mutex
+	exitStatus      int
+	bytesDownloaded int64
+	bytesUploaded   int64
+}
+
+func NewStats() *Stats {
+	return &Stats{}
+}
+
+// RecordExitStatus keeps the exit status of the latest process run.
+func (stats *Stats) RecordExitStatus(exitStatus int) {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+
+	stats.exitStatus = exitStatus
+}
+
+func (stats *Stats) RecordTransfer(downloaded int64, uploaded int64) {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+
+	stats.bytesDownloaded += downloaded
+	stats.bytesUploaded += uploaded
+}
+
+func (stats *Stats) snapshot() (int, int64, int64) {
+	if stats == nil {
+		return 0, 0, 0
+	}
+
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+
+	return stats.exitStatus, stats.bytesDownloaded, stats.bytesUploaded
+}
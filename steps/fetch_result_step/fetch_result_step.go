@@ -2,77 +2,137 @@ package fetch_result_step
 
 import (
 	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 
+	"github.com/cloudfoundry-incubator/executor/cancellable"
 	"github.com/cloudfoundry-incubator/executor/steps/emittable_error"
 	"github.com/cloudfoundry-incubator/garden/warden"
 	"github.com/cloudfoundry-incubator/runtime-schema/models"
 	"github.com/pivotal-golang/lager"
 )
 
-const MAX_RESULT_SIZE = 1024 * 10
+const DefaultMaxResultSize = 1024 * 10
 
-var ErrResultTooLarge = fmt.Errorf("result file size exceeds limit of %d bytes", MAX_RESULT_SIZE)
+var ErrInvalidJSON = errors.New("result is not valid JSON")
+
+type ResultTooLargeError struct {
+	Limit int64
+}
+
+func (err ResultTooLargeError) Error() string {
+	return fmt.Sprintf("result file size exceeds limit of %d bytes", err.Limit)
+}
 
 type FetchResultStep struct {
 	container         warden.Container
 	fetchResultAction models.FetchResultAction
 	tempDir           string
+	maxResultSize     int64
 	logger            lager.Logger
-	result            *string
+	results           *Results
 }
 
 func New(
 	container warden.Container,
 	fetchResultAction models.FetchResultAction,
 	tempDir string,
+	maxResultSize int64,
 	logger lager.Logger,
-	result *string,
+	results *Results,
 ) *FetchResultStep {
 	return &FetchResultStep{
 		container:         container,
 		fetchResultAction: fetchResultAction,
 		tempDir:           tempDir,
+		maxResultSize:     maxResultSize,
 		logger:            logger,
-		result:            result,
+		results:           results,
 	}
 }
 
-func (step *FetchResultStep) Perform() error {
-	data, err := step.copyAndReadResult()
+func ValidateFormat(model models.FetchResultAction) error {
+	switch model.Format {
+	case "", models.FetchResultFormatJSON:
+		return nil
+	}
+
+	return fmt.Errorf("unsupported result format: %s", model.Format)
+}
+
+func (step *FetchResultStep) Perform(ctx context.Context) error {
+	data, err := step.copyAndReadResult(ctx)
 	if err != nil {
 		return emittable_error.New(err, "Copying out of the container failed")
 	}
 
-	*step.result = string(data)
+	if step.fetchResultAction.Format == models.FetchResultFormatJSON {
+		var parsed interface{}
+
+		err := json.Unmarshal(data, &parsed)
+		if err != nil {
+			step.logger.Error("invalid-json-result", err, lager.Data{
+				"file": step.fetchResultAction.File,
+			})
+
+			return emittable_error.New(ErrInvalidJSON, "Result is not valid JSON")
+		}
+	}
+
+	step.results.Set(step.fetchResultAction.Name, string(data))
 
 	return nil
 }
 
-func (step *FetchResultStep) copyAndReadResult() ([]byte, error) {
+// limit is the executor-wide limit, unless the action asks for less.
+func (step *FetchResultStep) limit() int64 {
+	actionLimit := step.fetchResultAction.MaxSizeBytes
+	if actionLimit > 0 && actionLimit < step.maxResultSize {
+		return actionLimit
+	}
+
+	return step.maxResultSize
+}
+
+func (step *FetchResultStep) copyAndReadResult(ctx context.Context) ([]byte, error) {
 	reader, err := step.container.StreamOut(step.fetchResultAction.File)
 	if err != nil {
 		return nil, err
 	}
 	defer reader.Close()
 
+	stopClosing := cancellable.CloseOnDone(ctx, reader)
+	defer stopClosing()
+
 	tarReader := tar.NewReader(reader)
 
-	_, err = tarReader.Next()
+	header, err := tarReader.Next()
 	if err != nil {
 		return nil, err
 	}
 
-	buf := make([]byte, MAX_RESULT_SIZE+1)
+	limit := step.limit()
 
-	n, err := tarReader.Read(buf)
-	if n > MAX_RESULT_SIZE {
-		return nil, ErrResultTooLarge
+	if header.Size > limit {
+		return nil, ResultTooLargeError{Limit: limit}
 	}
 
-	return buf[:n], nil
-}
+	data, err := ioutil.ReadAll(io.LimitReader(tarReader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > limit {
+		return nil, ResultTooLargeError{Limit: limit}
+	}
 
-func (step *FetchResultStep) Cancel() {}
+	return data, nil
+}
 
-func (step *FetchResultStep) Cleanup() {}
+func (step *FetchResultStep) Cleanup() error {
+	return nil
+}
@@ -3,8 +3,12 @@ package fetch_result_step_test
 import (
 	"archive/tar"
 	"bytes"
+	"context"
 	"errors"
 	"io"
+	"io/ioutil"
+	"strings"
+	"testing/iotest"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -42,19 +46,40 @@ func (b *ClosableBuffer) IsClosed() bool {
 	}
 }
 
+func tarWithContent(content string) *ClosableBuffer {
+	buffer := NewClosableBuffer()
+	tarWriter := tar.NewWriter(buffer)
+
+	err := tarWriter.WriteHeader(&tar.Header{
+		Name: "foo",
+		Size: int64(len(content)),
+	})
+	Ω(err).ShouldNot(HaveOccurred())
+
+	_, err = tarWriter.Write([]byte(content))
+	Ω(err).ShouldNot(HaveOccurred())
+
+	err = tarWriter.Close()
+	Ω(err).ShouldNot(HaveOccurred())
+
+	return buffer
+}
+
 var _ = Describe("FetchResultStep", func() {
 	var (
 		step              sequence.Step
 		fetchResultAction models.FetchResultAction
 		logger            *lagertest.TestLogger
 		wardenClient      *fake_warden_client.FakeClient
-		result            string
+		maxResultSize     int64
+		results           *Results
 	)
 
 	handle := "some-container-handle"
 
 	BeforeEach(func() {
-		result = ""
+		maxResultSize = DefaultMaxResultSize
+		results = NewResults()
 
 		fetchResultAction = models.FetchResultAction{
 			File: "/var/some-dir/foo",
@@ -75,8 +100,9 @@ var _ = Describe("FetchResultStep", func() {
 			container,
 			fetchResultAction,
 			"/tmp",
+			maxResultSize,
 			logger,
-			&result,
+			results,
 		)
 	})
 
@@ -105,12 +131,97 @@ var _ = Describe("FetchResultStep", func() {
 		})
 
 		It("should return the contents of the file", func() {
-			err := step.Perform()
+			err := step.Perform(context.Background())
 			Ω(err).ShouldNot(HaveOccurred())
 
-			Ω(result).Should(Equal("result content"))
+			Ω(results.Result()).Should(Equal("result content"))
+			Ω(results.Named()).Should(BeNil())
 			Ω(buffer.IsClosed()).Should(BeTrue())
 		})
+
+		Context("when the action names its result", func() {
+			BeforeEach(func() {
+				fetchResultAction.Name = "some-result"
+			})
+
+			It("stores the contents under that name", func() {
+				err := step.Perform(context.Background())
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(results.Named()).Should(Equal(map[string]string{"some-result": "result content"}))
+				Ω(results.Result()).Should(BeZero())
+			})
+		})
+
+		Context("when the action asks for a smaller limit than the file", func() {
+			BeforeEach(func() {
+				fetchResultAction.MaxSizeBytes = 5
+			})
+
+			It("should error", func() {
+				err := step.Perform(context.Background())
+				Ω(err).Should(MatchError(emittable_error.New(ResultTooLargeError{Limit: 5}, "Copying out of the container failed")))
+				Ω(results.Result()).Should(BeZero())
+			})
+		})
+
+		Context("when the action asks for a larger limit than the executor allows", func() {
+			BeforeEach(func() {
+				maxResultSize = 5
+				fetchResultAction.MaxSizeBytes = 1024
+			})
+
+			It("enforces the executor's limit", func() {
+				err := step.Perform(context.Background())
+				Ω(err).Should(MatchError(emittable_error.New(ResultTooLargeError{Limit: 5}, "Copying out of the container failed")))
+			})
+		})
+
+		Context("when the format is json", func() {
+			BeforeEach(func() {
+				fetchResultAction.Format = models.FetchResultFormatJSON
+			})
+
+			It("rejects content that is not valid JSON", func() {
+				err := step.Perform(context.Background())
+				Ω(err).Should(MatchError(emittable_error.New(ErrInvalidJSON, "Result is not valid JSON")))
+				Ω(results.Result()).Should(BeZero())
+			})
+		})
+	})
+
+	Context("when the file is valid JSON", func() {
+		BeforeEach(func() {
+			fetchResultAction.Format = models.FetchResultFormatJSON
+
+			wardenClient.Connection.StreamOutStub = func(handle, src string) (io.ReadCloser, error) {
+				return tarWithContent(`{"detected_buildpack":"ruby"}`), nil
+			}
+		})
+
+		It("stores it as is", func() {
+			err := step.Perform(context.Background())
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(results.Result()).Should(Equal(`{"detected_buildpack":"ruby"}`))
+		})
+	})
+
+	Context("when the file arrives in short reads", func() {
+		content := strings.Repeat("x", 4096)
+
+		BeforeEach(func() {
+			wardenClient.Connection.StreamOutStub = func(handle, src string) (io.ReadCloser, error) {
+				return ioutil.NopCloser(iotest.OneByteReader(tarWithContent(content))), nil
+			}
+		})
+
+		It("reads all of it", func() {
+			err := step.Perform(context.Background())
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(results.Result()).Should(Equal(content))
+		})
 	})
 
 	Context("when the file exists but is too large", func() {
@@ -137,15 +248,38 @@ var _ = Describe("FetchResultStep", func() {
 		})
 
 		It("should error", func() {
-			err := step.Perform()
+			err := step.Perform(context.Background())
 			Ω(err.Error()).Should(ContainSubstring("Copying out of the container failed"))
 			Ω(err.Error()).Should(ContainSubstring("result file size exceeds limit"))
 
-			Ω(result).Should(BeZero())
+			Ω(results.Result()).Should(BeZero())
 			Ω(buffer.IsClosed()).Should(BeTrue())
 		})
 	})
 
+	Context("when the context is cancelled while streaming out", func() {
+		var stream *blockingReader
+
+		BeforeEach(func() {
+			stream = newBlockingReader()
+			wardenClient.Connection.StreamOutReturns(stream, nil)
+		})
+
+		It("closes the stream and fails", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+
+			errs := make(chan error)
+			go func() { errs <- step.Perform(ctx) }()
+
+			Eventually(stream.reading).Should(Receive())
+
+			cancel()
+
+			Eventually(errs).Should(Receive(HaveOccurred()))
+			Ω(stream.closed).Should(BeClosed())
+		})
+	})
+
 	Context("when the file does not exist", func() {
 		disaster := errors.New("kaboom")
 
@@ -154,10 +288,42 @@ var _ = Describe("FetchResultStep", func() {
 		})
 
 		It("should return an error and an empty result", func() {
-			err := step.Perform()
+			err := step.Perform(context.Background())
 			Ω(err).Should(MatchError(emittable_error.New(disaster, "Copying out of the container failed")))
 
-			Ω(result).Should(BeZero())
+			Ω(results.Result()).Should(BeZero())
 		})
 	})
 })
+
+type blockingReader struct {
+	reading chan struct{}
+	closed  chan struct{}
+}
+
+func newBlockingReader() *blockingReader {
+	return &blockingReader{
+		reading: make(chan struct{}, 1),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (r *blockingReader) Read([]byte) (int, error) {
+	select {
+	case r.reading <- struct{}{}:
+	default:
+	}
+
+	<-r.closed
+	return 0, errors.New("read on closed stream")
+}
+
+func (r *blockingReader) Close() error {
+	select {
+	case <-r.closed:
+	default:
+		close(r.closed)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package fetch_result_step
+
+import "sync"
+
+// Results collects what the fetch_result steps of one run read out of the
+// container. Unnamed results share the single legacy Result; named ones are
+// kept apart, so a run can return several files.
+type Results struct {
+	lock   sync.Mutex
+	result string
+	named  map[string]string
+}
+
+func NewResults() *Results {
+	return &Results{}
+}
+
+func (results *Results) Set(name string, value string) {
+	results.lock.Lock()
+	defer results.lock.Unlock()
+
+	if name == "" {
+		results.result = value
+		return
+	}
+
+	if results.named == nil {
+		results.named = map[string]string{}
+	}
+
+	results.named[name] = value
+}
+
+func (results *Results) Result() string {
+	results.lock.Lock()
+	defer results.lock.Unlock()
+
+	return results.result
+}
+
+func (results *Results) Named() map[string]string {
+	results.lock.Lock()
+	defer results.lock.Unlock()
+
+	if results.named == nil {
+		return nil
+	}
+
+	named := make(map[string]string, len(results.named))
+	for name, value := range results.named {
+		named[name] = value
+	}
+
+	return named
+}
@@ -0,0 +1,142 @@
+package http_check_step
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/executor/steps/container_address"
+	"github.com/cloudfoundry-incubator/executor/steps/emittable_error"
+	"github.com/cloudfoundry-incubator/garden/warden"
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/pivotal-golang/lager"
+)
+
+const DefaultTimeout = time.Second
+
+var ErrInvalidPath = errors.New("path must be an absolute path, optionally with a query")
+
+// HTTPCheckStep succeeds if a GET against a port on the container's IP
+// answers with one of the expected status codes, or any 2xx if none are
+// given. Redirects are not followed, as they could point the executor
+// anywhere. The request is made from the executor, so no process is spawned
+// in the container.
+type HTTPCheckStep struct {
+	model      models.HTTPCheckAction
+	ip         *container_address.IP
+	logger     lager.Logger
+	httpClient *http.Client
+}
+
+func New(container warden.Container, model models.HTTPCheckAction, logger lager.Logger) *HTTPCheckStep {
+	timeout := model.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &HTTPCheckStep{
+		model:  model,
+		ip:     container_address.NewIP(container),
+		logger: logger,
+		httpClient: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DisableKeepAlives: true,
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func Validate(model models.HTTPCheckAction) error {
+	err := container_address.ValidatePort(model.Port)
+	if err != nil {
+		return err
+	}
+
+	_, err = parsePath(model.Path)
+	return err
+}
+
+func (step *HTTPCheckStep) Perform(ctx context.Context) error {
+	ip, err := step.ip.Get()
+	if err != nil {
+		step.logger.Error("failed-to-get-info", err)
+		return err
+	}
+
+	checkURL, err := parsePath(step.model.Path)
+	if err != nil {
+		return err
+	}
+
+	checkURL.Scheme = "http"
+	checkURL.Host = net.JoinHostPort(ip, fmt.Sprintf("%d", step.model.Port))
+
+	request, err := http.NewRequestWithContext(ctx, "GET", checkURL.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := step.httpClient.Do(request)
+	if err != nil {
+		step.logger.Info("request-failed", lager.Data{
+			"url":   checkURL.String(),
+			"error": err.Error(),
+		})
+
+		return emittable_error.New(err, "Failed to make HTTP request to port %d", step.model.Port)
+	}
+	resp.Body.Close()
+
+	if !step.expected(resp.StatusCode) {
+		step.logger.Info("unexpected-status-code", lager.Data{
+			"url":         checkURL.String(),
+			"status-code": resp.StatusCode,
+		})
+
+		return emittable_error.New(nil, "HTTP check on port %d returned status code %d", step.model.Port, resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (step *HTTPCheckStep) expected(statusCode int) bool {
+	if len(step.model.ExpectedStatusCodes) == 0 {
+		return statusCode >= 200 && statusCode < 300
+	}
+
+	for _, expected := range step.model.ExpectedStatusCodes {
+		if statusCode == expected {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parsePath splits path into its path and query, leaving the scheme and
+// host to be filled in. It refuses anything that would name another host.
+func parsePath(path string) (*url.URL, error) {
+	if path == "" {
+		return &url.URL{Path: "/"}, nil
+	}
+
+	parsed, err := url.Parse(path)
+	if err != nil || parsed.Scheme != "" || parsed.Host != "" || parsed.Opaque != "" || !strings.HasPrefix(parsed.Path, "/") {
+		return nil, ErrInvalidPath
+	}
+
+	return parsed, nil
+}
+
+func (step *HTTPCheckStep) Cleanup() error {
+	return nil
+}
@@ -0,0 +1,13 @@
+package http_check_step_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestHTTPCheckStep(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Http_check_step Suite")
+}
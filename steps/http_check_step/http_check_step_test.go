@@ -0,0 +1,236 @@
+package http_check_step_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cloudfoundry-incubator/executor/sequence"
+	"github.com/cloudfoundry-incubator/executor/steps/container_address"
+	"github.com/cloudfoundry-incubator/executor/steps/emittable_error"
+	. "github.com/cloudfoundry-incubator/executor/steps/http_check_step"
+	"github.com/cloudfoundry-incubator/garden/warden"
+	"github.com/cloudfoundry-incubator/garden/warden/fakes"
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("HTTPCheckStep", func() {
+	var (
+		container *fakes.FakeContainer
+		server    *ghttp.Server
+		model     models.HTTPCheckAction
+
+		step sequence.Step
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+
+		host, portString, err := net.SplitHostPort(server.HTTPTestServer.Listener.Addr().String())
+		Ω(err).ShouldNot(HaveOccurred())
+
+		port, err := strconv.Atoi(portString)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		model = models.HTTPCheckAction{
+			Port: uint32(port),
+			Path: "/health",
+		}
+
+		container = new(fakes.FakeContainer)
+		container.InfoReturns(warden.ContainerInfo{ContainerIP: host}, nil)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		step = New(container, model, lagertest.NewTestLogger("test"))
+	})
+
+	Context("when no status codes are expected", func() {
+		It("succeeds on a 2xx", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/health"),
+				ghttp.RespondWith(http.StatusNoContent, nil),
+			))
+
+			Ω(step.Perform(context.Background())).ShouldNot(HaveOccurred())
+		})
+
+		It("fails on anything else", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusServiceUnavailable, nil))
+
+			err := step.Perform(context.Background())
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*emittable_error.EmittableError).EmittableError()).Should(ContainSubstring("returned status code 503"))
+		})
+	})
+
+	Context("when redirected", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusFound, nil, http.Header{"Location": []string{"/elsewhere"}}),
+				ghttp.RespondWith(http.StatusOK, nil),
+			)
+		})
+
+		It("does not follow the redirect", func() {
+			err := step.Perform(context.Background())
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*emittable_error.EmittableError).EmittableError()).Should(ContainSubstring("returned status code 302"))
+
+			Ω(server.ReceivedRequests()).Should(HaveLen(1))
+		})
+	})
+
+	Context("when status codes are expected", func() {
+		BeforeEach(func() {
+			model.ExpectedStatusCodes = []int{http.StatusOK, http.StatusUnauthorized}
+		})
+
+		It("succeeds on an expected status code", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusUnauthorized, nil))
+
+			Ω(step.Perform(context.Background())).ShouldNot(HaveOccurred())
+		})
+
+		It("fails on an unexpected one, even a 2xx", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusNoContent, nil))
+
+			Ω(step.Perform(context.Background())).Should(HaveOccurred())
+		})
+	})
+
+	Context("when the context is cancelled while waiting for a response", func() {
+		var requested chan struct{}
+
+		BeforeEach(func() {
+			model.Timeout = time.Minute
+			requested = make(chan struct{}, 1)
+
+			server.AppendHandlers(func(w http.ResponseWriter, r *http.Request) {
+				requested <- struct{}{}
+				<-r.Context().Done()
+			})
+		})
+
+		It("gives up on the request", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+
+			errs := make(chan error)
+			go func() { errs <- step.Perform(ctx) }()
+
+			Eventually(requested).Should(Receive())
+
+			cancel()
+
+			Eventually(errs).Should(Receive(HaveOccurred()))
+		})
+	})
+
+	Context("when no path is given", func() {
+		BeforeEach(func() {
+			model.Path = ""
+		})
+
+		It("requests /", func() {
+			server.AppendHandlers(ghttp.VerifyRequest("GET", "/"))
+
+			Ω(step.Perform(context.Background())).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Context("when the path has a query", func() {
+		BeforeEach(func() {
+			model.Path = "/health?verbose=true"
+		})
+
+		It("sends the query as a query", func() {
+			server.AppendHandlers(ghttp.VerifyRequest("GET", "/health", "verbose=true"))
+
+			Ω(step.Perform(context.Background())).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Context("when nothing is listening on the port", func() {
+		BeforeEach(func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			_, portString, err := net.SplitHostPort(listener.Addr().String())
+			Ω(err).ShouldNot(HaveOccurred())
+
+			port, err := strconv.Atoi(portString)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			listener.Close()
+
+			model.Port = uint32(port)
+			container.InfoReturns(warden.ContainerInfo{ContainerIP: "127.0.0.1"}, nil)
+		})
+
+		It("fails with an emittable error", func() {
+			err := step.Perform(context.Background())
+			Ω(err).Should(BeAssignableToTypeOf(&emittable_error.EmittableError{}))
+		})
+	})
+
+	Context("when the container has no IP yet", func() {
+		BeforeEach(func() {
+			container.InfoReturns(warden.ContainerInfo{}, nil)
+		})
+
+		It("fails, and looks it up again the next time", func() {
+			Ω(step.Perform(context.Background())).Should(Equal(container_address.ErrNoContainerIP))
+			Ω(step.Perform(context.Background())).Should(Equal(container_address.ErrNoContainerIP))
+
+			Ω(container.InfoCallCount()).Should(Equal(2))
+		})
+	})
+
+	Context("when getting the container's info fails", func() {
+		disaster := errors.New("oh no")
+
+		BeforeEach(func() {
+			container.InfoReturns(warden.ContainerInfo{}, disaster)
+		})
+
+		It("returns the error", func() {
+			Ω(step.Perform(context.Background())).Should(Equal(disaster))
+		})
+	})
+})
+
+var _ = Describe("Validate", func() {
+	It("accepts a port with an absolute path", func() {
+		Ω(Validate(models.HTTPCheckAction{Port: 8080, Path: "/health?verbose=true"})).ShouldNot(HaveOccurred())
+	})
+
+	It("accepts a port without a path", func() {
+		Ω(Validate(models.HTTPCheckAction{Port: 8080})).ShouldNot(HaveOccurred())
+	})
+
+	It("rejects a port out of range", func() {
+		Ω(Validate(models.HTTPCheckAction{Port: 0})).Should(Equal(container_address.ErrInvalidPort))
+		Ω(Validate(models.HTTPCheckAction{Port: 65536})).Should(Equal(container_address.ErrInvalidPort))
+	})
+
+	It("rejects a path that is not absolute", func() {
+		Ω(Validate(models.HTTPCheckAction{Port: 8080, Path: "health"})).Should(Equal(ErrInvalidPath))
+	})
+
+	It("rejects a path that names another host", func() {
+		Ω(Validate(models.HTTPCheckAction{Port: 8080, Path: "http://example.com/health"})).Should(Equal(ErrInvalidPath))
+		Ω(Validate(models.HTTPCheckAction{Port: 8080, Path: "//example.com/health"})).Should(Equal(ErrInvalidPath))
+	})
+})
@@ -0,0 +1,176 @@
+package monitor_step
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/pivotal-golang/lager"
+)
+
+const DefaultHookTimeout = 10 * time.Second
+
+// A failed hook is retried after its RetryInterval, or HookRetryInterval if
+// it has none, doubling after every attempt up to MaxHookRetryInterval.
+const (
+	HookRetryInterval    = time.Second
+	MaxHookRetryInterval = 30 * time.Second
+)
+
+// Hook is a request fired when a container becomes healthy or unhealthy.
+type Hook struct {
+	Method  string
+	URL     *url.URL
+	Headers http.Header
+	Body    *template.Template
+	Timeout time.Duration
+	Retries uint
+
+	RetryInterval time.Duration
+}
+
+// HookPayload is what a hook's body template is rendered with.
+type HookPayload struct {
+	Guid           string
+	Status         string
+	HealthyCount   uint
+	UnhealthyCount uint
+}
+
+// ParseBody parses a hook body template. Besides the usual template
+// functions it has json, so values can be spliced safely into a JSON body:
+//
+//	{"guid": {{json .Guid}}, "healthy": {{.HealthyCount}}}
+func ParseBody(body string) (*template.Template, error) {
+	return template.New("body").Funcs(template.FuncMap{
+		"json": toJSON,
+	}).Parse(body)
+}
+
+func toJSON(value interface{}) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(encoded), nil
+}
+
+// HookStatusError is a hook answered with a status code other than 2xx.
+type HookStatusError struct {
+	StatusCode int
+}
+
+func (err *HookStatusError) Error() string {
+	return fmt.Sprintf("hook failed: status code %d", err.StatusCode)
+}
+
+// fire sends the hook, backing off and trying again on network errors and
+// 5xx responses until its retries run out or ctx is done. Any other status
+// code but a 2xx fails it straight away.
+func (hook *Hook) fire(ctx context.Context, payload HookPayload, timer Timer, logger lager.Logger) error {
+	var body []byte
+
+	if hook.Body != nil {
+		rendered := new(bytes.Buffer)
+
+		err := hook.Body.Execute(rendered, payload)
+		if err != nil {
+			return err
+		}
+
+		body = rendered.Bytes()
+	}
+
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+
+	client := &http.Client{Timeout: timeout}
+
+	var err error
+
+	interval := hook.RetryInterval
+	if interval <= 0 {
+		interval = HookRetryInterval
+	}
+
+	for attempt := uint(0); ; attempt++ {
+		err = hook.send(ctx, client, body)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+
+		logger.Info("hook-attempt-failed", lager.Data{
+			"url":     hook.URL.String(),
+			"attempt": attempt + 1,
+			"error":   err.Error(),
+		})
+
+		if attempt >= hook.Retries || !retryable(err) {
+			return err
+		}
+
+		select {
+		case <-timer.After(interval):
+		case <-ctx.Done():
+			return err
+		}
+
+		interval *= 2
+		if interval > MaxHookRetryInterval {
+			interval = MaxHookRetryInterval
+		}
+	}
+}
+
+func retryable(err error) bool {
+	statusErr, ok := err.(*HookStatusError)
+	if !ok {
+		return true
+	}
+
+	return statusErr.StatusCode >= 500
+}
+
+func (hook *Hook) send(ctx context.Context, client *http.Client, body []byte) error {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, hook.Method, hook.URL.String(), bodyReader)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	for name, values := range hook.Headers {
+		request.Header.Del(name)
+		for _, value := range values {
+			request.Header.Add(name, value)
+		}
+	}
+
+	resp, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &HookStatusError{StatusCode: resp.StatusCode}
+	}
+
+	return nil
+}
@@ -1,9 +1,12 @@
 package monitor_step
 
 import (
-	"net/http"
+	"context"
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/cloudfoundry-incubator/executor/log_streamer"
 	"github.com/cloudfoundry-incubator/executor/sequence"
 	"github.com/pivotal-golang/lager"
 )
@@ -11,25 +14,59 @@ import (
 const BaseInterval = 500 * time.Millisecond
 const MaxInterval = 30 * time.Second
 
+// HookQueueSize is how many hooks may wait behind the one being sent. Hooks
+// are sent apart from the checks, so a slow endpoint never holds them up;
+// once the queue is full the oldest waiting hook is dropped.
+const HookQueueSize = 8
+
+var ErrCheckTimedOut = errors.New("health check timed out")
+
+// Status is what the monitor knows after each check, and again after each
+// hook is sent. CheckError is that of the latest check, and HookError that
+// of the hook just sent, if they failed.
+type Status struct {
+	Healthy        bool
+	HealthyCount   uint
+	UnhealthyCount uint
+	CheckError     error
+	HookError      error
+}
+
 type monitorStep struct {
 	check sequence.Step
+	guid  string
 
 	healthyThreshold   uint
 	unhealthyThreshold uint
 
-	healthyHook   *http.Request
-	unhealthyHook *http.Request
+	initialInterval time.Duration
+	maxInterval     time.Duration
+	checkTimeout    time.Duration
+
+	healthyHook   *Hook
+	unhealthyHook *Hook
+
+	record   func(Status)
+	streamer log_streamer.LogStreamer
 
 	logger lager.Logger
 	timer  Timer
-
-	cancel chan struct{}
 }
 
+// New monitors check, starting at initialInterval and backing off while it
+// stays healthy up to maxInterval; zero intervals fall back to BaseInterval
+// and MaxInterval. A check running longer than a non-zero checkTimeout is
+// cancelled and counts as unhealthy. Every check's outcome is passed to
+// record, and each change between healthy and unhealthy is written to
+// streamer.
 func New(
 	check sequence.Step,
+	guid string,
 	healthyThreshold, unhealthyThreshold uint,
-	healthyHook, unhealthyHook *http.Request,
+	initialInterval, maxInterval, checkTimeout time.Duration,
+	healthyHook, unhealthyHook *Hook,
+	record func(Status),
+	streamer log_streamer.LogStreamer,
 	logger lager.Logger,
 	timer Timer,
 ) sequence.Step {
@@ -41,38 +78,92 @@ func New(
 		unhealthyThreshold = 1
 	}
 
+	if initialInterval <= 0 {
+		initialInterval = BaseInterval
+	}
+
+	if maxInterval <= 0 {
+		maxInterval = MaxInterval
+	}
+
+	if maxInterval < initialInterval {
+		maxInterval = initialInterval
+	}
+
 	return &monitorStep{
 		check:              check,
+		guid:               guid,
 		healthyThreshold:   healthyThreshold,
 		unhealthyThreshold: unhealthyThreshold,
+		initialInterval:    initialInterval,
+		maxInterval:        maxInterval,
+		checkTimeout:       checkTimeout,
 		healthyHook:        healthyHook,
 		unhealthyHook:      unhealthyHook,
+		record:             record,
+		streamer:           streamer,
 		logger:             logger,
 		timer:              timer,
-
-		cancel: make(chan struct{}),
 	}
 }
 
-func (step *monitorStep) Perform() error {
-	timer := step.timer.After(BaseInterval)
+type hookFiring struct {
+	hook    *Hook
+	payload HookPayload
+}
+
+// Perform keeps checking until ctx is done, and then succeeds.
+func (step *monitorStep) Perform(ctx context.Context) error {
+	hooks := make(chan hookFiring, HookQueueSize)
+	hookResults := make(chan error)
+	hooksDone := make(chan struct{})
+
+	go func() {
+		step.sendHooks(ctx, hooks, hookResults)
+		close(hooksDone)
+	}()
+
+	defer func() {
+		close(hooks)
+		<-hooksDone
+	}()
+
+	timer := step.timer.After(step.initialInterval)
 
 	var healthyCount uint
 	var unhealthyCount uint
+	var latest Status
+
+	checked := false
+	wasHealthy := false
 
 	for {
 		select {
 		case <-timer:
-			healthy := step.check.Perform() == nil
+			checkErr := step.performCheck(ctx)
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			healthy := checkErr == nil
+
+			if !checked || healthy != wasHealthy {
+				step.transitioned(healthy)
+			}
+
+			checked = true
+			wasHealthy = healthy
 
-			var request *http.Request
+			var hook *Hook
+			var status string
 
 			if healthy {
 				healthyCount++
 				unhealthyCount = 0
+				status = "healthy"
 
 				if step.healthyHook != nil && (healthyCount%step.healthyThreshold) == 0 {
-					request = step.healthyHook
+					hook = step.healthyHook
 				}
 
 				step.logger.Debug("healthy", lager.Data{
@@ -81,9 +172,10 @@ func (step *monitorStep) Perform() error {
 			} else {
 				unhealthyCount++
 				healthyCount = 0
+				status = "unhealthy"
 
 				if step.unhealthyHook != nil && (unhealthyCount%step.unhealthyThreshold) == 0 {
-					request = step.unhealthyHook
+					hook = step.unhealthyHook
 				}
 
 				step.logger.Info("unhealthy", lager.Data{
@@ -91,46 +183,138 @@ func (step *monitorStep) Perform() error {
 				})
 			}
 
-			if request != nil {
-				resp, err := http.DefaultClient.Do(request)
-				if err != nil {
-					step.logger.Error("callback-failed", err)
-				} else {
-					resp.Body.Close()
-				}
+			if hook != nil {
+				step.queueHook(hooks, hookFiring{
+					hook: hook,
+					payload: HookPayload{
+						Guid:           step.guid,
+						Status:         status,
+						HealthyCount:   healthyCount,
+						UnhealthyCount: unhealthyCount,
+					},
+				})
+			}
+
+			latest = Status{
+				Healthy:        healthy,
+				HealthyCount:   healthyCount,
+				UnhealthyCount: unhealthyCount,
+				CheckError:     checkErr,
 			}
 
-			backoff := backoffForHealthyCount(healthyCount)
+			if step.record != nil {
+				step.record(latest)
+			}
+
+			backoff := step.backoffForHealthyCount(healthyCount)
 			step.logger.Debug("sleeping", lager.Data{
 				"duration": backoff,
 			})
 			timer = step.timer.After(backoff)
-		case <-step.cancel:
+		case hookErr := <-hookResults:
+			if hookErr != nil {
+				step.logger.Error("callback-failed", hookErr)
+			}
+
+			if step.record != nil {
+				status := latest
+				status.HookError = hookErr
+				step.record(status)
+			}
+		case <-ctx.Done():
 			return nil
 		}
 	}
+}
 
-	return nil
+// queueHook never blocks the checks: if the queue is full, the oldest hook
+// waiting in it makes way.
+func (step *monitorStep) queueHook(hooks chan hookFiring, firing hookFiring) {
+	select {
+	case hooks <- firing:
+		return
+	default:
+	}
+
+	select {
+	case dropped := <-hooks:
+		step.logger.Info("hook-dropped", lager.Data{
+			"url":    dropped.hook.URL.String(),
+			"status": dropped.payload.Status,
+		})
+	default:
+	}
+
+	hooks <- firing
 }
 
-func backoffForHealthyCount(healthyCount uint) time.Duration {
-	if healthyCount == 0 {
-		return BaseInterval
+// sendHooks sends each queued hook in turn, handing back how it went, until
+// hooks is closed.
+func (step *monitorStep) sendHooks(ctx context.Context, hooks <-chan hookFiring, results chan<- error) {
+	for firing := range hooks {
+		err := firing.hook.fire(ctx, firing.payload, step.timer, step.logger)
+
+		select {
+		case results <- err:
+		case <-ctx.Done():
+		}
+	}
+}
+
+func (step *monitorStep) transitioned(healthy bool) {
+	status := "unhealthy"
+	if healthy {
+		status = "healthy"
 	}
 
-	backoff := BaseInterval * time.Duration(uint(1)<<(healthyCount-1))
+	step.logger.Info("health-transition", lager.Data{
+		"status": status,
+	})
 
-	// Guard against integer overflow caused by bitshifting
-	if backoff > MaxInterval || healthyCount > 32 {
-		return MaxInterval
-	} else {
-		return backoff
+	if step.streamer != nil {
+		fmt.Fprintf(step.streamer.Stdout(), "Container became %s\n", status)
 	}
 }
 
-func (step *monitorStep) Cancel() {
-	step.cancel <- struct{}{}
+func (step *monitorStep) performCheck(ctx context.Context) error {
+	if step.checkTimeout <= 0 {
+		return step.check.Perform(ctx)
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, step.checkTimeout)
+	defer cancel()
+
+	err := step.check.Perform(checkCtx)
+
+	if checkCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		step.logger.Info("check-timed-out", lager.Data{
+			"timeout": step.checkTimeout.String(),
+		})
+
+		return ErrCheckTimedOut
+	}
+
+	return err
+}
+
+func (step *monitorStep) backoffForHealthyCount(healthyCount uint) time.Duration {
+	if healthyCount == 0 {
+		return step.initialInterval
+	}
+
+	// Guard against integer overflow caused by bitshifting
+	if healthyCount > 32 {
+		return step.maxInterval
+	}
+
+	backoff := step.initialInterval * time.Duration(uint(1)<<(healthyCount-1))
+	if backoff > step.maxInterval || backoff <= 0 {
+		return step.maxInterval
+	}
+
+	return backoff
 }
 
-func (step *monitorStep) Cleanup() {
+func (step *monitorStep) Cleanup() error {
+	return nil
 }
@@ -1,11 +1,13 @@
 package monitor_step_test
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/url"
 	"time"
 
+	"github.com/cloudfoundry-incubator/executor/log_streamer/fake_log_streamer"
 	"github.com/cloudfoundry-incubator/executor/sequence"
 	"github.com/cloudfoundry-incubator/executor/sequence/fake_step"
 	. "github.com/cloudfoundry-incubator/executor/steps/monitor_step"
@@ -14,6 +16,7 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 	"github.com/onsi/gomega/ghttp"
 )
 
@@ -29,9 +32,28 @@ var _ = Describe("MonitorStep", func() {
 		hookServer *ghttp.Server
 		logger     *lagertest.TestLogger
 		timer      *fakes.FakeTimer
+
+		statuses     chan Status
+		record       func(Status)
+		streamer     *fake_log_streamer.FakeLogStreamer
+		streamOutput *gbytes.Buffer
+
+		ctx    context.Context
+		cancel context.CancelFunc
 	)
 
 	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+
+		statuses = make(chan Status, 100)
+		record = func(status Status) {
+			statuses <- status
+		}
+
+		streamOutput = gbytes.NewBuffer()
+		streamer = new(fake_log_streamer.FakeLogStreamer)
+		streamer.StdoutReturns(streamOutput)
+
 		timer = fakes.NewFakeTimer(time.Now())
 		check = new(fake_step.FakeStep)
 
@@ -68,24 +90,30 @@ var _ = Describe("MonitorStep", func() {
 			BeforeEach(func() {
 				step = New(
 					check,
+					"some-guid",
 					2,
 					2,
-					&http.Request{
+					0,
+					0,
+					0,
+					&Hook{
 						Method: "PUT",
 						URL:    healthyHookURL,
 					},
-					&http.Request{
+					&Hook{
 						Method: "PUT",
 						URL:    unhealthyHookURL,
 					},
+					record,
+					streamer,
 					logger,
 					timer,
 				)
-				go step.Perform()
+				go step.Perform(ctx)
 			})
 
 			AfterEach(func() {
-				step.Cancel()
+				cancel()
 			})
 
 			Context("when the check succeeds", func() {
@@ -248,25 +276,31 @@ var _ = Describe("MonitorStep", func() {
 			BeforeEach(func() {
 				step = New(
 					check,
+					"some-guid",
+					0,
 					0,
 					0,
-					&http.Request{
+					0,
+					0,
+					&Hook{
 						Method: "PUT",
 						URL:    healthyHookURL,
 					},
-					&http.Request{
+					&Hook{
 						Method: "PUT",
 						URL:    unhealthyHookURL,
 					},
+					record,
+					streamer,
 					logger,
 					timer,
 				)
 
-				go step.Perform()
+				go step.Perform(ctx)
 			})
 
 			AfterEach(func() {
-				step.Cancel()
+				cancel()
 			})
 
 			Context("when the check succeeds", func() {
@@ -293,22 +327,477 @@ var _ = Describe("MonitorStep", func() {
 				})
 			})
 		})
+
+		Context("when recording health", func() {
+			BeforeEach(func() {
+				hookServer.AllowUnhandledRequests = true
+				hookServer.UnhandledRequestStatusCode = http.StatusInternalServerError
+
+				step = New(
+					check,
+					"some-guid",
+					1,
+					1,
+					0,
+					0,
+					0,
+					nil,
+					&Hook{
+						Method: "PUT",
+						URL:    unhealthyHookURL,
+					},
+					record,
+					streamer,
+					logger,
+					timer,
+				)
+
+				go step.Perform(ctx)
+			})
+
+			AfterEach(func() {
+				cancel()
+			})
+
+			It("records the outcome of every check", func() {
+				check.PerformReturns(nil)
+				expectCheckAfterInterval(BaseInterval)
+				Eventually(statuses).Should(Receive(Equal(Status{
+					Healthy:      true,
+					HealthyCount: 1,
+				})))
+
+				expectCheckAfterInterval(BaseInterval)
+				Eventually(statuses).Should(Receive(Equal(Status{
+					Healthy:      true,
+					HealthyCount: 2,
+				})))
+
+				disaster := errors.New("nope")
+				check.PerformReturns(disaster)
+				expectCheckAfterInterval(2 * BaseInterval)
+
+				var status Status
+				Eventually(statuses).Should(Receive(&status))
+				Ω(status.Healthy).Should(BeFalse())
+				Ω(status.UnhealthyCount).Should(Equal(uint(1)))
+				Ω(status.CheckError).Should(Equal(disaster))
+				Ω(status.HookError).ShouldNot(HaveOccurred())
+			})
+
+			It("records the outcome of every hook once it has been sent", func() {
+				disaster := errors.New("nope")
+				check.PerformReturns(disaster)
+				expectCheckAfterInterval(BaseInterval)
+
+				var status Status
+				Eventually(statuses).Should(Receive(&status))
+				Ω(status.HookError).ShouldNot(HaveOccurred())
+
+				Eventually(statuses).Should(Receive(&status))
+				Ω(status.Healthy).Should(BeFalse())
+				Ω(status.UnhealthyCount).Should(Equal(uint(1)))
+				Ω(status.CheckError).Should(Equal(disaster))
+				Ω(status.HookError).Should(Equal(&HookStatusError{StatusCode: http.StatusInternalServerError}))
+			})
+
+			It("emits each transition to the log stream", func() {
+				check.PerformReturns(nil)
+				expectCheckAfterInterval(BaseInterval)
+				Eventually(streamOutput).Should(gbytes.Say("Container became healthy\n"))
+
+				expectCheckAfterInterval(BaseInterval)
+
+				check.PerformReturns(errors.New("nope"))
+				expectCheckAfterInterval(2 * BaseInterval)
+				Eventually(streamOutput).Should(gbytes.Say("Container became unhealthy\n"))
+
+				Ω(streamOutput.Contents()).Should(Equal([]byte("Container became healthy\nContainer became unhealthy\n")))
+			})
+		})
+
+		Context("when intervals are specified", func() {
+			BeforeEach(func() {
+				step = New(
+					check,
+					"some-guid",
+					1,
+					1,
+					2*time.Second,
+					5*time.Second,
+					0,
+					nil,
+					nil,
+					record,
+					streamer,
+					logger,
+					timer,
+				)
+
+				go step.Perform(ctx)
+			})
+
+			AfterEach(func() {
+				cancel()
+			})
+
+			It("backs off from the initial interval up to the maximum interval", func() {
+				check.PerformReturns(nil)
+
+				expectCheckAfterInterval(2 * time.Second)
+				expectCheckAfterInterval(2 * time.Second)
+				expectCheckAfterInterval(4 * time.Second)
+				expectCheckAfterInterval(5 * time.Second)
+				expectCheckAfterInterval(5 * time.Second)
+			})
+
+			It("checks again after the initial interval once the check fails", func() {
+				check.PerformReturns(nil)
+				expectCheckAfterInterval(2 * time.Second)
+				expectCheckAfterInterval(2 * time.Second)
+
+				check.PerformReturns(errors.New("nope"))
+				expectCheckAfterInterval(4 * time.Second)
+				expectCheckAfterInterval(2 * time.Second)
+			})
+		})
+
+		Context("when the check takes longer than the check timeout", func() {
+			BeforeEach(func() {
+				check.PerformStub = func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+
+				hookServer.AppendHandlers(ghttp.VerifyRequest("PUT", "/unhealthy"))
+
+				step = New(
+					check,
+					"some-guid",
+					1,
+					1,
+					0,
+					0,
+					50*time.Millisecond,
+					nil,
+					&Hook{
+						Method: "PUT",
+						URL:    unhealthyHookURL,
+					},
+					record,
+					streamer,
+					logger,
+					timer,
+				)
+
+				go step.Perform(ctx)
+			})
+
+			AfterEach(func() {
+				cancel()
+			})
+
+			It("cancels the check and treats the container as unhealthy", func() {
+				expectCheckAfterInterval(BaseInterval)
+
+				var status Status
+				Eventually(statuses).Should(Receive(&status))
+				Ω(status.Healthy).Should(BeFalse())
+				Ω(status.CheckError).Should(Equal(ErrCheckTimedOut))
+
+				Eventually(hookServer.ReceivedRequests, 10).Should(HaveLen(1))
+			})
+		})
+
+		Context("when the hook has headers and a body", func() {
+			BeforeEach(func() {
+				body, err := ParseBody(`{"guid":{{json .Guid}},"status":{{json .Status}},"healthy":{{.HealthyCount}},"unhealthy":{{.UnhealthyCount}}}`)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				step = New(
+					check,
+					"some-guid",
+					1,
+					1,
+					0,
+					0,
+					0,
+					&Hook{
+						Method:  "POST",
+						URL:     healthyHookURL,
+						Headers: http.Header{"X-Token": []string{"secret"}},
+						Body:    body,
+					},
+					nil,
+					record,
+					streamer,
+					logger,
+					timer,
+				)
+
+				go step.Perform(ctx)
+			})
+
+			AfterEach(func() {
+				cancel()
+			})
+
+			It("sends the headers and the rendered body", func() {
+				hookServer.AppendHandlers(ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/healthy"),
+					ghttp.VerifyHeader(http.Header{
+						"X-Token":      []string{"secret"},
+						"Content-Type": []string{"application/json"},
+					}),
+					ghttp.VerifyJSON(`{"guid":"some-guid","status":"healthy","healthy":1,"unhealthy":0}`),
+				))
+
+				check.PerformReturns(nil)
+				expectCheckAfterInterval(BaseInterval)
+
+				Eventually(hookServer.ReceivedRequests, 10).Should(HaveLen(1))
+			})
+		})
+
+		Context("when the hook fails", func() {
+			var retries uint
+			var retryInterval time.Duration
+			var hookStatuses chan Status
+
+			BeforeEach(func() {
+				retryInterval = 0
+
+				// steps left running by other specs still record to statuses
+				hookStatuses = make(chan Status, 100)
+				record = func(status Status) {
+					hookStatuses <- status
+				}
+			})
+
+			JustBeforeEach(func() {
+				step = New(
+					check,
+					"some-guid",
+					1,
+					1,
+					time.Hour,
+					time.Hour,
+					0,
+					&Hook{
+						Method:  "PUT",
+						URL:     healthyHookURL,
+						Timeout: 100 * time.Millisecond,
+						Retries: retries,
+
+						RetryInterval: retryInterval,
+					},
+					nil,
+					record,
+					streamer,
+					logger,
+					timer,
+				)
+
+				go step.Perform(ctx)
+			})
+
+			AfterEach(func() {
+				cancel()
+			})
+
+			Context("with retries", func() {
+				BeforeEach(func() {
+					retries = 2
+
+					hookServer.AppendHandlers(
+						ghttp.RespondWith(http.StatusServiceUnavailable, nil),
+						ghttp.RespondWith(http.StatusInternalServerError, nil),
+						ghttp.RespondWith(http.StatusOK, nil),
+					)
+				})
+
+				It("backs off and tries again until it succeeds", func() {
+					check.PerformReturns(nil)
+					expectCheckAfterInterval(time.Hour)
+
+					Eventually(hookServer.ReceivedRequests, 10).Should(HaveLen(1))
+
+					// the next check's timer and the hook's backoff
+					Eventually(timer.ActiveAfterCount).Should(Equal(2))
+					timer.Elapse(HookRetryInterval - time.Microsecond)
+					Consistently(hookServer.ReceivedRequests).Should(HaveLen(1))
+					timer.Elapse(time.Microsecond)
+					Eventually(hookServer.ReceivedRequests).Should(HaveLen(2))
+
+					Eventually(timer.ActiveAfterCount).Should(Equal(2))
+					timer.Elapse(2*HookRetryInterval - time.Microsecond)
+					Consistently(hookServer.ReceivedRequests).Should(HaveLen(2))
+					timer.Elapse(time.Microsecond)
+					Eventually(hookServer.ReceivedRequests).Should(HaveLen(3))
+
+					var status Status
+					Eventually(hookStatuses).Should(Receive(&status))
+					Ω(status.HookError).ShouldNot(HaveOccurred())
+
+					Eventually(hookStatuses).Should(Receive(&status))
+					Ω(status.HookError).ShouldNot(HaveOccurred())
+					Ω(hookStatuses).ShouldNot(Receive())
+				})
+			})
+
+			Context("with a retry interval of its own", func() {
+				BeforeEach(func() {
+					retries = 1
+					retryInterval = 5 * time.Second
+
+					hookServer.AppendHandlers(
+						ghttp.RespondWith(http.StatusServiceUnavailable, nil),
+						ghttp.RespondWith(http.StatusOK, nil),
+					)
+				})
+
+				It("backs off from that instead", func() {
+					check.PerformReturns(nil)
+					expectCheckAfterInterval(time.Hour)
+
+					Eventually(hookServer.ReceivedRequests, 10).Should(HaveLen(1))
+
+					Eventually(timer.ActiveAfterCount).Should(Equal(2))
+					timer.Elapse(retryInterval - time.Microsecond)
+					Consistently(hookServer.ReceivedRequests).Should(HaveLen(1))
+					timer.Elapse(time.Microsecond)
+					Eventually(hookServer.ReceivedRequests).Should(HaveLen(2))
+				})
+			})
+
+			Context("when the hook is answered with a status code that is not 2xx or 5xx", func() {
+				BeforeEach(func() {
+					retries = 2
+
+					hookServer.AppendHandlers(
+						ghttp.RespondWith(http.StatusNotFound, nil),
+					)
+				})
+
+				It("fails without trying again", func() {
+					check.PerformReturns(nil)
+					expectCheckAfterInterval(time.Hour)
+
+					var status Status
+					Eventually(hookStatuses).Should(Receive(&status))
+					Ω(status.HookError).ShouldNot(HaveOccurred())
+
+					Eventually(hookStatuses).Should(Receive(&status))
+					Ω(status.HookError).Should(Equal(&HookStatusError{StatusCode: http.StatusNotFound}))
+
+					Ω(hookServer.ReceivedRequests()).Should(HaveLen(1))
+				})
+			})
+
+			Context("without retries", func() {
+				BeforeEach(func() {
+					retries = 0
+
+					hookServer.AllowUnhandledRequests = true
+					hookServer.UnhandledRequestStatusCode = http.StatusInternalServerError
+				})
+
+				It("only tries once", func() {
+					check.PerformReturns(nil)
+					expectCheckAfterInterval(time.Hour)
+
+					Eventually(hookServer.ReceivedRequests, 10).Should(HaveLen(1))
+					Consistently(hookServer.ReceivedRequests).Should(HaveLen(1))
+				})
+			})
+
+			Context("when the hook takes longer than its timeout", func() {
+				var unblock chan struct{}
+
+				BeforeEach(func() {
+					retries = 0
+					unblock = make(chan struct{})
+
+					hookServer.AppendHandlers(func(w http.ResponseWriter, r *http.Request) {
+						<-unblock
+					})
+				})
+
+				AfterEach(func() {
+					close(unblock)
+				})
+
+				It("gives up and keeps monitoring", func() {
+					check.PerformReturns(nil)
+					expectCheckAfterInterval(time.Hour)
+					expectCheckAfterInterval(time.Hour)
+				})
+			})
+		})
+
+		Context("while a hook is backing off", func() {
+			BeforeEach(func() {
+				hookServer.AllowUnhandledRequests = true
+				hookServer.UnhandledRequestStatusCode = http.StatusServiceUnavailable
+
+				step = New(
+					check,
+					"some-guid",
+					1,
+					1,
+					0,
+					0,
+					0,
+					&Hook{
+						Method:  "PUT",
+						URL:     healthyHookURL,
+						Retries: 5,
+					},
+					nil,
+					record,
+					streamer,
+					logger,
+					timer,
+				)
+
+				go step.Perform(ctx)
+			})
+
+			AfterEach(func() {
+				cancel()
+			})
+
+			It("keeps checking on schedule", func() {
+				check.PerformReturns(nil)
+				expectCheckAfterInterval(BaseInterval)
+
+				Eventually(hookServer.ReceivedRequests, 10).Should(HaveLen(1))
+
+				expectCheckAfterInterval(BaseInterval)
+			})
+		})
 	})
 
-	Describe("Cancel", func() {
+	Context("when the context is cancelled", func() {
 		BeforeEach(func() {
 			step = New(
 				check,
+				"some-guid",
 				2,
 				2,
-				&http.Request{
+				0,
+				0,
+				0,
+				&Hook{
 					Method: "PUT",
 					URL:    healthyHookURL,
 				},
-				&http.Request{
+				&Hook{
 					Method: "PUT",
 					URL:    unhealthyHookURL,
 				},
+				record,
+				streamer,
 				logger,
 				timer,
 			)
@@ -317,11 +806,11 @@ var _ = Describe("MonitorStep", func() {
 		It("interrupts the monitoring", func() {
 			performResult := make(chan error)
 
-			go func() { performResult <- step.Perform() }()
+			go func() { performResult <- step.Perform(ctx) }()
 
-			step.Cancel()
+			cancel()
 
-			Eventually(performResult).Should(Receive())
+			Eventually(performResult).Should(Receive(BeNil()))
 		})
 	})
 })
@@ -1,6 +1,10 @@
 package parallel_step
 
-import "github.com/cloudfoundry-incubator/executor/sequence"
+import (
+	"context"
+
+	"github.com/cloudfoundry-incubator/executor/sequence"
+)
 
 type ParallelStep struct {
 	substeps []sequence.Step
@@ -12,12 +16,12 @@ func New(substeps []sequence.Step) *ParallelStep {
 	}
 }
 
-func (step *ParallelStep) Perform() error {
+func (step *ParallelStep) Perform(ctx context.Context) error {
 	errs := make(chan error, len(step.substeps))
 
 	for _, step := range step.substeps {
 		go func(step sequence.Step) {
-			errs <- step.Perform()
+			errs <- step.Perform(ctx)
 		}(step)
 	}
 
@@ -32,14 +36,12 @@ func (step *ParallelStep) Perform() error {
 	return err
 }
 
-func (step *ParallelStep) Cancel() {
-	for _, step := range step.substeps {
-		step.Cancel()
+// Cleanup cleans up every substep, whether or not the others could be.
+func (step *ParallelStep) Cleanup() error {
+	errs := make([]error, len(step.substeps))
+	for i, step := range step.substeps {
+		errs[i] = step.Cleanup()
 	}
-}
 
-func (step *ParallelStep) Cleanup() {
-	for _, step := range step.substeps {
-		step.Cleanup()
-	}
+	return sequence.Cleanups(errs...)
 }
@@ -1,6 +1,7 @@
 package parallel_step_test
 
 import (
+	"context"
 	"errors"
 	"sync"
 
@@ -19,43 +20,37 @@ var _ = Describe("ParallelStep", func() {
 
 	var thingHappened chan bool
 	var cleanedUp chan bool
-	var cancelled chan bool
 
 	BeforeEach(func() {
 		thingHappened = make(chan bool, 2)
 		cleanedUp = make(chan bool, 2)
-		cancelled = make(chan bool, 2)
 
 		running := new(sync.WaitGroup)
 		running.Add(2)
 
 		subStep1 = &fake_step.FakeStep{
-			PerformStub: func() error {
+			PerformStub: func(context.Context) error {
 				running.Done()
 				running.Wait()
 				thingHappened <- true
 				return nil
 			},
-			CleanupStub: func() {
+			CleanupStub: func() error {
 				cleanedUp <- true
-			},
-			CancelStub: func() {
-				cancelled <- true
+				return nil
 			},
 		}
 
 		subStep2 = &fake_step.FakeStep{
-			PerformStub: func() error {
+			PerformStub: func(context.Context) error {
 				running.Done()
 				running.Wait()
 				thingHappened <- true
 				return nil
 			},
-			CleanupStub: func() {
+			CleanupStub: func() error {
 				cleanedUp <- true
-			},
-			CancelStub: func() {
-				cancelled <- true
+				return nil
 			},
 		}
 	})
@@ -67,7 +62,7 @@ var _ = Describe("ParallelStep", func() {
 	It("performs its substeps in parallel", func(done Done) {
 		defer close(done)
 
-		err := step.Perform()
+		err := step.Perform(context.Background())
 		Ω(err).ShouldNot(HaveOccurred())
 
 		Eventually(thingHappened).Should(Receive())
@@ -84,13 +79,13 @@ var _ = Describe("ParallelStep", func() {
 			step2Completed = make(chan struct{})
 
 			subStep1 = &fake_step.FakeStep{
-				PerformStub: func() error {
+				PerformStub: func(context.Context) error {
 					return disaster
 				},
 			}
 
 			subStep2 = &fake_step.FakeStep{
-				PerformStub: func() error {
+				PerformStub: func(context.Context) error {
 					<-triggerStep2
 					close(step2Completed)
 					return nil
@@ -102,7 +97,7 @@ var _ = Describe("ParallelStep", func() {
 			errs := make(chan error)
 
 			go func() {
-				errs <- step.Perform()
+				errs <- step.Perform(context.Background())
 			}()
 
 			Consistently(errs).ShouldNot(Receive())
@@ -123,12 +118,39 @@ var _ = Describe("ParallelStep", func() {
 		})
 	})
 
-	Context("when told to cancel", func() {
-		It("passes the message along", func() {
-			step.Cancel()
+	Context("when cleaning up substeps fails", func() {
+		disaster1 := errors.New("oh no!")
+		disaster2 := errors.New("oh dear!")
+
+		BeforeEach(func() {
+			fake1 := &fake_step.FakeStep{}
+			fake1.CleanupReturns(disaster1)
+			subStep1 = fake1
+
+			fake2 := &fake_step.FakeStep{}
+			fake2.CleanupReturns(disaster2)
+			subStep2 = fake2
+		})
+
+		It("cleans up all of them and aggregates the errors", func() {
+			Ω(step.Cleanup()).Should(Equal(&sequence.CleanupError{
+				Errors: []error{disaster1, disaster2},
+			}))
+
+			Ω(subStep1.(*fake_step.FakeStep).CleanupCallCount()).Should(Equal(1))
+			Ω(subStep2.(*fake_step.FakeStep).CleanupCallCount()).Should(Equal(1))
+		})
+	})
+
+	Context("when the context is cancelled", func() {
+		It("passes it along to all substeps", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			step.Perform(ctx)
 
-			Eventually(cancelled).Should(Receive())
-			Eventually(cancelled).Should(Receive())
+			Ω(subStep1.(*fake_step.FakeStep).PerformArgsForCall(0)).Should(Equal(ctx))
+			Ω(subStep2.(*fake_step.FakeStep).PerformArgsForCall(0)).Should(Equal(ctx))
 		})
 	})
 })
@@ -1,34 +1,68 @@
 package run_step
 
 import (
+	"context"
 	"time"
 
 	"github.com/cloudfoundry-incubator/garden/warden"
 	"github.com/cloudfoundry-incubator/runtime-schema/models"
 	"github.com/pivotal-golang/lager"
 
+	"github.com/cloudfoundry-incubator/executor/api"
 	"github.com/cloudfoundry-incubator/executor/log_streamer"
 	"github.com/cloudfoundry-incubator/executor/steps/emittable_error"
 )
 
+// EventPollInterval is how often the container's events are looked at while
+// the process runs.
+const EventPollInterval = time.Second
+
+// StopWaitTimeout is how long a stopped process has to exit before the step
+// stops waiting for it, unless the step is given another.
+const StopWaitTimeout = 10 * time.Second
+
 type RunStep struct {
-	container warden.Container
-	model     models.RunAction
-	streamer  log_streamer.LogStreamer
-	logger    lager.Logger
+	container         warden.Container
+	model             models.RunAction
+	streamer          log_streamer.LogStreamer
+	recordExitStatus  func(int)
+	recordEvents      func([]string)
+	eventPollInterval time.Duration
+	stopWaitTimeout   time.Duration
+	logger            lager.Logger
+
+	reportedEvents map[string]bool
 }
 
+// New runs the action's process in container. The container's warden events
+// are passed to recordEvents as they are first seen, both every
+// eventPollInterval while the process runs, if it is non-zero, and once it
+// exits. A stopped process has stopWaitTimeout to exit, or StopWaitTimeout
+// if that is zero.
 func New(
 	container warden.Container,
 	model models.RunAction,
 	streamer log_streamer.LogStreamer,
+	recordExitStatus func(int),
+	recordEvents func([]string),
+	eventPollInterval time.Duration,
+	stopWaitTimeout time.Duration,
 	logger lager.Logger,
 ) *RunStep {
+	if stopWaitTimeout <= 0 {
+		stopWaitTimeout = StopWaitTimeout
+	}
+
 	return &RunStep{
-		container: container,
-		model:     model,
-		streamer:  streamer,
-		logger:    logger,
+		container:         container,
+		model:             model,
+		streamer:          streamer,
+		recordExitStatus:  recordExitStatus,
+		recordEvents:      recordEvents,
+		eventPollInterval: eventPollInterval,
+		stopWaitTimeout:   stopWaitTimeout,
+		logger:            logger,
+		reportedEvents:    map[string]bool{},
 	}
 }
 
@@ -42,18 +76,22 @@ func convertEnvironmentVariables(environmentVariables []models.EnvironmentVariab
 	return converted
 }
 
-func (step *RunStep) Perform() error {
+// Perform runs the process and waits for it to exit. If ctx is done first
+// the container is stopped; if the action's timeout passes first the step
+// fails, leaving the process be. However it ends, what the process has
+// written so far is flushed.
+func (step *RunStep) Perform(ctx context.Context) error {
 	step.logger.Debug("running")
 
 	exitStatusChan := make(chan int, 1)
 	errChan := make(chan error, 1)
 
-	var timeoutChan <-chan time.Time
+	runCtx := ctx
 
 	if step.model.Timeout != 0 {
-		timer := time.NewTimer(step.model.Timeout)
-		timeoutChan = timer.C
-		defer timer.Stop()
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, step.model.Timeout)
+		defer cancel()
 	}
 
 	process, err := step.container.Run(warden.ProcessSpec{
@@ -79,39 +117,113 @@ func (step *RunStep) Perform() error {
 		}
 	}()
 
-	select {
-	case exitStatus := <-exitStatusChan:
-		step.streamer.Flush()
+	var pollEvents <-chan time.Time
+	if step.eventPollInterval > 0 {
+		ticker := time.NewTicker(step.eventPollInterval)
+		defer ticker.Stop()
 
-		info, err := step.container.Info()
-		if err != nil {
-			step.logger.Error("failed-to-get-info", err)
-		} else {
-			for _, ev := range info.Events {
-				if ev == "out of memory" {
-					return emittable_error.New(nil, "Exited with status %d (out of memory)", exitStatus)
-				}
+		pollEvents = ticker.C
+	}
+
+	for {
+		select {
+		case <-pollEvents:
+			step.checkEvents()
+
+		case exitStatus := <-exitStatusChan:
+			step.streamer.Flush()
+
+			if step.recordExitStatus != nil {
+				step.recordExitStatus(exitStatus)
 			}
+
+			outOfMemory := step.checkEvents()
+			if outOfMemory {
+				return emittable_error.New(nil, "Exited with status %d (out of memory)", exitStatus)
+			}
+
+			if exitStatus != 0 {
+				return emittable_error.New(nil, "Exited with status %d", exitStatus)
+			}
+
+			return nil
+
+		case err := <-errChan:
+			step.streamer.Flush()
+
+			return err
+
+		case <-runCtx.Done():
+			if ctx.Err() == nil {
+				step.streamer.Flush()
+
+				return emittable_error.New(nil, "Timed out after %s", step.model.Timeout)
+			}
+
+			err := step.container.Stop(false)
+			if err != nil {
+				step.logger.Error("failed-to-stop", err)
+			}
+
+			step.waitForExit(exitStatusChan, errChan)
+
+			step.streamer.Flush()
+
+			return ctx.Err()
 		}
+	}
+}
+
+// waitForExit gives a stopped process a while to exit, so that nothing is
+// left waiting on it once the step is done.
+func (step *RunStep) waitForExit(exitStatusChan <-chan int, errChan <-chan error) {
+	timer := time.NewTimer(step.stopWaitTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-exitStatusChan:
+	case <-errChan:
+	case <-timer.C:
+		step.logger.Info("process-did-not-exit-after-stop")
+	}
+}
 
-		if exitStatus != 0 {
-			return emittable_error.New(nil, "Exited with status %d", exitStatus)
+// checkEvents records the container's events not yet reported, and says
+// whether it has run out of memory.
+func (step *RunStep) checkEvents() bool {
+	info, err := step.container.Info()
+	if err != nil {
+		step.logger.Error("failed-to-get-info", err)
+		return false
+	}
+
+	outOfMemory := false
+	newEvents := []string{}
+
+	for _, ev := range info.Events {
+		if ev == api.OutOfMemoryEvent {
+			outOfMemory = true
+		}
+
+		if step.reportedEvents[ev] {
+			continue
 		}
 
-		return nil
+		step.reportedEvents[ev] = true
+		newEvents = append(newEvents, ev)
 
-	case err := <-errChan:
-		return err
+		if ev == api.OutOfMemoryEvent {
+			step.logger.Info("out-of-memory")
+		}
+	}
 
-	case <-timeoutChan:
-		return emittable_error.New(nil, "Timed out after %s", step.model.Timeout)
+	if len(newEvents) > 0 && step.recordEvents != nil {
+		step.recordEvents(newEvents)
 	}
 
-	panic("unreachable")
+	return outOfMemory
 }
 
-func (step *RunStep) Cancel() {
-	step.container.Stop(false)
+func (step *RunStep) Cleanup() error {
+	return nil
 }
-
-func (step *RunStep) Cleanup() {}
@@ -1,7 +1,9 @@
 package run_step_test
 
 import (
+	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/cloudfoundry-incubator/executor/sequence"
@@ -32,9 +34,26 @@ var _ = Describe("RunAction", func() {
 
 	var spawnedProcess *wfakes.FakeProcess
 	var runError error
+	var exitStatuses []int
+	var eventPollInterval time.Duration
+	var stopWaitTimeout time.Duration
+
+	var eventsLock sync.Mutex
+	var events []string
+
+	recordedEvents := func() []string {
+		eventsLock.Lock()
+		defer eventsLock.Unlock()
+
+		return events
+	}
 
 	BeforeEach(func() {
 		fileDescriptorLimit = 17
+		exitStatuses = nil
+		eventPollInterval = 0
+		stopWaitTimeout = 0
+		events = nil
 
 		runAction = models.RunAction{
 			Path: "sudo",
@@ -74,6 +93,17 @@ var _ = Describe("RunAction", func() {
 			container,
 			runAction,
 			fakeStreamer,
+			func(exitStatus int) {
+				exitStatuses = append(exitStatuses, exitStatus)
+			},
+			func(newEvents []string) {
+				eventsLock.Lock()
+				defer eventsLock.Unlock()
+
+				events = append(events, newEvents...)
+			},
+			eventPollInterval,
+			stopWaitTimeout,
 			logger,
 		)
 	})
@@ -82,7 +112,7 @@ var _ = Describe("RunAction", func() {
 		var stepErr error
 
 		JustBeforeEach(func() {
-			stepErr = step.Perform()
+			stepErr = step.Perform(context.Background())
 		})
 
 		Context("when the script succeeds", func() {
@@ -124,6 +154,10 @@ var _ = Describe("RunAction", func() {
 			It("should return an emittable error with the exit code", func() {
 				Ω(stepErr).Should(MatchError(emittable_error.New(nil, "Exited with status 19")))
 			})
+
+			It("records the exit status", func() {
+				Ω(exitStatuses).Should(Equal([]int{19}))
+			})
 		})
 
 		Context("when Warden errors", func() {
@@ -138,6 +172,22 @@ var _ = Describe("RunAction", func() {
 			})
 		})
 
+		Context("when waiting for the process fails", func() {
+			disaster := errors.New("lost the process")
+
+			BeforeEach(func() {
+				spawnedProcess.WaitReturns(0, disaster)
+			})
+
+			It("returns the error", func() {
+				Ω(stepErr).Should(Equal(disaster))
+			})
+
+			It("flushes the output written so far", func() {
+				Ω(fakeStreamer.FlushCallCount()).Should(Equal(1))
+			})
+		})
+
 		Context("when the step does not have a timeout", func() {
 			BeforeEach(func() {
 				spawnedProcess.WaitStub = func() (int, error) {
@@ -179,6 +229,10 @@ var _ = Describe("RunAction", func() {
 				It("returns an emittable error", func() {
 					Ω(stepErr).Should(MatchError(emittable_error.New(nil, "Timed out after 100ms")))
 				})
+
+				It("flushes the output written so far", func() {
+					Ω(fakeStreamer.FlushCallCount()).Should(Equal(1))
+				})
 			})
 		})
 
@@ -197,6 +251,10 @@ var _ = Describe("RunAction", func() {
 			It("returns an emittable error", func() {
 				Ω(stepErr).Should(MatchError(emittable_error.New(nil, "Exited with status 19 (out of memory)")))
 			})
+
+			It("records the events", func() {
+				Ω(recordedEvents()).Should(Equal([]string{"happy land", "out of memory", "another event"}))
+			})
 		})
 
 		Describe("emitting logs", func() {
@@ -233,17 +291,144 @@ var _ = Describe("RunAction", func() {
 		})
 	})
 
-	Describe("Cancel", func() {
-		JustBeforeEach(func() {
-			step.Cancel()
+	Context("when the container runs out of memory while the process is running", func() {
+		var exited chan struct{}
+
+		BeforeEach(func() {
+			eventPollInterval = 10 * time.Millisecond
+
+			exited = make(chan struct{})
+			processExited := exited
+
+			wardenClient.Connection.InfoReturns(
+				warden.ContainerInfo{
+					Events: []string{"out of memory"},
+				},
+				nil,
+			)
+
+			spawnedProcess.WaitStub = func() (int, error) {
+				<-processExited
+				return 137, nil
+			}
 		})
 
-		It("stops the container", func() {
+		It("records the event before the process exits, and only once", func() {
+			errs := make(chan error)
+			go func() { errs <- step.Perform(context.Background()) }()
+
+			Eventually(recordedEvents).Should(Equal([]string{"out of memory"}))
+			Consistently(errs).ShouldNot(Receive())
+
+			close(exited)
+
+			Eventually(errs).Should(Receive(MatchError(emittable_error.New(nil, "Exited with status 137 (out of memory)"))))
+			Ω(recordedEvents()).Should(Equal([]string{"out of memory"}))
+		})
+	})
+
+	Context("when the context is cancelled while the process is running", func() {
+		var ctx context.Context
+		var cancel context.CancelFunc
+		var waiting chan struct{}
+		var waitReturned chan struct{}
+
+		BeforeEach(func() {
+			ctx, cancel = context.WithCancel(context.Background())
+
+			waiting = make(chan struct{}, 1)
+			processWaiting := waiting
+
+			waitReturned = make(chan struct{})
+			processWaitReturned := waitReturned
+
+			exited := make(chan struct{})
+
+			spawnedProcess.WaitStub = func() (int, error) {
+				defer close(processWaitReturned)
+
+				processWaiting <- struct{}{}
+				<-exited
+				return 0, nil
+			}
+
+			wardenClient.Connection.StopStub = func(string, bool) error {
+				close(exited)
+				return nil
+			}
+		})
+
+		It("stops the container and fails with the context's error", func() {
+			errs := make(chan error)
+			go func() { errs <- step.Perform(ctx) }()
+
+			Eventually(waiting).Should(Receive())
+
+			cancel()
+
+			Eventually(errs).Should(Receive(Equal(context.Canceled)))
+
 			Ω(wardenClient.Connection.StopCallCount()).Should(Equal(1))
 
 			stoppedHandle, kill := wardenClient.Connection.StopArgsForCall(0)
 			Ω(stoppedHandle).Should(Equal(handle))
 			Ω(kill).Should(BeFalse())
 		})
+
+		It("waits for the stopped process to exit", func() {
+			errs := make(chan error)
+			go func() { errs <- step.Perform(ctx) }()
+
+			Eventually(waiting).Should(Receive())
+
+			cancel()
+
+			Eventually(errs).Should(Receive())
+			Ω(waitReturned).Should(BeClosed())
+		})
+
+		It("does not record an exit status", func() {
+			errs := make(chan error)
+			go func() { errs <- step.Perform(ctx) }()
+
+			Eventually(waiting).Should(Receive())
+
+			cancel()
+
+			Eventually(errs).Should(Receive())
+			Ω(exitStatuses).Should(BeEmpty())
+		})
+
+		It("flushes the output written before it was stopped", func() {
+			errs := make(chan error)
+			go func() { errs <- step.Perform(ctx) }()
+
+			Eventually(waiting).Should(Receive())
+
+			cancel()
+
+			Eventually(errs).Should(Receive())
+			Ω(fakeStreamer.FlushCallCount()).Should(Equal(1))
+		})
+
+		Context("and the stopped process does not exit", func() {
+			BeforeEach(func() {
+				stopWaitTimeout = 50 * time.Millisecond
+
+				wardenClient.Connection.StopStub = nil
+			})
+
+			It("gives up waiting for it after the stop wait timeout", func() {
+				errs := make(chan error)
+				go func() { errs <- step.Perform(ctx) }()
+
+				Eventually(waiting).Should(Receive())
+
+				cancel()
+
+				Eventually(errs).Should(Receive(Equal(context.Canceled)))
+				Ω(waitReturned).ShouldNot(BeClosed())
+			})
+		})
 	})
 })
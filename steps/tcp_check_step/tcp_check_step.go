@@ -0,0 +1,70 @@
+package tcp_check_step
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/cloudfoundry-incubator/executor/steps/container_address"
+	"github.com/cloudfoundry-incubator/executor/steps/emittable_error"
+	"github.com/cloudfoundry-incubator/garden/warden"
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/pivotal-golang/lager"
+)
+
+const DefaultTimeout = time.Second
+
+// TCPCheckStep succeeds if a port on the container's IP accepts a
+// connection. It dials from the executor, so no process is spawned in the
+// container.
+type TCPCheckStep struct {
+	model  models.TCPCheckAction
+	ip     *container_address.IP
+	logger lager.Logger
+}
+
+func New(container warden.Container, model models.TCPCheckAction, logger lager.Logger) *TCPCheckStep {
+	return &TCPCheckStep{
+		model:  model,
+		ip:     container_address.NewIP(container),
+		logger: logger,
+	}
+}
+
+func Validate(model models.TCPCheckAction) error {
+	return container_address.ValidatePort(model.Port)
+}
+
+func (step *TCPCheckStep) Perform(ctx context.Context) error {
+	ip, err := step.ip.Get()
+	if err != nil {
+		step.logger.Error("failed-to-get-info", err)
+		return err
+	}
+
+	timeout := step.model.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	address := net.JoinHostPort(ip, fmt.Sprintf("%d", step.model.Port))
+
+	dialer := &net.Dialer{Timeout: timeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		step.logger.Info("failed-to-connect", lager.Data{
+			"address": address,
+			"error":   err.Error(),
+		})
+
+		return emittable_error.New(err, "Failed to connect to port %d", step.model.Port)
+	}
+
+	return conn.Close()
+}
+
+func (step *TCPCheckStep) Cleanup() error {
+	return nil
+}
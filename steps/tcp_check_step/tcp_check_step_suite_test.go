@@ -0,0 +1,13 @@
+package tcp_check_step_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestTCPCheckStep(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tcp_check_step Suite")
+}
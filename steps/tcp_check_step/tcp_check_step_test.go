@@ -0,0 +1,117 @@
+package tcp_check_step_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+
+	"github.com/cloudfoundry-incubator/executor/sequence"
+	"github.com/cloudfoundry-incubator/executor/steps/container_address"
+	"github.com/cloudfoundry-incubator/executor/steps/emittable_error"
+	. "github.com/cloudfoundry-incubator/executor/steps/tcp_check_step"
+	"github.com/cloudfoundry-incubator/garden/warden"
+	"github.com/cloudfoundry-incubator/garden/warden/fakes"
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TCPCheckStep", func() {
+	var (
+		container *fakes.FakeContainer
+		listener  net.Listener
+		port      uint32
+
+		step sequence.Step
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, portString, err := net.SplitHostPort(listener.Addr().String())
+		Ω(err).ShouldNot(HaveOccurred())
+
+		parsedPort, err := strconv.Atoi(portString)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		port = uint32(parsedPort)
+
+		container = new(fakes.FakeContainer)
+		container.InfoReturns(warden.ContainerInfo{ContainerIP: "127.0.0.1"}, nil)
+	})
+
+	AfterEach(func() {
+		listener.Close()
+	})
+
+	JustBeforeEach(func() {
+		step = New(container, models.TCPCheckAction{Port: port}, lagertest.NewTestLogger("test"))
+	})
+
+	Context("when the port accepts connections", func() {
+		It("succeeds", func() {
+			Ω(step.Perform(context.Background())).ShouldNot(HaveOccurred())
+		})
+
+		It("only looks up the container's IP once", func() {
+			Ω(step.Perform(context.Background())).ShouldNot(HaveOccurred())
+			Ω(step.Perform(context.Background())).ShouldNot(HaveOccurred())
+
+			Ω(container.InfoCallCount()).Should(Equal(1))
+		})
+	})
+
+	Context("when nothing is listening on the port", func() {
+		BeforeEach(func() {
+			listener.Close()
+		})
+
+		It("fails with an emittable error", func() {
+			err := step.Perform(context.Background())
+			Ω(err).Should(BeAssignableToTypeOf(&emittable_error.EmittableError{}))
+			Ω(err.(*emittable_error.EmittableError).EmittableError()).Should(ContainSubstring("Failed to connect to port"))
+		})
+	})
+
+	Context("when the container has no IP yet", func() {
+		BeforeEach(func() {
+			container.InfoReturns(warden.ContainerInfo{}, nil)
+		})
+
+		It("fails, and looks it up again the next time", func() {
+			Ω(step.Perform(context.Background())).Should(Equal(container_address.ErrNoContainerIP))
+			Ω(step.Perform(context.Background())).Should(Equal(container_address.ErrNoContainerIP))
+
+			Ω(container.InfoCallCount()).Should(Equal(2))
+		})
+	})
+
+	Context("when getting the container's info fails", func() {
+		disaster := errors.New("oh no")
+
+		BeforeEach(func() {
+			container.InfoReturns(warden.ContainerInfo{}, disaster)
+		})
+
+		It("returns the error", func() {
+			Ω(step.Perform(context.Background())).Should(Equal(disaster))
+		})
+	})
+})
+
+var _ = Describe("Validate", func() {
+	It("accepts a port in range", func() {
+		Ω(Validate(models.TCPCheckAction{Port: 8080})).ShouldNot(HaveOccurred())
+	})
+
+	It("rejects a port out of range", func() {
+		Ω(Validate(models.TCPCheckAction{Port: 0})).Should(Equal(container_address.ErrInvalidPort))
+		Ω(Validate(models.TCPCheckAction{Port: 65536})).Should(Equal(container_address.ErrInvalidPort))
+	})
+})
@@ -0,0 +1,167 @@
+package transfer_progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/bytefmt"
+)
+
+// Reporter keeps track of a single download or upload. Every interval it
+// prints how far along the transfer is, and passes the bytes moved since the
+// last interval to record so they can be counted against the container; the
+// rest are passed on when it is stopped. A retry that starts over is only
+// counted once it gets further than the attempts before it.
+type Reporter struct {
+	verb     string
+	out      io.Writer
+	interval time.Duration
+	record   func(transferred int64)
+
+	lock        sync.Mutex
+	transferred int64
+	counted     int64
+	unrecorded  int64
+	total       int64
+	startedAt   time.Time
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func New(verb string, out io.Writer, interval time.Duration, record func(transferred int64)) *Reporter {
+	return &Reporter{
+		verb:     verb,
+		out:      out,
+		interval: interval,
+		record:   record,
+		total:    -1,
+	}
+}
+
+// Start begins printing progress lines. With no interval it only counts.
+func (reporter *Reporter) Start() {
+	reporter.lock.Lock()
+	reporter.startedAt = time.Now()
+	reporter.lock.Unlock()
+
+	if reporter.interval <= 0 {
+		return
+	}
+
+	reporter.stop = make(chan struct{})
+	reporter.stopped = make(chan struct{})
+
+	go reporter.report(reporter.stop, reporter.stopped)
+}
+
+// Stop stops printing, and records whatever has not been yet.
+func (reporter *Reporter) Stop() {
+	if reporter.stop != nil {
+		close(reporter.stop)
+		<-reporter.stopped
+		reporter.stop = nil
+	}
+
+	reporter.flush()
+}
+
+// Progress takes the bytes written so far out of the total (-1 if unknown),
+// in the shape of a cacheddownloader.ProgressFunc. A count that goes back
+// down is a retry starting over.
+func (reporter *Reporter) Progress(written int64, total int64) {
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+
+	reporter.transferred = written
+	reporter.total = total
+	reporter.count()
+}
+
+// Reader counts everything read from source as transferred.
+func (reporter *Reporter) Reader(source io.Reader) io.Reader {
+	return &countingReader{
+		source:   source,
+		reporter: reporter,
+	}
+}
+
+// count notes the bytes transferred beyond what any attempt got to before.
+// It is called with the lock held.
+func (reporter *Reporter) count() {
+	if reporter.transferred > reporter.counted {
+		reporter.unrecorded += reporter.transferred - reporter.counted
+		reporter.counted = reporter.transferred
+	}
+}
+
+func (reporter *Reporter) flush() {
+	reporter.lock.Lock()
+	unrecorded := reporter.unrecorded
+	reporter.unrecorded = 0
+	reporter.lock.Unlock()
+
+	if unrecorded > 0 && reporter.record != nil {
+		reporter.record(unrecorded)
+	}
+}
+
+func (reporter *Reporter) report(stop <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+
+	ticker := time.NewTicker(reporter.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			fmt.Fprintln(reporter.out, reporter.line())
+			reporter.flush()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (reporter *Reporter) line() string {
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+
+	rate := "0"
+	elapsed := time.Since(reporter.startedAt).Seconds()
+	if elapsed > 0 {
+		rate = bytefmt.ByteSize(uint64(float64(reporter.transferred) / elapsed))
+	}
+
+	if reporter.total > 0 {
+		return fmt.Sprintf(
+			"%s %s of %s (%d%%), %s/s",
+			reporter.verb,
+			bytefmt.ByteSize(uint64(reporter.transferred)),
+			bytefmt.ByteSize(uint64(reporter.total)),
+			reporter.transferred*100/reporter.total,
+			rate,
+		)
+	}
+
+	return fmt.Sprintf("%s %s, %s/s", reporter.verb, bytefmt.ByteSize(uint64(reporter.transferred)), rate)
+}
+
+type countingReader struct {
+	source   io.Reader
+	reporter *Reporter
+}
+
+func (reader *countingReader) Read(p []byte) (int, error) {
+	n, err := reader.source.Read(p)
+	if n > 0 {
+		reader.reporter.lock.Lock()
+		reader.reporter.transferred += int64(n)
+		reader.reporter.count()
+		reader.reporter.lock.Unlock()
+	}
+
+	return n, err
+}
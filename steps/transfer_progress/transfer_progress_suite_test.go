@@ -0,0 +1,13 @@
+package transfer_progress_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestTransferProgress(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "TransferProgress Suite")
+}
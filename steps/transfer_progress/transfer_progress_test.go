@@ -0,0 +1,135 @@
+package transfer_progress_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/executor/steps/transfer_progress"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("TransferProgress", func() {
+	var (
+		out      *gbytes.Buffer
+		interval time.Duration
+		reporter *transfer_progress.Reporter
+
+		recordLock sync.Mutex
+		recorded   int64
+	)
+
+	BeforeEach(func() {
+		out = gbytes.NewBuffer()
+		interval = 10 * time.Millisecond
+		recorded = 0
+	})
+
+	JustBeforeEach(func() {
+		reporter = transfer_progress.New("Downloaded", out, interval, func(transferred int64) {
+			recordLock.Lock()
+			recorded += transferred
+			recordLock.Unlock()
+		})
+
+		reporter.Start()
+	})
+
+	AfterEach(func() {
+		reporter.Stop()
+	})
+
+	totalRecorded := func() int64 {
+		recordLock.Lock()
+		defer recordLock.Unlock()
+		return recorded
+	}
+
+	Context("when the total is known", func() {
+		It("periodically prints the bytes transferred, the percentage and the rate", func() {
+			reporter.Progress(1024, 4096)
+			Eventually(out).Should(gbytes.Say(`Downloaded 1K of 4K \(25\x25\), [\d.]+[KMGT]?/s`))
+
+			reporter.Progress(2048, 4096)
+			Eventually(out).Should(gbytes.Say(`Downloaded 2K of 4K \(50\x25\)`))
+		})
+	})
+
+	Context("when the total is not known", func() {
+		It("prints the bytes transferred and the rate", func() {
+			reporter.Progress(2048, -1)
+			Eventually(out).Should(gbytes.Say(`Downloaded 2K, [\d.]+[KMGT]?/s`))
+		})
+	})
+
+	It("records every byte transferred once stopped", func() {
+		reporter.Progress(100, 300)
+		reporter.Progress(300, 300)
+
+		reporter.Stop()
+
+		Ω(totalRecorded()).Should(Equal(int64(300)))
+	})
+
+	It("records the bytes transferred every interval", func() {
+		reporter.Progress(100, 300)
+		Eventually(totalRecorded).Should(Equal(int64(100)))
+
+		reporter.Progress(300, 300)
+		Eventually(totalRecorded).Should(Equal(int64(300)))
+	})
+
+	It("does not record a retry that starts over until it gets further", func() {
+		reporter.Progress(200, 300)
+		reporter.Progress(50, 300)
+		reporter.Progress(200, 300)
+		reporter.Progress(250, 300)
+
+		reporter.Stop()
+
+		Ω(totalRecorded()).Should(Equal(int64(250)))
+	})
+
+	It("counts what is read through Reader", func() {
+		_, err := ioutil.ReadAll(reporter.Reader(strings.NewReader("some-payload")))
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Eventually(out).Should(gbytes.Say(`Downloaded 12, `))
+
+		reporter.Stop()
+
+		Ω(totalRecorded()).Should(Equal(int64(len("some-payload"))))
+	})
+
+	It("stops printing once stopped", func() {
+		reporter.Progress(1024, -1)
+		Eventually(out).Should(gbytes.Say(`Downloaded 1K`))
+
+		reporter.Stop()
+		printed := len(out.Contents())
+
+		Consistently(func() int {
+			return len(out.Contents())
+		}, 5*interval).Should(Equal(printed))
+	})
+
+	Context("without an interval", func() {
+		BeforeEach(func() {
+			interval = 0
+		})
+
+		It("only counts", func() {
+			reporter.Progress(1024, 4096)
+
+			Consistently(out, 50*time.Millisecond).ShouldNot(gbytes.Say(`Downloaded`))
+
+			reporter.Stop()
+
+			Ω(totalRecorded()).Should(Equal(int64(1024)))
+		})
+	})
+})
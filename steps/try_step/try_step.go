@@ -1,6 +1,8 @@
 package try_step
 
 import (
+	"context"
+
 	"github.com/cloudfoundry-incubator/executor/sequence"
 	"github.com/pivotal-golang/lager"
 )
@@ -17,8 +19,8 @@ func New(substep sequence.Step, logger lager.Logger) *TryStep {
 	}
 }
 
-func (step *TryStep) Perform() error {
-	err := step.substep.Perform()
+func (step *TryStep) Perform(ctx context.Context) error {
+	err := step.substep.Perform(ctx)
 	if err != nil {
 		step.logger.Info("failed", lager.Data{
 			"error": err.Error(),
@@ -28,10 +30,6 @@ func (step *TryStep) Perform() error {
 	return nil //We never return an error.  That's the point.
 }
 
-func (step *TryStep) Cancel() {
-	step.substep.Cancel()
-}
-
-func (step *TryStep) Cleanup() {
-	step.substep.Cleanup()
+func (step *TryStep) Cleanup() error {
+	return step.substep.Cleanup()
 }
@@ -1,6 +1,7 @@
 package try_step_test
 
 import (
+	"context"
 	"errors"
 
 	. "github.com/onsi/ginkgo"
@@ -18,22 +19,19 @@ var _ = Describe("TryStep", func() {
 	var subStep sequence.Step
 	var thingHappened bool
 	var cleanedUp bool
-	var cancelled bool
 	var logger *lagertest.TestLogger
 
 	BeforeEach(func() {
-		thingHappened, cleanedUp, cancelled = false, false, false
+		thingHappened, cleanedUp = false, false
 
 		subStep = &fake_step.FakeStep{
-			PerformStub: func() error {
+			PerformStub: func(context.Context) error {
 				thingHappened = true
 				return nil
 			},
-			CleanupStub: func() {
+			CleanupStub: func() error {
 				cleanedUp = true
-			},
-			CancelStub: func() {
-				cancelled = true
+				return nil
 			},
 		}
 
@@ -45,7 +43,7 @@ var _ = Describe("TryStep", func() {
 	})
 
 	It("performs its substep", func() {
-		err := step.Perform()
+		err := step.Perform(context.Background())
 		Ω(err).ShouldNot(HaveOccurred())
 
 		Ω(thingHappened).To(BeTrue())
@@ -56,19 +54,19 @@ var _ = Describe("TryStep", func() {
 
 		BeforeEach(func() {
 			subStep = &fake_step.FakeStep{
-				PerformStub: func() error {
+				PerformStub: func(context.Context) error {
 					return disaster
 				},
 			}
 		})
 
 		It("succeeds anyway", func() {
-			err := step.Perform()
+			err := step.Perform(context.Background())
 			Ω(err).ShouldNot(HaveOccurred())
 		})
 
 		It("logs the failure", func() {
-			err := step.Perform()
+			err := step.Perform(context.Background())
 			Ω(err).ShouldNot(HaveOccurred())
 
 			Ω(logger.TestSink.Buffer).Should(gbytes.Say("failed"))
@@ -79,16 +77,32 @@ var _ = Describe("TryStep", func() {
 	Context("when told to clean up", func() {
 		It("passes the message along", func() {
 			Ω(cleanedUp).Should(BeFalse())
-			step.Cleanup()
+			Ω(step.Cleanup()).ShouldNot(HaveOccurred())
 			Ω(cleanedUp).Should(BeTrue())
 		})
+
+		Context("when the substep fails to clean up", func() {
+			disaster := errors.New("leaked")
+
+			BeforeEach(func() {
+				subStep = &fake_step.FakeStep{}
+				subStep.(*fake_step.FakeStep).CleanupReturns(disaster)
+			})
+
+			It("returns its error", func() {
+				Ω(step.Cleanup()).Should(Equal(disaster))
+			})
+		})
 	})
 
-	Context("when told to cancel", func() {
-		It("passes the message along", func() {
-			Ω(cancelled).Should(BeFalse())
-			step.Cancel()
-			Ω(cancelled).Should(BeTrue())
+	Context("when the context is cancelled", func() {
+		It("passes it along to the substep", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			step.Perform(ctx)
+
+			Ω(subStep.(*fake_step.FakeStep).PerformArgsForCall(0)).Should(Equal(ctx))
 		})
 	})
 })
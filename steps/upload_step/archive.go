@@ -0,0 +1,176 @@
+package upload_step
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+)
+
+var ErrNotASingleFile = errors.New("raw uploads must contain exactly one file")
+var ErrCompressedZip = errors.New("zip uploads cannot also be compressed")
+
+func ValidateFormat(model models.UploadAction) error {
+	switch model.Format {
+	case models.UploadFormatZip:
+		if model.Compress {
+			return ErrCompressedZip
+		}
+
+		return nil
+	case "", models.UploadFormatRaw, models.UploadFormatTar, models.UploadFormatTgz:
+		return nil
+	}
+
+	return fmt.Errorf("unsupported upload format: %s", model.Format)
+}
+
+// writeArchive converts the tar stream coming out of the container into the
+// shape requested by the action. Without an explicit format it is gzipped,
+// as every upload used to be; a raw or tar upload is gzipped if the action
+// asks for compression.
+func writeArchive(destination io.Writer, tarStream io.Reader, model models.UploadAction) error {
+	format := model.Format
+	if format == "" {
+		format = models.UploadFormatTgz
+	}
+
+	if format == models.UploadFormatTgz {
+		format = models.UploadFormatTar
+		model.Compress = true
+	}
+
+	if format == models.UploadFormatZip {
+		return writeZip(destination, tarStream)
+	}
+
+	if model.Compress {
+		gzipWriter := gzip.NewWriter(destination)
+
+		err := writeUncompressed(gzipWriter, tarStream, format)
+		if err != nil {
+			gzipWriter.Close()
+			return err
+		}
+
+		return gzipWriter.Close()
+	}
+
+	return writeUncompressed(destination, tarStream, format)
+}
+
+func writeUncompressed(destination io.Writer, tarStream io.Reader, format string) error {
+	if format == models.UploadFormatRaw {
+		return writeSingleFile(destination, tarStream)
+	}
+
+	_, err := io.Copy(destination, tarStream)
+	return err
+}
+
+func writeSingleFile(destination io.Writer, tarStream io.Reader) error {
+	tarReader := tar.NewReader(tarStream)
+
+	found := false
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if header.FileInfo().IsDir() {
+			continue
+		}
+
+		if found || !header.FileInfo().Mode().IsRegular() {
+			return ErrNotASingleFile
+		}
+
+		found = true
+
+		_, err = io.Copy(destination, tarReader)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !found {
+		return ErrNotASingleFile
+	}
+
+	return nil
+}
+
+func writeZip(destination io.Writer, tarStream io.Reader) error {
+	tarReader := tar.NewReader(tarStream)
+	zipWriter := zip.NewWriter(destination)
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			zipWriter.Close()
+			return err
+		}
+
+		err = writeZipEntry(zipWriter, header, tarReader)
+		if err != nil {
+			zipWriter.Close()
+			return err
+		}
+	}
+
+	return zipWriter.Close()
+}
+
+// writeZipEntry adds a tar entry to the zip. StreamOut names entries
+// relative to "./", which zip tools would keep, so it is dropped.
+func writeZipEntry(zipWriter *zip.Writer, header *tar.Header, contents io.Reader) error {
+	fileInfo := header.FileInfo()
+
+	name := strings.TrimPrefix(header.Name, "./")
+	if name == "" || name == "." {
+		return nil
+	}
+
+	zipHeader, err := zip.FileInfoHeader(fileInfo)
+	if err != nil {
+		return err
+	}
+
+	zipHeader.Name = name
+	if fileInfo.IsDir() {
+		if zipHeader.Name[len(zipHeader.Name)-1] != '/' {
+			zipHeader.Name += "/"
+		}
+	} else {
+		zipHeader.Method = zip.Deflate
+	}
+
+	entry, err := zipWriter.CreateHeader(zipHeader)
+	if err != nil {
+		return err
+	}
+
+	switch header.Typeflag {
+	case tar.TypeSymlink:
+		_, err = io.WriteString(entry, header.Linkname)
+	case tar.TypeReg:
+		_, err = io.Copy(entry, contents)
+	}
+
+	return err
+}
@@ -1,12 +1,10 @@
 package upload_step
 
 import (
-	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/url"
-	"os"
 
 	"github.com/cloudfoundry-incubator/garden/warden"
 	"github.com/cloudfoundry-incubator/runtime-schema/models"
@@ -14,8 +12,10 @@ import (
 	"github.com/pivotal-golang/bytefmt"
 	"github.com/pivotal-golang/lager"
 
+	"github.com/cloudfoundry-incubator/executor/cancellable"
 	"github.com/cloudfoundry-incubator/executor/log_streamer"
 	"github.com/cloudfoundry-incubator/executor/steps/emittable_error"
+	"github.com/cloudfoundry-incubator/executor/steps/transfer_progress"
 	"github.com/cloudfoundry-incubator/executor/uploader"
 )
 
@@ -24,8 +24,8 @@ type UploadStep struct {
 	model      models.UploadAction
 	uploader   uploader.Uploader
 	compressor compressor.Compressor
-	tempDir    string
 	streamer   log_streamer.LogStreamer
+	progress   *transfer_progress.Reporter
 	logger     lager.Logger
 }
 
@@ -34,8 +34,8 @@ func New(
 	model models.UploadAction,
 	uploader uploader.Uploader,
 	compressor compressor.Compressor,
-	tempDir string,
 	streamer log_streamer.LogStreamer,
+	progress *transfer_progress.Reporter,
 	logger lager.Logger,
 ) *UploadStep {
 	return &UploadStep{
@@ -43,60 +43,70 @@ func New(
 		model:      model,
 		uploader:   uploader,
 		compressor: compressor,
-		tempDir:    tempDir,
 		streamer:   streamer,
+		progress:   progress,
 		logger:     logger,
 	}
 }
 
-func (step *UploadStep) Perform() (err error) {
+func (step *UploadStep) Perform(ctx context.Context) (err error) {
 	url, err := url.ParseRequestURI(step.model.To)
 	if err != nil {
 		return err
 	}
 
-	tempDir, err := ioutil.TempDir(step.tempDir, "upload")
-	if err != nil {
-		return err
-	}
-
-	defer os.RemoveAll(tempDir)
-
 	streamOut, err := step.container.StreamOut(step.model.From)
 	if err != nil {
 		return emittable_error.New(err, "Copying out of the container failed")
 	}
 	defer streamOut.Close()
 
-	tempFile, err := ioutil.TempFile(step.tempDir, "compressed")
-	if err != nil {
-		return emittable_error.New(err, "Compression failed")
-	}
+	stopClosing := cancellable.CloseOnDone(ctx, streamOut)
+	defer stopClosing()
 
-	gzipWriter := gzip.NewWriter(tempFile)
+	reader, writer := io.Pipe()
 
-	_, err = io.Copy(gzipWriter, streamOut)
-	if err != nil {
-		return emittable_error.New(err, "Copying out of the container failed")
-	}
+	archiveResult := make(chan error, 1)
 
-	gzipWriter.Close()
-	tempFile.Close()
+	go func() {
+		err := writeArchive(writer, streamOut, step.model)
+		writer.CloseWithError(err)
+		archiveResult <- err
+	}()
 
-	finalFileLocation := tempFile.Name()
+	step.progress.Start()
+	uploadedBytes, uploadErr := step.uploader.Upload(ctx, step.progress.Reader(reader), url, step.logger)
+	step.progress.Stop()
 
-	defer os.RemoveAll(finalFileLocation)
+	reader.Close()
 
-	uploadedBytes, err := step.uploader.Upload(finalFileLocation, url, step.logger)
-	if err != nil {
-		return err
+	archiveErr := <-archiveResult
+	if archiveErr != nil && archiveErr != io.ErrClosedPipe {
+		return emittable_error.New(archiveErr, "Copying out of the container failed")
+	}
+
+	if uploadErr != nil {
+		return uploadFailure(uploadErr)
 	}
 
-	fmt.Fprintf(step.streamer.Stdout(), fmt.Sprintf("Uploaded (%s)\n", bytefmt.ByteSize(uint64(uploadedBytes))))
+	fmt.Fprintf(step.streamer.Stdout(), "Uploaded (%s)\n", bytefmt.ByteSize(uint64(uploadedBytes)))
 
 	return nil
 }
 
-func (step *UploadStep) Cancel() {}
+func (step *UploadStep) Cleanup() error {
+	return nil
+}
 
-func (step *UploadStep) Cleanup() {}
+func uploadFailure(err error) error {
+	uploadErr, ok := err.(*uploader.UploadError)
+	if !ok {
+		return err
+	}
+
+	if uploadErr.StatusCode == 0 {
+		return emittable_error.New(err, "Uploading failed after %d attempt(s)", uploadErr.Attempts)
+	}
+
+	return emittable_error.New(err, "Uploading failed after %d attempt(s), last status %d", uploadErr.Attempts, uploadErr.StatusCode)
+}
@@ -2,14 +2,15 @@ package upload_step_test
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"errors"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
-	"os/user"
 	"time"
 
 	"github.com/cloudfoundry-incubator/garden/client/fake_warden_client"
@@ -21,6 +22,7 @@ import (
 	"github.com/cloudfoundry-incubator/executor/log_streamer/fake_log_streamer"
 	"github.com/cloudfoundry-incubator/executor/sequence"
 	"github.com/cloudfoundry-incubator/executor/steps/emittable_error"
+	"github.com/cloudfoundry-incubator/executor/steps/transfer_progress"
 	. "github.com/cloudfoundry-incubator/executor/steps/upload_step"
 	Uploader "github.com/cloudfoundry-incubator/executor/uploader"
 	"github.com/cloudfoundry-incubator/executor/uploader/fake_uploader"
@@ -53,25 +55,22 @@ func (b *ClosableBuffer) IsClosed() bool {
 
 var _ = Describe("UploadStep", func() {
 	var step sequence.Step
-	var result chan error
 
 	var uploadAction *models.UploadAction
 	var uploader Uploader.Uploader
-	var tempDir string
 	var wardenClient *fake_warden_client.FakeClient
 	var logger *lagertest.TestLogger
 	var compressor Compressor.Compressor
 	var fakeStreamer *fake_log_streamer.FakeLogStreamer
-	var currentUser *user.User
 	var uploadTarget *httptest.Server
 	var uploadedPayload []byte
+	var uploadStatus int
 	var stdoutBuffer *bytes.Buffer
 	var stderrBuffer *bytes.Buffer
+	var uploaded int64
 
 	BeforeEach(func() {
-		var err error
-
-		result = make(chan error)
+		uploadStatus = http.StatusOK
 
 		uploadTarget = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 			var err error
@@ -79,7 +78,7 @@ var _ = Describe("UploadStep", func() {
 			uploadedPayload, err = ioutil.ReadAll(req.Body)
 			Ω(err).ShouldNot(HaveOccurred())
 
-			w.WriteHeader(http.StatusOK)
+			w.WriteHeader(uploadStatus)
 		}))
 
 		uploadAction = &models.UploadAction{
@@ -87,25 +86,25 @@ var _ = Describe("UploadStep", func() {
 			From: "/Antarctica",
 		}
 
-		tempDir, err = ioutil.TempDir("", "upload-step-tmpdir")
-		Ω(err).ShouldNot(HaveOccurred())
-
 		wardenClient = fake_warden_client.New()
 
 		logger = lagertest.NewTestLogger("test")
 
 		compressor = Compressor.NewTgz()
-		uploader = Uploader.New(5 * time.Second)
+		uploader = Uploader.New(5*time.Second, 1024*1024, Uploader.RetryPolicy{
+			MaxAttempts: 3,
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    10 * time.Millisecond,
+		})
 
 		fakeStreamer = new(fake_log_streamer.FakeLogStreamer)
 
-		currentUser, err = user.Current()
-		Ω(err).ShouldNot(HaveOccurred())
-
 		stdoutBuffer = new(bytes.Buffer)
 		stderrBuffer = new(bytes.Buffer)
 		fakeStreamer.StdoutReturns(stdoutBuffer)
 		fakeStreamer.StderrReturns(stderrBuffer)
+
+		uploaded = 0
 	})
 
 	AfterEach(func() {
@@ -125,8 +124,10 @@ var _ = Describe("UploadStep", func() {
 			*uploadAction,
 			uploader,
 			compressor,
-			tempDir,
 			fakeStreamer,
+			transfer_progress.New("Uploaded", stdoutBuffer, 0, func(transferred int64) {
+				uploaded += transferred
+			}),
 			logger,
 		)
 	})
@@ -140,63 +141,226 @@ var _ = Describe("UploadStep", func() {
 				wardenClient.Connection.StreamOutReturns(buffer, nil)
 			})
 
-			It("uploads a .tgz to the destination", func() {
-				wardenClient.Connection.StreamOutStub = func(handle, src string) (io.ReadCloser, error) {
-					Ω(handle).Should(Equal("some-container-handle"))
+			Context("when no format is given", func() {
+				It("uploads a .tgz to the destination", func() {
+					wardenClient.Connection.StreamOutStub = func(handle, src string) (io.ReadCloser, error) {
+						Ω(handle).Should(Equal("some-container-handle"))
+
+						if src == "/Antarctica" {
+							tarWriter := tar.NewWriter(buffer)
+
+							contents1 := "some-file-contents"
+
+							err := tarWriter.WriteHeader(&tar.Header{
+								Name: "some-file",
+								Size: int64(len(contents1)),
+							})
+							Ω(err).ShouldNot(HaveOccurred())
+
+							_, err = tarWriter.Write([]byte(contents1))
+							Ω(err).ShouldNot(HaveOccurred())
+
+							err = tarWriter.Flush()
+							Ω(err).ShouldNot(HaveOccurred())
+
+							return buffer, nil
+						}
+
+						return NewClosableBuffer(), nil
+					}
+
+					err := step.Perform(context.Background())
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(uploadedPayload).ShouldNot(BeZero())
+
+					Ω(buffer.IsClosed()).Should(BeTrue())
+
+					ungzip, err := gzip.NewReader(bytes.NewReader(uploadedPayload))
+					Ω(err).ShouldNot(HaveOccurred())
+
+					untar := tar.NewReader(ungzip)
+
+					tarContents := map[string][]byte{}
+					for {
+						hdr, err := untar.Next()
+						if err == io.EOF {
+							break
+						}
+
+						Ω(err).ShouldNot(HaveOccurred())
+
+						content, err := ioutil.ReadAll(untar)
+						Ω(err).ShouldNot(HaveOccurred())
+
+						tarContents[hdr.Name] = content
+					}
+
+					Ω(tarContents).Should(HaveKey("some-file"))
+
+					Ω(string(tarContents["some-file"])).Should(Equal("some-file-contents"))
+				})
+			})
+
+			Context("when the tar contains a single file", func() {
+				BeforeEach(func() {
+					writeTar(buffer, map[string]string{"some-file": "some-file-contents"})
+				})
+
+				Context("and the format is tar", func() {
+					BeforeEach(func() {
+						uploadAction.Format = models.UploadFormatTar
+					})
 
-					if src == "/Antarctica" {
-						tarWriter := tar.NewWriter(buffer)
+					It("uploads the tar as is", func() {
+						err := step.Perform(context.Background())
+						Ω(err).ShouldNot(HaveOccurred())
 
-						contents1 := "some-file-contents"
+						Ω(readTar(bytes.NewReader(uploadedPayload))).Should(Equal(map[string]string{
+							"some-file": "some-file-contents",
+						}))
+					})
 
-						err := tarWriter.WriteHeader(&tar.Header{
-							Name: "some-file",
-							Size: int64(len(contents1)),
+					Context("and compressing", func() {
+						BeforeEach(func() {
+							uploadAction.Compress = true
 						})
+
+						It("uploads a gzipped tar", func() {
+							err := step.Perform(context.Background())
+							Ω(err).ShouldNot(HaveOccurred())
+
+							ungzip, err := gzip.NewReader(bytes.NewReader(uploadedPayload))
+							Ω(err).ShouldNot(HaveOccurred())
+
+							Ω(readTar(ungzip)).Should(Equal(map[string]string{
+								"some-file": "some-file-contents",
+							}))
+						})
+					})
+				})
+
+				It("records the bytes uploaded", func() {
+					err := step.Perform(context.Background())
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(uploaded).Should(Equal(int64(len(uploadedPayload))))
+				})
+
+				Context("and the format is tgz", func() {
+					BeforeEach(func() {
+						uploadAction.Format = models.UploadFormatTgz
+					})
+
+					It("uploads a gzipped tar", func() {
+						err := step.Perform(context.Background())
 						Ω(err).ShouldNot(HaveOccurred())
 
-						_, err = tarWriter.Write([]byte(contents1))
+						ungzip, err := gzip.NewReader(bytes.NewReader(uploadedPayload))
 						Ω(err).ShouldNot(HaveOccurred())
 
-						err = tarWriter.Flush()
+						Ω(readTar(ungzip)).Should(Equal(map[string]string{
+							"some-file": "some-file-contents",
+						}))
+					})
+				})
+
+				Context("and the format is raw", func() {
+					BeforeEach(func() {
+						uploadAction.Format = models.UploadFormatRaw
+					})
+
+					It("uploads the contents of the file", func() {
+						err := step.Perform(context.Background())
 						Ω(err).ShouldNot(HaveOccurred())
 
-						return buffer, nil
-					}
+						Ω(string(uploadedPayload)).Should(Equal("some-file-contents"))
+					})
+
+					Context("and compressing", func() {
+						BeforeEach(func() {
+							uploadAction.Compress = true
+						})
 
-					return NewClosableBuffer(), nil
-				}
+						It("uploads the gzipped contents of the file", func() {
+							err := step.Perform(context.Background())
+							Ω(err).ShouldNot(HaveOccurred())
 
-				err := step.Perform()
-				Ω(err).ShouldNot(HaveOccurred())
+							ungzip, err := gzip.NewReader(bytes.NewReader(uploadedPayload))
+							Ω(err).ShouldNot(HaveOccurred())
 
-				Ω(uploadedPayload).ShouldNot(BeZero())
+							Ω(ioutil.ReadAll(ungzip)).Should(Equal([]byte("some-file-contents")))
+						})
+					})
+				})
 
-				Ω(buffer.IsClosed()).Should(BeTrue())
+				Context("and the format is zip", func() {
+					BeforeEach(func() {
+						uploadAction.Format = models.UploadFormatZip
+					})
 
-				ungzip, err := gzip.NewReader(bytes.NewReader(uploadedPayload))
-				Ω(err).ShouldNot(HaveOccurred())
+					It("uploads a zip of the files", func() {
+						err := step.Perform(context.Background())
+						Ω(err).ShouldNot(HaveOccurred())
 
-				untar := tar.NewReader(ungzip)
+						zipReader, err := zip.NewReader(bytes.NewReader(uploadedPayload), int64(len(uploadedPayload)))
+						Ω(err).ShouldNot(HaveOccurred())
 
-				tarContents := map[string][]byte{}
-				for {
-					hdr, err := untar.Next()
-					if err == io.EOF {
-						break
-					}
+						Ω(zipReader.File).Should(HaveLen(1))
+						Ω(zipReader.File[0].Name).Should(Equal("some-file"))
+
+						contents, err := zipReader.File[0].Open()
+						Ω(err).ShouldNot(HaveOccurred())
+						defer contents.Close()
+
+						Ω(ioutil.ReadAll(contents)).Should(Equal([]byte("some-file-contents")))
+					})
+				})
+			})
+
+			Context("when the format is zip and the tar names entries relative to ./", func() {
+				BeforeEach(func() {
+					uploadAction.Format = models.UploadFormatZip
+
+					tarWriter := tar.NewWriter(buffer)
 
+					err := tarWriter.WriteHeader(&tar.Header{Name: "./", Mode: 0755, Typeflag: tar.TypeDir})
 					Ω(err).ShouldNot(HaveOccurred())
 
-					content, err := ioutil.ReadAll(untar)
+					err = tarWriter.WriteHeader(&tar.Header{Name: "./some-file", Mode: 0644, Size: 4})
 					Ω(err).ShouldNot(HaveOccurred())
 
-					tarContents[hdr.Name] = content
-				}
+					_, err = tarWriter.Write([]byte("four"))
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(tarWriter.Close()).ShouldNot(HaveOccurred())
+				})
 
-				Ω(tarContents).Should(HaveKey("some-file"))
+				It("drops the ./ from the zip's entry names", func() {
+					err := step.Perform(context.Background())
+					Ω(err).ShouldNot(HaveOccurred())
 
-				Ω(string(tarContents["some-file"])).Should(Equal("some-file-contents"))
+					zipReader, err := zip.NewReader(bytes.NewReader(uploadedPayload), int64(len(uploadedPayload)))
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(zipReader.File).Should(HaveLen(1))
+					Ω(zipReader.File[0].Name).Should(Equal("some-file"))
+				})
+			})
+
+			Context("when the format is raw but the tar contains multiple files", func() {
+				BeforeEach(func() {
+					uploadAction.Format = models.UploadFormatRaw
+					writeTar(buffer, map[string]string{
+						"some-file":  "some-file-contents",
+						"other-file": "other-file-contents",
+					})
+				})
+
+				It("returns an error", func() {
+					err := step.Perform(context.Background())
+					Ω(err).Should(MatchError(emittable_error.New(ErrNotASingleFile, "Copying out of the container failed")))
+				})
 			})
 
 			Describe("streaming logs for uploads", func() {
@@ -207,20 +371,32 @@ var _ = Describe("UploadStep", func() {
 				})
 
 				It("streams the upload filesize", func() {
-					err := step.Perform()
+					err := step.Perform(context.Background())
 					Ω(err).ShouldNot(HaveOccurred())
 
 					Ω(stdoutBuffer.String()).Should(ContainSubstring("Uploaded (1K)"))
 				})
 
 				It("does not stream an error", func() {
-					err := step.Perform()
+					err := step.Perform(context.Background())
 					Ω(err).ShouldNot(HaveOccurred())
 
 					Ω(stderrBuffer.String()).Should(Equal(""))
 				})
 			})
 
+			Context("when the upload target keeps failing", func() {
+				BeforeEach(func() {
+					uploadStatus = http.StatusServiceUnavailable
+				})
+
+				It("returns an emittable error with the attempts and the last status", func() {
+					err := step.Perform(context.Background())
+					Ω(err).Should(BeAssignableToTypeOf(&emittable_error.EmittableError{}))
+					Ω(err.(*emittable_error.EmittableError).EmittableError()).Should(Equal("Uploading failed after 3 attempt(s), last status 503"))
+				})
+			})
+
 			Context("when there is an error uploading", func() {
 				BeforeEach(func() {
 					fakeUploader := new(fake_uploader.FakeUploader)
@@ -229,7 +405,7 @@ var _ = Describe("UploadStep", func() {
 				})
 
 				It("returns the error", func() {
-					err := step.Perform()
+					err := step.Perform(context.Background())
 					Ω(err).Should(HaveOccurred())
 				})
 			})
@@ -241,7 +417,7 @@ var _ = Describe("UploadStep", func() {
 			})
 
 			It("returns the error and loggregates a message to STDERR", func() {
-				err := step.Perform()
+				err := step.Perform(context.Background())
 				Ω(err).Should(HaveOccurred())
 			})
 		})
@@ -254,11 +430,34 @@ var _ = Describe("UploadStep", func() {
 			})
 
 			It("returns the error ", func() {
-				err := step.Perform()
+				err := step.Perform(context.Background())
 				Ω(err).Should(MatchError(emittable_error.New(disaster, "Copying out of the container failed")))
 			})
 		})
 
+		Context("when the context is cancelled while streaming out", func() {
+			var stream *blockingReader
+
+			BeforeEach(func() {
+				stream = newBlockingReader()
+				wardenClient.Connection.StreamOutReturns(stream, nil)
+			})
+
+			It("closes the stream and fails", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+
+				errs := make(chan error)
+				go func() { errs <- step.Perform(ctx) }()
+
+				Eventually(stream.reading).Should(Receive())
+
+				cancel()
+
+				Eventually(errs).Should(Receive(HaveOccurred()))
+				Ω(stream.closed).Should(BeClosed())
+			})
+		})
+
 		Context("when there is an error in the middle of streaming the data", func() {
 			disaster := errors.New("no room in the copy inn")
 
@@ -267,13 +466,45 @@ var _ = Describe("UploadStep", func() {
 			})
 
 			It("returns the error ", func() {
-				err := step.Perform()
+				err := step.Perform(context.Background())
 				Ω(err).Should(MatchError(emittable_error.New(disaster, "Copying out of the container failed")))
 			})
 		})
 	})
 })
 
+type blockingReader struct {
+	reading chan struct{}
+	closed  chan struct{}
+}
+
+func newBlockingReader() *blockingReader {
+	return &blockingReader{
+		reading: make(chan struct{}, 1),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (r *blockingReader) Read([]byte) (int, error) {
+	select {
+	case r.reading <- struct{}{}:
+	default:
+	}
+
+	<-r.closed
+	return 0, errors.New("read on closed stream")
+}
+
+func (r *blockingReader) Close() error {
+	select {
+	case <-r.closed:
+	default:
+		close(r.closed)
+	}
+
+	return nil
+}
+
 type errorReader struct {
 	err error
 }
@@ -285,3 +516,58 @@ func (r *errorReader) Read([]byte) (int, error) {
 func (r *errorReader) Close() error {
 	return nil
 }
+
+var _ = Describe("ValidateFormat", func() {
+	It("accepts no format, raw, tar, tgz and zip", func() {
+		for _, format := range []string{"", models.UploadFormatRaw, models.UploadFormatTar, models.UploadFormatTgz, models.UploadFormatZip} {
+			Ω(ValidateFormat(models.UploadAction{Format: format})).ShouldNot(HaveOccurred())
+		}
+	})
+
+	It("rejects unknown formats", func() {
+		Ω(ValidateFormat(models.UploadAction{Format: "rar"})).Should(HaveOccurred())
+	})
+
+	It("rejects compressing a zip", func() {
+		Ω(ValidateFormat(models.UploadAction{Format: models.UploadFormatZip, Compress: true})).Should(Equal(ErrCompressedZip))
+	})
+})
+
+func writeTar(destination io.Writer, files map[string]string) {
+	tarWriter := tar.NewWriter(destination)
+
+	for name, contents := range files {
+		err := tarWriter.WriteHeader(&tar.Header{
+			Name: name,
+			Mode: 0644,
+			Size: int64(len(contents)),
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, err = tarWriter.Write([]byte(contents))
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+
+	err := tarWriter.Close()
+	Ω(err).ShouldNot(HaveOccurred())
+}
+
+func readTar(source io.Reader) map[string]string {
+	tarReader := tar.NewReader(source)
+
+	files := map[string]string{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		Ω(err).ShouldNot(HaveOccurred())
+
+		contents, err := ioutil.ReadAll(tarReader)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		files[header.Name] = string(contents)
+	}
+
+	return files
+}
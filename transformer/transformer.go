@@ -6,72 +6,138 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
+	"sync"
+	"time"
 
 	"github.com/cloudfoundry-incubator/executor/api"
 	"github.com/cloudfoundry-incubator/executor/log_streamer"
+	"github.com/cloudfoundry-incubator/executor/redaction"
 	"github.com/cloudfoundry-incubator/executor/sequence"
 	"github.com/cloudfoundry-incubator/executor/steps/download_step"
 	"github.com/cloudfoundry-incubator/executor/steps/emit_progress_step"
 	"github.com/cloudfoundry-incubator/executor/steps/fetch_result_step"
+	"github.com/cloudfoundry-incubator/executor/steps/http_check_step"
 	"github.com/cloudfoundry-incubator/executor/steps/monitor_step"
 	"github.com/cloudfoundry-incubator/executor/steps/parallel_step"
 	"github.com/cloudfoundry-incubator/executor/steps/run_step"
+	"github.com/cloudfoundry-incubator/executor/steps/tcp_check_step"
+	"github.com/cloudfoundry-incubator/executor/steps/transfer_progress"
 	"github.com/cloudfoundry-incubator/executor/steps/try_step"
 	"github.com/cloudfoundry-incubator/executor/steps/upload_step"
 	"github.com/cloudfoundry-incubator/executor/uploader"
 	"github.com/cloudfoundry-incubator/garden/warden"
 	"github.com/cloudfoundry-incubator/runtime-schema/models"
-	"github.com/cloudfoundry/loggregatorlib/emitter"
+	"github.com/cloudfoundry/gunk/timeprovider"
 	"github.com/pivotal-golang/archiver/compressor"
-	"github.com/pivotal-golang/archiver/extractor"
 	"github.com/pivotal-golang/cacheddownloader"
 	"github.com/pivotal-golang/lager"
 )
 
 var ErrNoCheck = errors.New("no check configured")
 
+// Timings are how long steps wait between polls and retries. Any left zero
+// is the step's own default.
+type Timings struct {
+	EventPollInterval time.Duration
+	StopWaitTimeout   time.Duration
+	HookRetryInterval time.Duration
+}
+
 type Transformer struct {
-	logEmitter       emitter.Emitter
+	logSink          log_streamer.Sink
 	cachedDownloader cacheddownloader.CachedDownloader
 	uploader         uploader.Uploader
-	extractor        extractor.Extractor
 	compressor       compressor.Compressor
 	logger           lager.Logger
 	tempDir          string
-	result           *string
+	maxSpoolSize     int64
+	progressInterval time.Duration
+	maxResultSize    int64
+	logRateLimit     log_streamer.RateLimit
+	timings          Timings
 }
 
 func NewTransformer(
-	logEmitter emitter.Emitter,
+	logSink log_streamer.Sink,
 	cachedDownloader cacheddownloader.CachedDownloader,
 	uploader uploader.Uploader,
-	extractor extractor.Extractor,
 	compressor compressor.Compressor,
 	logger lager.Logger,
 	tempDir string,
+	maxSpoolSize int64,
+	progressInterval time.Duration,
+	maxResultSize int64,
+	logRateLimit log_streamer.RateLimit,
+	timings Timings,
 ) *Transformer {
+	if timings.EventPollInterval <= 0 {
+		timings.EventPollInterval = run_step.EventPollInterval
+	}
+
 	return &Transformer{
-		logEmitter:       logEmitter,
+		logSink:          logSink,
 		cachedDownloader: cachedDownloader,
 		uploader:         uploader,
-		extractor:        extractor,
 		compressor:       compressor,
 		logger:           logger,
 		tempDir:          tempDir,
+		maxSpoolSize:     maxSpoolSize,
+		progressInterval: progressInterval,
+		maxResultSize:    maxResultSize,
+		logRateLimit:     logRateLimit,
+		timings:          timings,
 	}
 }
 
-func (transformer *Transformer) StepsFor(
-	logConfig api.LogConfig,
-	actions []models.ExecutorAction,
-	globalEnv []api.EnvironmentVariable,
-	container warden.Container,
-	result *string,
-) ([]sequence.Step, error) {
+// Run is what the steps of one run of a container share.
+type Run struct {
+	Guid      string
+	LogConfig api.LogConfig
+	Env       []api.EnvironmentVariable
+	Container warden.Container
+	Results   *fetch_result_step.Results
+
+	// Progress is the root the steps' progress is recorded under.
+	Progress *sequence.Progress
+
+	RecordTransfer    func(api.TransferProgress)
+	RecordHealth      func(api.Health)
+	RecordLogsDropped func(lines int)
+	RecordExitStatus  func(int)
+	RecordEvents      func([]string)
+
+	// masks secrets in what the steps log
+	Redactor *redaction.Redactor
+
+	// shared by every step's log streamer
+	logLimiter   *log_streamer.RateLimiter
+	logMultiLine *log_streamer.MultiLine
+
+	// set for the actions inside an emit_progress action
+	recordExitStatus func(int)
+	timestamps       bool
+
+	// set for the check of a monitor action, which is run over and over
+	untracked bool
+}
+
+func (transformer *Transformer) StepsFor(run Run, actions []models.ExecutorAction) ([]sequence.Step, error) {
 	subSteps := []sequence.Step{}
 
+	multiLine, err := log_streamer.MultiLineFor(run.LogConfig.MultiLine)
+	if err != nil {
+		return nil, err
+	}
+
+	run.logMultiLine = multiLine
+	run.recordExitStatus = run.RecordExitStatus
+
+	if transformer.logRateLimit.Enabled() {
+		run.logLimiter = log_streamer.NewRateLimiter(transformer.logRateLimit, timeprovider.NewTimeProvider(), run.RecordLogsDropped)
+	}
+
 	for _, a := range actions {
-		step, err := transformer.convertAction(logConfig, a, globalEnv, container, result)
+		step, err := transformer.convertAction(run, a, run.Progress)
 		if err != nil {
 			return nil, err
 		}
@@ -82,14 +148,53 @@ func (transformer *Transformer) StepsFor(
 	return subSteps, nil
 }
 
-func (transformer *Transformer) convertAction(
-	logConfig api.LogConfig,
-	action models.ExecutorAction,
-	globalEnv []api.EnvironmentVariable,
-	container warden.Container,
-	result *string,
-) (sequence.Step, error) {
-	logStreamer := log_streamer.New(logConfig.Guid, logConfig.SourceName, logConfig.Index, transformer.logEmitter)
+// ReleaseContainer lets the log sinks let go of a deleted container's logs.
+func (transformer *Transformer) ReleaseContainer(guid string) {
+	log_streamer.ReleaseContainer(transformer.logSink, guid)
+}
+
+func (transformer *Transformer) convertAction(run Run, action models.ExecutorAction, parent *sequence.Progress) (sequence.Step, error) {
+	if run.untracked {
+		return transformer.buildStep(run, action, parent.Detached(action.Name()))
+	}
+
+	progress := parent.Child(action.Name())
+
+	step, err := transformer.buildStep(run, action, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	return sequence.Track(step, progress), nil
+}
+
+func (transformer *Transformer) buildStep(run Run, action models.ExecutorAction, progress *sequence.Progress) (sequence.Step, error) {
+	logConfig := run.LogConfig
+	container := run.Container
+
+	// an emit_progress action timestamps its own messages too
+	emitProgress, ok := action.Action.(models.EmitProgressAction)
+	if ok && emitProgress.Timestamps {
+		run.timestamps = true
+	}
+
+	var timestamps timeprovider.TimeProvider
+	if run.timestamps {
+		timestamps = timeprovider.NewTimeProvider()
+	}
+
+	logStreamer := log_streamer.NewWithOptions(logConfig.Guid, logConfig.SourceName, logConfig.Index, transformer.logSink, log_streamer.Options{
+		Limiter:    run.logLimiter,
+		MultiLine:  run.logMultiLine,
+		Timestamps: timestamps,
+		Redactor:   run.Redactor,
+		Metadata: log_streamer.Metadata{
+			ContainerGuid: run.Guid,
+			Handle:        container.Handle(),
+			Step:          progress.Path(),
+			Action:        action.Name(),
+		},
+	})
 
 	sessionName := reflect.TypeOf(action.Action).Name()
 	stepLogger := transformer.logger.Session(sessionName, lager.Data{
@@ -99,7 +204,7 @@ func (transformer *Transformer) convertAction(
 	switch actionModel := action.Action.(type) {
 	case models.RunAction:
 		var runEnv []models.EnvironmentVariable
-		for _, e := range globalEnv {
+		for _, e := range run.Env {
 			runEnv = append(runEnv, models.EnvironmentVariable{
 				Name:  e.Name,
 				Value: e.Value,
@@ -112,128 +217,171 @@ func (transformer *Transformer) convertAction(
 			container,
 			actionModel,
 			logStreamer,
+			run.recordExitStatus,
+			run.RecordEvents,
+			transformer.timings.EventPollInterval,
+			transformer.timings.StopWaitTimeout,
 			stepLogger,
 		), nil
 	case models.DownloadAction:
+		err := download_step.ValidateChecksum(actionModel)
+		if err != nil {
+			return nil, err
+		}
+
 		return download_step.New(
 			container,
 			actionModel,
 			transformer.cachedDownloader,
-			transformer.extractor,
 			transformer.tempDir,
+			transformer.maxSpoolSize,
+			transfer_progress.New("Downloaded", logStreamer.Stdout(), transformer.progressInterval, func(transferred int64) {
+				run.RecordTransfer(api.TransferProgress{BytesDownloaded: transferred})
+			}),
 			stepLogger,
 		), nil
 	case models.UploadAction:
+		err := upload_step.ValidateFormat(actionModel)
+		if err != nil {
+			return nil, err
+		}
+
 		return upload_step.New(
 			container,
 			actionModel,
 			transformer.uploader,
 			transformer.compressor,
-			transformer.tempDir,
 			logStreamer,
+			transfer_progress.New("Uploaded", logStreamer.Stdout(), transformer.progressInterval, func(transferred int64) {
+				run.RecordTransfer(api.TransferProgress{BytesUploaded: transferred})
+			}),
 			stepLogger,
 		), nil
 	case models.FetchResultAction:
+		err := fetch_result_step.ValidateFormat(actionModel)
+		if err != nil {
+			return nil, err
+		}
+
 		return fetch_result_step.New(
 			container,
 			actionModel,
 			transformer.tempDir,
+			transformer.maxResultSize,
 			stepLogger,
-			result,
+			run.Results,
 		), nil
 	case models.EmitProgressAction:
-		subStep, err := transformer.convertAction(
-			logConfig,
-			actionModel.Action,
-			globalEnv,
-			container,
-			result,
-		)
+		err := emit_progress_step.ValidateMessages(actionModel)
+		if err != nil {
+			return nil, err
+		}
+
+		stats := emit_progress_step.NewStats()
+
+		subRun := run
+		subRun.RecordTransfer = func(transferred api.TransferProgress) {
+			stats.RecordTransfer(transferred.BytesDownloaded, transferred.BytesUploaded)
+			run.RecordTransfer(transferred)
+		}
+		subRun.recordExitStatus = func(exitStatus int) {
+			stats.RecordExitStatus(exitStatus)
+			if run.recordExitStatus != nil {
+				run.recordExitStatus(exitStatus)
+			}
+		}
+
+		subStep, err := transformer.convertAction(subRun, actionModel.Action, progress)
 		if err != nil {
 			return nil, err
 		}
 
 		return emit_progress_step.New(
 			subStep,
-			actionModel.StartMessage,
-			actionModel.SuccessMessage,
-			actionModel.FailureMessage,
+			actionModel,
+			stats,
 			logStreamer,
 			stepLogger,
 		), nil
 	case models.TryAction:
-		subStep, err := transformer.convertAction(
-			logConfig,
-			actionModel.Action,
-			globalEnv,
-			container,
-			result,
-		)
+		subStep, err := transformer.convertAction(run, actionModel.Action, progress)
 		if err != nil {
 			return nil, err
 		}
 
 		return try_step.New(subStep, stepLogger), nil
 	case models.MonitorAction:
-		var healthyHook *http.Request
-		var unhealthyHook *http.Request
+		var healthyHook *monitor_step.Hook
+		var unhealthyHook *monitor_step.Hook
+		var err error
 
 		if actionModel.HealthyHook.URL != "" {
-			healthyHookURL, err := url.ParseRequestURI(actionModel.HealthyHook.URL)
+			healthyHook, err = convertHealthRequest(actionModel.HealthyHook, transformer.timings.HookRetryInterval)
 			if err != nil {
 				return nil, err
 			}
-
-			healthyHook = &http.Request{
-				Method: actionModel.HealthyHook.Method,
-				URL:    healthyHookURL,
-			}
 		}
 
 		if actionModel.UnhealthyHook.URL != "" {
-			unhealthyHookURL, err := url.ParseRequestURI(actionModel.UnhealthyHook.URL)
+			unhealthyHook, err = convertHealthRequest(actionModel.UnhealthyHook, transformer.timings.HookRetryInterval)
 			if err != nil {
 				return nil, err
 			}
-
-			unhealthyHook = &http.Request{
-				Method: actionModel.UnhealthyHook.Method,
-				URL:    unhealthyHookURL,
-			}
 		}
 
-		check, err := transformer.convertAction(
-			logConfig,
-			actionModel.Action,
-			globalEnv,
-			container,
-			result,
-		)
+		// the check's processes are probes, not what the run ran, and its
+		// progress would change with every check
+		checkRun := run
+		checkRun.recordExitStatus = nil
+		checkRun.untracked = true
+
+		check, err := transformer.convertAction(checkRun, actionModel.Action, progress)
 		if err != nil {
 			return nil, err
 		}
 
 		return monitor_step.New(
 			check,
+			run.Guid,
 			actionModel.HealthyThreshold,
 			actionModel.UnhealthyThreshold,
+			actionModel.InitialInterval,
+			actionModel.MaxInterval,
+			actionModel.CheckTimeout,
 			healthyHook,
 			unhealthyHook,
+			func(status monitor_step.Status) {
+				run.RecordHealth(convertHealthStatus(status, run.Redactor))
+			},
+			logStreamer,
 			stepLogger,
 			monitor_step.NewTimer(),
 		), nil
+	case models.TCPCheckAction:
+		err := tcp_check_step.Validate(actionModel)
+		if err != nil {
+			return nil, err
+		}
+
+		return tcp_check_step.New(container, actionModel, stepLogger), nil
+	case models.HTTPCheckAction:
+		err := http_check_step.Validate(actionModel)
+		if err != nil {
+			return nil, err
+		}
+
+		return http_check_step.New(container, actionModel, stepLogger), nil
 	case models.ParallelAction:
+		branchRun := run
+		if run.recordExitStatus != nil {
+			branchRun.recordExitStatus = firstFailedExitStatus(run.recordExitStatus)
+		}
+
 		steps := make([]sequence.Step, len(actionModel.Actions))
 		for i, action := range actionModel.Actions {
 			var err error
 
-			steps[i], err = transformer.convertAction(
-				logConfig,
-				action,
-				globalEnv,
-				container,
-				result,
-			)
+			steps[i], err = transformer.convertAction(branchRun, action, progress)
 			if err != nil {
 				return nil, err
 			}
@@ -244,3 +392,75 @@ func (transformer *Transformer) convertAction(
 
 	panic(fmt.Sprintf("unknown action: %T", action))
 }
+
+// firstFailedExitStatus passes exit statuses on to record until one is
+// non-zero, so of parallel branches the first to fail decides the status.
+func firstFailedExitStatus(record func(int)) func(int) {
+	var lock sync.Mutex
+	failed := false
+
+	return func(exitStatus int) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if failed {
+			return
+		}
+
+		failed = exitStatus != 0
+		record(exitStatus)
+	}
+}
+
+func convertHealthRequest(request models.HealthRequest, retryInterval time.Duration) (*monitor_step.Hook, error) {
+	hookURL, err := url.ParseRequestURI(request.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := http.Header{}
+	for name, value := range request.Headers {
+		headers.Set(name, value)
+	}
+
+	hook := &monitor_step.Hook{
+		Method:  request.Method,
+		URL:     hookURL,
+		Headers: headers,
+		Timeout: request.Timeout,
+		Retries: request.Retries,
+
+		RetryInterval: retryInterval,
+	}
+
+	if request.Body != "" {
+		hook.Body, err = monitor_step.ParseBody(request.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return hook, nil
+}
+
+func convertHealthStatus(status monitor_step.Status, redactor *redaction.Redactor) api.Health {
+	health := api.Health{
+		Status:         api.HealthUnhealthy,
+		HealthyCount:   status.HealthyCount,
+		UnhealthyCount: status.UnhealthyCount,
+	}
+
+	if status.Healthy {
+		health.Status = api.HealthHealthy
+	}
+
+	if status.CheckError != nil {
+		health.LastCheckError = redactor.Redact(status.CheckError.Error())
+	}
+
+	if status.HookError != nil {
+		health.LastHookError = redactor.Redact(status.HookError.Error())
+	}
+
+	return health
+}
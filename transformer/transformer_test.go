@@ -0,0 +1,284 @@
+package transformer_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden/client/fake_warden_client"
+	"github.com/cloudfoundry-incubator/garden/warden"
+	wfakes "github.com/cloudfoundry-incubator/garden/warden/fakes"
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/cloudfoundry/gunk/timeprovider"
+	"github.com/pivotal-golang/archiver/compressor/fake_compressor"
+	"github.com/pivotal-golang/cacheddownloader/fakecacheddownloader"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-incubator/executor/api"
+	"github.com/cloudfoundry-incubator/executor/log_streamer"
+	"github.com/cloudfoundry-incubator/executor/redaction"
+	"github.com/cloudfoundry-incubator/executor/sequence"
+	"github.com/cloudfoundry-incubator/executor/steps/container_address"
+	. "github.com/cloudfoundry-incubator/executor/transformer"
+	"github.com/cloudfoundry-incubator/executor/uploader/fake_uploader"
+)
+
+var _ = Describe("Transformer", func() {
+	var (
+		transformer  *Transformer
+		wardenClient *fake_warden_client.FakeClient
+		run          Run
+
+		lock         sync.Mutex
+		exitStatuses []int
+		healths      []api.Health
+		snapshots    int
+	)
+
+	recordedExitStatuses := func() []int {
+		lock.Lock()
+		defer lock.Unlock()
+
+		return append([]int{}, exitStatuses...)
+	}
+
+	recordedHealths := func() []api.Health {
+		lock.Lock()
+		defer lock.Unlock()
+
+		return append([]api.Health{}, healths...)
+	}
+
+	recordedSnapshots := func() int {
+		lock.Lock()
+		defer lock.Unlock()
+
+		return snapshots
+	}
+
+	processExitingWith := func(exitStatus int) *wfakes.FakeProcess {
+		process := new(wfakes.FakeProcess)
+		process.WaitReturns(exitStatus, nil)
+		return process
+	}
+
+	BeforeEach(func() {
+		exitStatuses = nil
+		healths = nil
+		snapshots = 0
+
+		transformer = NewTransformer(
+			log_streamer.NewMultiSink(),
+			fakecacheddownloader.New(),
+			&fake_uploader.FakeUploader{},
+			&fake_compressor.FakeCompressor{},
+			lagertest.NewTestLogger("test"),
+			"/tmp",
+			0,
+			0,
+			1024,
+			log_streamer.RateLimit{},
+			Timings{},
+		)
+
+		wardenClient = fake_warden_client.New()
+		wardenClient.Connection.CreateReturns("some-handle", nil)
+
+		container, err := wardenClient.Create(warden.ContainerSpec{})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		run = Run{
+			Guid:      "some-guid",
+			Container: container,
+
+			Progress: sequence.NewProgress(timeprovider.NewTimeProvider(), func([]api.StepProgress) {
+				lock.Lock()
+				defer lock.Unlock()
+
+				snapshots++
+			}),
+
+			RecordTransfer:    func(api.TransferProgress) {},
+			RecordLogsDropped: func(int) {},
+			RecordEvents:      func([]string) {},
+
+			RecordHealth: func(health api.Health) {
+				lock.Lock()
+				defer lock.Unlock()
+
+				healths = append(healths, health)
+			},
+
+			RecordExitStatus: func(exitStatus int) {
+				lock.Lock()
+				defer lock.Unlock()
+
+				exitStatuses = append(exitStatuses, exitStatus)
+			},
+		}
+	})
+
+	Describe("a monitor whose check runs a process", func() {
+		var step sequence.Step
+
+		BeforeEach(func() {
+			wardenClient.Connection.RunReturns(processExitingWith(1), nil)
+
+			steps, err := transformer.StepsFor(run, []models.ExecutorAction{
+				{
+					Action: models.MonitorAction{
+						Action: models.ExecutorAction{
+							Action: models.RunAction{Path: "check"},
+						},
+						HealthyThreshold:   1,
+						UnhealthyThreshold: 1,
+						InitialInterval:    time.Millisecond,
+					},
+				},
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(steps).Should(HaveLen(1))
+
+			step = steps[0]
+		})
+
+		It("does not record the check's exit status as the run's", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+
+			done := make(chan error, 1)
+			go func() {
+				done <- step.Perform(ctx)
+			}()
+
+			Eventually(recordedHealths).ShouldNot(BeEmpty())
+
+			cancel()
+			Eventually(done).Should(Receive())
+
+			Ω(recordedHealths()[0].Status).Should(Equal(api.HealthUnhealthy))
+			Ω(recordedExitStatuses()).Should(BeEmpty())
+		})
+
+		It("does not track the check's progress", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+
+			done := make(chan error, 1)
+			go func() {
+				done <- step.Perform(ctx)
+			}()
+
+			Eventually(func() int {
+				return len(recordedHealths())
+			}).Should(BeNumerically(">=", 3))
+
+			Ω(recordedSnapshots()).Should(Equal(1))
+			Ω(run.Progress.Steps()).Should(HaveLen(1))
+			Ω(run.Progress.Steps()[0].Steps).Should(BeEmpty())
+
+			cancel()
+			Eventually(done).Should(Receive())
+		})
+	})
+
+	Describe("a monitor whose check fails with a secret in its error", func() {
+		BeforeEach(func() {
+			run.Redactor = redaction.New([]string{"hunter2"})
+			wardenClient.Connection.InfoReturns(warden.ContainerInfo{}, errors.New("no info for hunter2"))
+		})
+
+		It("masks the secret in the recorded health", func() {
+			steps, err := transformer.StepsFor(run, []models.ExecutorAction{
+				{
+					Action: models.MonitorAction{
+						Action: models.ExecutorAction{
+							Action: models.TCPCheckAction{Port: 8080},
+						},
+						HealthyThreshold:   1,
+						UnhealthyThreshold: 1,
+						InitialInterval:    time.Millisecond,
+					},
+				},
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			ctx, cancel := context.WithCancel(context.Background())
+
+			done := make(chan error, 1)
+			go func() {
+				done <- steps[0].Perform(ctx)
+			}()
+
+			Eventually(recordedHealths).ShouldNot(BeEmpty())
+
+			cancel()
+			Eventually(done).Should(Receive())
+
+			Ω(recordedHealths()[0].LastCheckError).Should(Equal("no info for " + redaction.Mask))
+		})
+	})
+
+	Describe("checks with a port out of range", func() {
+		It("are rejected", func() {
+			_, err := transformer.StepsFor(run, []models.ExecutorAction{
+				{Action: models.TCPCheckAction{Port: 0}},
+			})
+			Ω(err).Should(Equal(container_address.ErrInvalidPort))
+
+			_, err = transformer.StepsFor(run, []models.ExecutorAction{
+				{Action: models.HTTPCheckAction{Port: 65536}},
+			})
+			Ω(err).Should(Equal(container_address.ErrInvalidPort))
+		})
+	})
+
+	Describe("parallel processes", func() {
+		var failedExited chan struct{}
+
+		BeforeEach(func() {
+			failedExited = make(chan struct{})
+
+			// the succeeding branch only exits once the failing one has
+			succeeding := new(wfakes.FakeProcess)
+			succeeding.WaitStub = func() (int, error) {
+				<-failedExited
+				return 0, nil
+			}
+
+			failing := new(wfakes.FakeProcess)
+			failing.WaitStub = func() (int, error) {
+				defer close(failedExited)
+				return 2, nil
+			}
+
+			wardenClient.Connection.RunStub = func(handle string, spec warden.ProcessSpec, io warden.ProcessIO) (warden.Process, error) {
+				if spec.Path == "fail" {
+					return failing, nil
+				}
+
+				return succeeding, nil
+			}
+		})
+
+		It("records the status of the first to exit non-zero", func() {
+			steps, err := transformer.StepsFor(run, []models.ExecutorAction{
+				{
+					Action: models.ParallelAction{
+						Actions: []models.ExecutorAction{
+							{Action: models.RunAction{Path: "succeed"}},
+							{Action: models.RunAction{Path: "fail"}},
+						},
+					},
+				},
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			steps[0].Perform(context.Background())
+
+			Ω(recordedExitStatuses()).Should(Equal([]int{2}))
+		})
+	})
+})
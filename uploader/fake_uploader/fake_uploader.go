@@ -2,18 +2,22 @@
 package fake_uploader
 
 import (
+	"context"
+
 	"github.com/cloudfoundry-incubator/executor/uploader"
 	"github.com/pivotal-golang/lager"
 
+	"io"
 	"net/url"
 	"sync"
 )
 
 type FakeUploader struct {
-	UploadStub        func(fileLocation string, destinationUrl *url.URL, logger lager.Logger) (int64, error)
+	UploadStub        func(ctx context.Context, source io.Reader, destinationUrl *url.URL, logger lager.Logger) (int64, error)
 	uploadMutex       sync.RWMutex
 	uploadArgsForCall []struct {
-		fileLocation   string
+		ctx            context.Context
+		source         io.Reader
 		destinationUrl *url.URL
 		logger         lager.Logger
 	}
@@ -23,16 +27,17 @@ type FakeUploader struct {
 	}
 }
 
-func (fake *FakeUploader) Upload(fileLocation string, destinationUrl *url.URL, logger lager.Logger) (int64, error) {
+func (fake *FakeUploader) Upload(ctx context.Context, source io.Reader, destinationUrl *url.URL, logger lager.Logger) (int64, error) {
 	fake.uploadMutex.Lock()
 	defer fake.uploadMutex.Unlock()
 	fake.uploadArgsForCall = append(fake.uploadArgsForCall, struct {
-		fileLocation   string
+		ctx            context.Context
+		source         io.Reader
 		destinationUrl *url.URL
 		logger         lager.Logger
-	}{fileLocation, destinationUrl, logger})
+	}{ctx, source, destinationUrl, logger})
 	if fake.UploadStub != nil {
-		return fake.UploadStub(fileLocation, destinationUrl, logger)
+		return fake.UploadStub(ctx, source, destinationUrl, logger)
 	} else {
 		return fake.uploadReturns.result1, fake.uploadReturns.result2
 	}
@@ -44,10 +49,10 @@ func (fake *FakeUploader) UploadCallCount() int {
 	return len(fake.uploadArgsForCall)
 }
 
-func (fake *FakeUploader) UploadArgsForCall(i int) (string, *url.URL, lager.Logger) {
+func (fake *FakeUploader) UploadArgsForCall(i int) (context.Context, io.Reader, *url.URL, lager.Logger) {
 	fake.uploadMutex.RLock()
 	defer fake.uploadMutex.RUnlock()
-	return fake.uploadArgsForCall[i].fileLocation, fake.uploadArgsForCall[i].destinationUrl, fake.uploadArgsForCall[i].logger
+	return fake.uploadArgsForCall[i].ctx, fake.uploadArgsForCall[i].source, fake.uploadArgsForCall[i].destinationUrl, fake.uploadArgsForCall[i].logger
 }
 
 func (fake *FakeUploader) UploadReturns(result1 int64, result2 error) {
@@ -0,0 +1,76 @@
+package uploader
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry-incubator/executor/cancellable"
+	"github.com/cloudfoundry-incubator/executor/file_scheme"
+	"github.com/pivotal-golang/lager"
+)
+
+var ErrMissingPath = file_scheme.ErrMissingPath
+var ErrOutsideRoot = file_scheme.ErrOutsideRoot
+
+// FileUploader writes uploads to file:// URLs within root, e.g. on a volume
+// shared with whoever consumes them. The file only appears once it is
+// complete.
+type FileUploader struct {
+	root string
+}
+
+func NewFileUploader(root string) Uploader {
+	return &FileUploader{
+		root: root,
+	}
+}
+
+func (uploader *FileUploader) Upload(ctx context.Context, source io.Reader, url *url.URL, logger lager.Logger) (int64, error) {
+	destination, err := file_scheme.Resolve(uploader.root, url)
+	if err != nil {
+		return 0, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(destination), 0755)
+	if err != nil {
+		return 0, err
+	}
+
+	tempFile, err := ioutil.TempFile(filepath.Dir(destination), ".upload")
+	if err != nil {
+		return 0, err
+	}
+
+	numBytes, err := io.Copy(tempFile, cancellable.Reader(ctx, source))
+	if err == nil {
+		err = tempFile.Close()
+	} else {
+		tempFile.Close()
+	}
+
+	if err == nil {
+		err = os.Chmod(tempFile.Name(), 0644)
+	}
+
+	if err == nil {
+		err = os.Rename(tempFile.Name(), destination)
+	}
+
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return 0, err
+	}
+
+	if logger != nil {
+		logger.Info("uploader.wrote-file", lager.Data{
+			"path":  destination,
+			"bytes": numBytes,
+		})
+	}
+
+	return numBytes, nil
+}
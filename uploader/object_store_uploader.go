@@ -0,0 +1,236 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/cloudfoundry-incubator/executor/object_store"
+	"github.com/pivotal-golang/lager"
+)
+
+// MinPartSize is the smallest part S3 accepts in a multipart upload, other
+// than the last.
+const MinPartSize = 5 * 1024 * 1024
+
+// ObjectStoreUploader puts uploads to s3:// URLs into an S3-compatible
+// object store. Anything larger than a chunk becomes a multipart upload, one
+// part per chunk, so it is streamed just like uploads over HTTP. Chunks are
+// never smaller than MinPartSize.
+type ObjectStoreUploader struct {
+	httpClient  *http.Client
+	store       *object_store.ObjectStore
+	chunkSize   int64
+	retryPolicy RetryPolicy
+}
+
+type initiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+type completedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+type objectStoreError struct {
+	XMLName xml.Name
+	Code    string
+	Message string
+}
+
+type objectStoreResponse struct {
+	header http.Header
+	body   []byte
+}
+
+func NewObjectStoreUploader(store *object_store.ObjectStore, timeout time.Duration, chunkSize int64, retryPolicy RetryPolicy) Uploader {
+	return &ObjectStoreUploader{
+		httpClient:  newHTTPClient(timeout),
+		store:       store,
+		chunkSize:   normalizePartSize(chunkSize),
+		retryPolicy: retryPolicy.normalize(),
+	}
+}
+
+func normalizePartSize(chunkSize int64) int64 {
+	chunkSize = normalizeChunkSize(chunkSize)
+	if chunkSize < MinPartSize {
+		return MinPartSize
+	}
+
+	return chunkSize
+}
+
+func (uploader *ObjectStoreUploader) Upload(ctx context.Context, source io.Reader, url *url.URL, logger lager.Logger) (int64, error) {
+	chunk := make([]byte, uploader.chunkSize)
+
+	n, err := io.ReadFull(source, chunk)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		_, err := uploader.send(ctx, "PUT", url, nil, chunk[:n], logger)
+		if err != nil {
+			return 0, err
+		}
+
+		return int64(n), nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	response, err := uploader.send(ctx, "POST", url, queryOf("uploads", ""), nil, logger)
+	if err != nil {
+		return 0, err
+	}
+
+	initiated := initiateMultipartUploadResult{}
+
+	err = xml.Unmarshal(response.body, &initiated)
+	if err != nil {
+		return 0, err
+	}
+
+	numBytes, err := uploader.uploadParts(ctx, source, chunk, n, url, initiated.UploadID, logger)
+	if err != nil {
+		// the parts already stored are only dropped once the upload is
+		// aborted, so do that even if the upload was cancelled
+		uploader.send(context.Background(), "DELETE", url, queryOf("uploadId", initiated.UploadID), nil, logger)
+		return 0, err
+	}
+
+	return numBytes, nil
+}
+
+func (uploader *ObjectStoreUploader) uploadParts(
+	ctx context.Context,
+	source io.Reader,
+	chunk []byte,
+	n int,
+	url *url.URL,
+	uploadID string,
+	logger lager.Logger,
+) (int64, error) {
+	completed := completeMultipartUpload{}
+
+	var numBytes int64
+
+	for partNumber := 1; ; partNumber++ {
+		query := queryOf("uploadId", uploadID)
+		query.Set("partNumber", strconv.Itoa(partNumber))
+
+		response, err := uploader.send(ctx, "PUT", url, query, chunk[:n], logger)
+		if err != nil {
+			return 0, err
+		}
+
+		completed.Parts = append(completed.Parts, completedPart{
+			PartNumber: partNumber,
+			ETag:       response.header.Get("ETag"),
+		})
+
+		numBytes += int64(n)
+
+		n, err = io.ReadFull(source, chunk)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+	}
+
+	body, err := xml.Marshal(completed)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = uploader.send(ctx, "POST", url, queryOf("uploadId", uploadID), body, logger)
+	if err != nil {
+		return 0, err
+	}
+
+	return numBytes, nil
+}
+
+func (uploader *ObjectStoreUploader) send(ctx context.Context, method string, objectURL *url.URL, query url.Values, body []byte, logger lager.Logger) (*objectStoreResponse, error) {
+	requestURL := *objectURL
+	requestURL.RawQuery = query.Encode()
+
+	var response *objectStoreResponse
+
+	err := withRetries(ctx, uploader.retryPolicy, logger, lager.Data{"method": method, "url": requestURL.String()}, func() (int, time.Duration, error) {
+		var statusCode int
+		var retryAfter time.Duration
+		var err error
+
+		statusCode, retryAfter, response, err = uploader.attemptSend(ctx, method, &requestURL, body)
+		return statusCode, retryAfter, err
+	})
+
+	return response, err
+}
+
+func (uploader *ObjectStoreUploader) attemptSend(ctx context.Context, method string, requestURL *url.URL, body []byte) (int, time.Duration, *objectStoreResponse, error) {
+	request, err := http.NewRequestWithContext(ctx, method, requestURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	bodyHash := md5.Sum(body)
+
+	request.ContentLength = int64(len(body))
+	request.Header.Set("Content-Type", "application/octet-stream")
+	request.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(bodyHash[:]))
+
+	err = uploader.store.Prepare(request)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	resp, err := uploader.httpClient.Do(request)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		return resp.StatusCode, parseRetryAfter(resp.Header.Get("Retry-After")), nil, fmt.Errorf("Upload failed: Status code %d", resp.StatusCode)
+	}
+
+	// completing a multipart upload can fail after the store has already
+	// answered 200, in which case the error is in the body
+	storeErr := objectStoreError{}
+	if xml.Unmarshal(responseBody, &storeErr) == nil && storeErr.XMLName.Local == "Error" {
+		return http.StatusInternalServerError, 0, nil, fmt.Errorf("Upload failed: %s: %s", storeErr.Code, storeErr.Message)
+	}
+
+	return resp.StatusCode, 0, &objectStoreResponse{
+		header: resp.Header,
+		body:   responseBody,
+	}, nil
+}
+
+func queryOf(key string, value string) url.Values {
+	return url.Values{key: []string{value}}
+}
@@ -0,0 +1,193 @@
+package uploader_test
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/executor/object_store"
+	. "github.com/cloudfoundry-incubator/executor/uploader"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeObjectStore stands in for an S3-compatible store, keeping just enough
+// state to serve plain and multipart uploads.
+type fakeObjectStore struct {
+	lock sync.Mutex
+
+	objects  map[string]string
+	parts    map[string]map[string]string
+	aborted  []string
+	requests []*http.Request
+
+	failParts bool
+}
+
+func (store *fakeObjectStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	store.requests = append(store.requests, r)
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS some-access-key:") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	query := r.URL.Query()
+
+	switch {
+	case r.Method == "POST" && query["uploads"] != nil:
+		store.parts[r.URL.Path] = map[string]string{}
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", r.URL.Path)
+
+	case r.Method == "PUT" && query.Get("partNumber") != "":
+		if store.failParts {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		store.parts[query.Get("uploadId")][query.Get("partNumber")] = string(body)
+		w.Header().Set("ETag", `"etag-`+query.Get("partNumber")+`"`)
+
+	case r.Method == "POST" && query.Get("uploadId") != "":
+		completed := struct {
+			Parts []struct {
+				PartNumber string
+				ETag       string
+			} `xml:"Part"`
+		}{}
+
+		err := xml.Unmarshal(body, &completed)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		object := ""
+		for _, part := range completed.Parts {
+			if part.ETag != `"etag-`+part.PartNumber+`"` {
+				fmt.Fprintf(w, "<Error><Code>InvalidPart</Code><Message>bad etag</Message></Error>")
+				return
+			}
+
+			object += store.parts[query.Get("uploadId")][part.PartNumber]
+		}
+
+		store.objects[r.URL.Path] = object
+
+	case r.Method == "DELETE" && query.Get("uploadId") != "":
+		store.aborted = append(store.aborted, query.Get("uploadId"))
+
+	case r.Method == "PUT":
+		store.objects[r.URL.Path] = string(body)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+var _ = Describe("ObjectStoreUploader", func() {
+	var store *fakeObjectStore
+	var server *httptest.Server
+	var uploader Uploader
+	var destination *url.URL
+
+	BeforeEach(func() {
+		store = &fakeObjectStore{
+			objects: map[string]string{},
+			parts:   map[string]map[string]string{},
+		}
+
+		server = httptest.NewServer(store)
+
+		endpoint, err := url.Parse(server.URL)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		uploader = NewObjectStoreUploader(
+			object_store.New(endpoint, "some-access-key", "some-secret-key"),
+			time.Second,
+			MinPartSize,
+			RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond},
+		)
+
+		destination, err = url.Parse("s3://some-bucket/some/droplet")
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("when the upload fits in a single chunk", func() {
+		It("puts the object in one signed request", func() {
+			numBytes, err := uploader.Upload(context.Background(), strings.NewReader("droplet"), destination, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(numBytes).Should(Equal(int64(len("droplet"))))
+
+			Ω(store.requests).Should(HaveLen(1))
+			Ω(store.objects["/some-bucket/some/droplet"]).Should(Equal("droplet"))
+		})
+	})
+
+	Context("when the chunk size is smaller than S3's minimum part size", func() {
+		BeforeEach(func() {
+			endpoint, err := url.Parse(server.URL)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			uploader = NewObjectStoreUploader(
+				object_store.New(endpoint, "some-access-key", "some-secret-key"),
+				time.Second,
+				10,
+				RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond},
+			)
+		})
+
+		It("uses the minimum part size instead", func() {
+			droplet := strings.Repeat("a", MinPartSize-1)
+
+			_, err := uploader.Upload(context.Background(), strings.NewReader(droplet), destination, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(len(store.requests)).Should(Equal(1))
+			Ω(len(store.objects["/some-bucket/some/droplet"])).Should(Equal(len(droplet)))
+		})
+	})
+
+	Context("when the upload is larger than a chunk", func() {
+		droplet := strings.Repeat("0123456789", MinPartSize/5) + "0123"
+
+		It("uploads it in parts and completes the upload", func() {
+			numBytes, err := uploader.Upload(context.Background(), strings.NewReader(droplet), destination, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(numBytes).Should(Equal(int64(len(droplet))))
+
+			Ω(len(store.parts["/some-bucket/some/droplet"])).Should(Equal(3))
+			Ω(store.objects["/some-bucket/some/droplet"] == droplet).Should(BeTrue())
+		})
+
+		Context("and a part fails", func() {
+			BeforeEach(func() {
+				store.failParts = true
+			})
+
+			It("aborts the multipart upload", func() {
+				_, err := uploader.Upload(context.Background(), strings.NewReader(droplet), destination, nil)
+				Ω(err).Should(HaveOccurred())
+
+				Ω(store.aborted).Should(Equal([]string{"/some-bucket/some/droplet"}))
+				Ω(store.objects).ShouldNot(HaveKey("/some-bucket/some/droplet"))
+			})
+		})
+	})
+})
@@ -0,0 +1,40 @@
+package uploader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/pivotal-golang/lager"
+)
+
+type UnsupportedSchemeError struct {
+	Scheme string
+}
+
+func (err UnsupportedSchemeError) Error() string {
+	return fmt.Sprintf("no uploader for scheme: %s", err.Scheme)
+}
+
+// SchemeUploader hands each upload to the backend registered for the
+// scheme of its destination, e.g. "https", "file" or "s3".
+type SchemeUploader struct {
+	backends map[string]Uploader
+}
+
+func NewSchemeUploader(backends map[string]Uploader) Uploader {
+	return &SchemeUploader{
+		backends: backends,
+	}
+}
+
+func (uploader *SchemeUploader) Upload(ctx context.Context, source io.Reader, url *url.URL, logger lager.Logger) (int64, error) {
+	backend, found := uploader.backends[strings.ToLower(url.Scheme)]
+	if !found {
+		return 0, UnsupportedSchemeError{Scheme: url.Scheme}
+	}
+
+	return backend.Upload(ctx, source, url, logger)
+}
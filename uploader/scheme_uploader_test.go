@@ -0,0 +1,99 @@
+package uploader_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/cloudfoundry-incubator/executor/uploader"
+	"github.com/cloudfoundry-incubator/executor/uploader/fake_uploader"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SchemeUploader", func() {
+	var httpUploader *fake_uploader.FakeUploader
+	var uploader Uploader
+	var destinationDir string
+
+	BeforeEach(func() {
+		var err error
+
+		destinationDir, err = ioutil.TempDir("", "upload-destination")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		httpUploader = &fake_uploader.FakeUploader{}
+
+		uploader = NewSchemeUploader(map[string]Uploader{
+			"http": httpUploader,
+			"file": NewFileUploader(destinationDir),
+		})
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(destinationDir)
+	})
+
+	It("hands the upload to the backend for the scheme", func() {
+		httpUploader.UploadReturns(42, nil)
+
+		destination, err := url.Parse("HTTP://example.com/droplet")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		numBytes, err := uploader.Upload(context.Background(), strings.NewReader("droplet"), destination, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(numBytes).Should(Equal(int64(42)))
+
+		Ω(httpUploader.UploadCallCount()).Should(Equal(1))
+
+		_, _, uploadedTo, _ := httpUploader.UploadArgsForCall(0)
+		Ω(uploadedTo).Should(Equal(destination))
+	})
+
+	It("returns an error when no backend is registered for the scheme", func() {
+		destination, err := url.Parse("ftp://example.com/droplet")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, err = uploader.Upload(context.Background(), strings.NewReader("droplet"), destination, nil)
+		Ω(err).Should(Equal(UnsupportedSchemeError{Scheme: "ftp"}))
+	})
+
+	Describe("file URLs", func() {
+		It("writes the upload to the path, creating its directory", func() {
+			destinationPath := filepath.Join(destinationDir, "some", "droplet")
+
+			numBytes, err := uploader.Upload(context.Background(), strings.NewReader("droplet"), &url.URL{Scheme: "file", Path: destinationPath}, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(numBytes).Should(Equal(int64(len("droplet"))))
+
+			contents, err := ioutil.ReadFile(destinationPath)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(contents)).Should(Equal("droplet"))
+
+			entries, err := ioutil.ReadDir(filepath.Dir(destinationPath))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(entries).Should(HaveLen(1))
+		})
+
+		It("refuses paths outside the root directory", func() {
+			escapingPath := destinationDir + "/../escaped-droplet"
+			_, err := uploader.Upload(context.Background(), strings.NewReader("droplet"), &url.URL{Scheme: "file", Path: escapingPath}, nil)
+			Ω(err).Should(Equal(ErrOutsideRoot))
+
+			_, err = os.Stat(filepath.Join(filepath.Dir(destinationDir), "escaped-droplet"))
+			Ω(os.IsNotExist(err)).Should(BeTrue())
+
+			_, err = uploader.Upload(context.Background(), strings.NewReader("droplet"), &url.URL{Scheme: "file", Path: "/etc/droplet"}, nil)
+			Ω(err).Should(Equal(ErrOutsideRoot))
+		})
+
+		It("requires a path", func() {
+			_, err := uploader.Upload(context.Background(), strings.NewReader("droplet"), &url.URL{Scheme: "file"}, nil)
+			Ω(err).Should(Equal(ErrMissingPath))
+		})
+	})
+})
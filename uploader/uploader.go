@@ -1,98 +1,518 @@
 package uploader
 
 import (
+	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/base64"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/pivotal-golang/lager"
 )
 
+const DefaultChunkSize = 16 * 1024 * 1024
+
+// MaxRetryWindow bounds how much of an upload is read before deciding how to
+// send it. An upload that does not fit is sent in chunks or streamed, and a
+// stream can only be retried while nothing past this window has been read.
+const MaxRetryWindow = 1024 * 1024
+
+// A destination that can put an upload back together from ranged chunks
+// says so by answering a HEAD with UploadOffsetHeader: how many bytes of the
+// upload it has already committed. Each chunk then carries a Content-Range,
+// and the last also carries the MD5 of the whole payload in
+// UploadContentMD5Header so the receiver can verify what it assembled.
+const (
+	UploadOffsetHeader     = "X-Upload-Offset"
+	UploadContentMD5Header = "X-Upload-Content-MD5"
+)
+
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+}
+
+// backoff doubles the delay with every attempt. A Retry-After sent by the
+// server wins if it asks for longer, up to MaxDelay.
+func (policy RetryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	delay := policy.MaxDelay
+	if attempt < 32 {
+		delay = policy.BaseDelay * time.Duration(uint(1)<<uint(attempt-1))
+	}
+
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+
+	if delay > policy.MaxDelay {
+		return policy.MaxDelay
+	}
+
+	return delay
+}
+
+func (policy RetryPolicy) normalize() RetryPolicy {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 1
+	}
+
+	return policy
+}
+
+type UploadError struct {
+	Attempts   int
+	StatusCode int
+	Err        error
+}
+
+func (err *UploadError) Error() string {
+	return fmt.Sprintf("%s (after %d attempts)", err.Err.Error(), err.Attempts)
+}
+
 type Uploader interface {
-	Upload(fileLocation string, destinationUrl *url.URL, logger lager.Logger) (int64, error)
+	Upload(ctx context.Context, source io.Reader, destinationUrl *url.URL, logger lager.Logger) (int64, error)
 }
 
 type URLUploader struct {
-	httpClient *http.Client
+	httpClient  *http.Client
+	chunkSize   int64
+	retryPolicy RetryPolicy
+	heads       *sync.Pool
 }
 
-func New(timeout time.Duration) Uploader {
-	httpTransport := &http.Transport{
-		ResponseHeaderTimeout: timeout,
-	}
+func New(timeout time.Duration, chunkSize int64, retryPolicy RetryPolicy) Uploader {
+	chunkSize = normalizeChunkSize(chunkSize)
 
-	httpClient := &http.Client{
-		Transport: httpTransport,
+	windowSize := chunkSize
+	if windowSize > MaxRetryWindow {
+		windowSize = MaxRetryWindow
 	}
 
 	return &URLUploader{
-		httpClient: httpClient,
+		httpClient:  newHTTPClient(timeout),
+		chunkSize:   chunkSize,
+		retryPolicy: retryPolicy.normalize(),
+		heads: &sync.Pool{
+			New: func() interface{} {
+				head := make([]byte, windowSize)
+				return &head
+			},
+		},
 	}
 }
 
-func (uploader *URLUploader) Upload(fileLocation string, url *url.URL, logger lager.Logger) (int64, error) {
-	var resp *http.Response
-	var err error
-	var uploadedBytes int64
+func newHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			ResponseHeaderTimeout: timeout,
+		},
+	}
+}
 
-	for attempt := 0; attempt < 3; attempt++ {
-		var request *http.Request
-		var sourceFile *os.File
-		var fileInfo os.FileInfo
+func normalizeChunkSize(chunkSize int64) int64 {
+	if chunkSize <= 0 {
+		return DefaultChunkSize
+	}
 
-		if logger != nil {
-			logger.Info("uploader.attempt", lager.Data{
-				"attempt": attempt,
-			})
-		}
+	return chunkSize
+}
+
+// Upload sends source in a single request, or in ranged chunks if the
+// destination supports them and source is larger than the retry window. Only
+// a chunk is ever held in memory, and nothing goes to disk.
+func (uploader *URLUploader) Upload(ctx context.Context, source io.Reader, url *url.URL, logger lager.Logger) (int64, error) {
+	pooledHead := uploader.heads.Get().(*[]byte)
+	defer uploader.heads.Put(pooledHead)
+
+	head := *pooledHead
 
-		sourceFile, err = os.Open(fileLocation)
+	n, err := io.ReadFull(source, head)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = uploader.uploadChunk(ctx, head[:n], nil, url, logger)
 		if err != nil {
 			return 0, err
 		}
 
-		fileInfo, err = sourceFile.Stat()
-		if err != nil {
+		return int64(n), nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	committed, chunked := uploader.committedOffset(ctx, url)
+	if chunked {
+		return uploader.uploadChunks(ctx, io.MultiReader(bytes.NewReader(head), source), committed, url, logger)
+	}
+
+	return uploader.uploadStream(ctx, head, source, url, logger)
+}
+
+// committedOffset asks the destination how much of the upload it has, which
+// it only answers if it takes ranged chunks.
+func (uploader *URLUploader) committedOffset(ctx context.Context, url *url.URL) (int64, bool) {
+	request, err := http.NewRequestWithContext(ctx, "HEAD", url.String(), nil)
+	if err != nil {
+		return 0, false
+	}
+
+	resp, err := uploader.httpClient.Do(request)
+	if err != nil {
+		return 0, false
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, false
+	}
+
+	offset, err := strconv.ParseInt(resp.Header.Get(UploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, false
+	}
+
+	return offset, true
+}
+
+// uploadChunks sends source in ranged chunks, skipping the committed bytes
+// the destination already has. A failed chunk is resent from wherever the
+// destination says it got to.
+func (uploader *URLUploader) uploadChunks(ctx context.Context, source io.Reader, committed int64, url *url.URL, logger lager.Logger) (int64, error) {
+	contentHash := md5.New()
+
+	_, err := io.CopyN(contentHash, source, committed)
+	if err != nil {
+		return 0, fmt.Errorf("destination has %d bytes, more than there are to upload", committed)
+	}
+
+	if committed > 0 && logger != nil {
+		logger.Info("uploader.resuming", lager.Data{"offset": committed})
+	}
+
+	chunk := make([]byte, uploader.chunkSize)
+	offset := committed
+
+	for {
+		n, err := io.ReadFull(source, chunk)
+
+		last := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !last {
 			return 0, err
 		}
 
-		contentHash := md5.New()
-		_, err = io.Copy(contentHash, sourceFile)
+		contentHash.Write(chunk[:n])
+
+		err = uploader.resumeChunk(ctx, chunk[:n], offset, last, contentHash, url, logger)
 		if err != nil {
 			return 0, err
 		}
 
-		_, err = sourceFile.Seek(0, 0)
-		if err != nil {
-			return 0, err
+		offset += int64(n)
+
+		if last {
+			return offset, nil
 		}
+	}
+}
+
+func (uploader *URLUploader) resumeChunk(ctx context.Context, chunk []byte, offset int64, last bool, contentHash hash.Hash, url *url.URL, logger lager.Logger) error {
+	var sent int64
+	attempted := false
+
+	return withRetries(ctx, uploader.retryPolicy, logger, lager.Data{"offset": offset, "length": len(chunk)}, func() (int, time.Duration, error) {
+		if attempted {
+			committed, ok := uploader.committedOffset(ctx, url)
+			if ok && committed >= offset && committed <= offset+int64(len(chunk)) {
+				sent = committed - offset
+			}
+		}
+
+		attempted = true
+
+		remaining := chunk[sent:]
+		if len(remaining) == 0 && !last {
+			return http.StatusOK, 0, nil
+		}
+
+		return uploader.attemptUpload(ctx, bytes.NewReader(remaining), int64(len(remaining)), chunkHeader(offset+sent, int64(len(remaining)), last, contentHash), url)
+	})
+}
+
+func chunkHeader(offset int64, length int64, last bool, contentHash hash.Hash) http.Header {
+	header := http.Header{}
+
+	total := "*"
+	if last {
+		total = fmt.Sprintf("%d", offset+length)
+		header.Set(UploadContentMD5Header, base64.StdEncoding.EncodeToString(contentHash.Sum(nil)))
+	}
 
-		request, err = http.NewRequest("POST", url.String(), sourceFile)
+	if length == 0 {
+		header.Set("Content-Range", fmt.Sprintf("bytes */%s", total))
+	} else {
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%s", offset, offset+length-1, total))
+	}
 
-		uploadedBytes = fileInfo.Size()
-		request.ContentLength = uploadedBytes
+	return header
+}
+
+// uploadStream sends head followed by the rest of source in one request,
+// with its MD5 in a Content-MD5 trailer. It can only be retried while
+// nothing past head has been read.
+func (uploader *URLUploader) uploadStream(ctx context.Context, head []byte, rest io.Reader, url *url.URL, logger lager.Logger) (int64, error) {
+	restReader := &countingReader{source: rest}
+	var uploaded int64
+
+	err := withRetries(ctx, uploader.retryPolicy, logger, lager.Data{"streamed": true}, func() (int, time.Duration, error) {
+		body := &streamBody{
+			source: io.MultiReader(bytes.NewReader(head), restReader),
+			hash:   md5.New(),
+		}
+
+		request, err := http.NewRequestWithContext(ctx, "POST", url.String(), body)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		request.ContentLength = -1
 		request.Header.Set("Content-Type", "application/octet-stream")
-		request.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(contentHash.Sum(nil)))
+		request.Trailer = http.Header{"Content-Md5": nil}
+		body.trailer = request.Trailer
+
+		statusCode, retryAfter, err := uploader.send(request)
+
+		// the transport may still be reading; nothing it reads now would be sent
+		body.close()
+
+		uploaded = int64(len(head)) + restReader.count
+		if err != nil && restReader.count > 0 {
+			return statusCode, retryAfter, finalError{err}
+		}
+
+		return statusCode, retryAfter, err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return uploaded, nil
+}
+
+type countingReader struct {
+	source io.Reader
+	count  int64
+}
+
+func (reader *countingReader) Read(p []byte) (int, error) {
+	n, err := reader.source.Read(p)
+	reader.count += int64(n)
+	return n, err
+}
+
+// streamBody hashes what it reads, filling in the Content-MD5 trailer at the
+// end. Once closed it reads nothing more.
+type streamBody struct {
+	lock    sync.Mutex
+	source  io.Reader
+	hash    hash.Hash
+	trailer http.Header
+	closed  bool
+}
+
+func (body *streamBody) Read(p []byte) (int, error) {
+	body.lock.Lock()
+	defer body.lock.Unlock()
+
+	if body.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	n, err := body.source.Read(p)
+	body.hash.Write(p[:n])
 
-		resp, err = uploader.httpClient.Do(request)
+	if err == io.EOF {
+		body.trailer.Set("Content-MD5", base64.StdEncoding.EncodeToString(body.hash.Sum(nil)))
+	}
+
+	return n, err
+}
+
+func (body *streamBody) close() {
+	body.lock.Lock()
+	defer body.lock.Unlock()
+
+	body.closed = true
+}
+
+// finalError is a failed attempt that must not be retried.
+type finalError struct {
+	err error
+}
+
+func (err finalError) Error() string {
+	return err.err.Error()
+}
+
+func (uploader *URLUploader) uploadChunk(ctx context.Context, chunk []byte, header http.Header, url *url.URL, logger lager.Logger) error {
+	return withRetries(ctx, uploader.retryPolicy, logger, lager.Data{"range": header.Get("Content-Range")}, func() (int, time.Duration, error) {
+		return uploader.attemptUpload(ctx, bytes.NewReader(chunk), int64(len(chunk)), header, url)
+	})
+}
+
+// withRetries calls attempt until it succeeds, fails in a way that is not
+// worth retrying, the policy runs out of attempts, or ctx is done.
+func withRetries(ctx context.Context, policy RetryPolicy, logger lager.Logger, data lager.Data, attempt func() (int, time.Duration, error)) error {
+	var lastStatusCode int
+
+	for attemptNumber := 1; ; attemptNumber++ {
+		if logger != nil {
+			logger.Info("uploader.attempt", withAttempt(data, attemptNumber))
+		}
+
+		statusCode, retryAfter, err := attempt()
 		if err == nil {
-			defer resp.Body.Close()
-			break
+			return nil
+		}
+
+		if statusCode != 0 {
+			lastStatusCode = statusCode
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		final, isFinal := err.(finalError)
+		if isFinal {
+			err = final.err
+		}
+
+		if isFinal || !isRetryable(statusCode) || attemptNumber >= policy.MaxAttempts {
+			return &UploadError{
+				Attempts:   attemptNumber,
+				StatusCode: lastStatusCode,
+				Err:        err,
+			}
+		}
+
+		delay := policy.backoff(attemptNumber, retryAfter)
+
+		if logger != nil {
+			retryData := withAttempt(data, attemptNumber)
+			retryData["status-code"] = statusCode
+			retryData["delay"] = delay.String()
+			retryData["error"] = err.Error()
+
+			logger.Info("uploader.retrying", retryData)
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
 		}
 	}
+}
+
+func withAttempt(data lager.Data, attempt int) lager.Data {
+	withAttempt := lager.Data{"attempt": attempt}
+	for key, value := range data {
+		withAttempt[key] = value
+	}
 
+	return withAttempt
+}
+
+func (uploader *URLUploader) attemptUpload(ctx context.Context, chunk *bytes.Reader, length int64, header http.Header, url *url.URL) (int, time.Duration, error) {
+	chunkHash := md5.New()
+	_, err := chunk.WriteTo(chunkHash)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 
+	_, err = chunk.Seek(0, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", url.String(), chunk)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for key, values := range header {
+		request.Header[key] = values
+	}
+
+	request.ContentLength = length
+	request.Header.Set("Content-Type", "application/octet-stream")
+	request.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(chunkHash.Sum(nil)))
+
+	return uploader.send(request)
+}
+
+func (uploader *URLUploader) send(request *http.Request) (int, time.Duration, error) {
+	resp, err := uploader.httpClient.Do(request)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
 	if resp.StatusCode >= 400 {
-		return 0, fmt.Errorf("Upload failed: Status code %d", resp.StatusCode)
+		return resp.StatusCode, parseRetryAfter(resp.Header.Get("Retry-After")), fmt.Errorf("Upload failed: Status code %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, 0, nil
+}
+
+// isRetryable treats transport errors (no status code), request timeouts,
+// throttling and server errors as transient. Any other 4xx is final.
+func isRetryable(statusCode int) bool {
+	switch {
+	case statusCode == 0:
+		return true
+	case statusCode == http.StatusRequestTimeout:
+		return true
+	case statusCode == 429:
+		return true
+	case statusCode >= 500:
+		return true
+	}
+
+	return false
+}
+
+func parseRetryAfter(retryAfter string) time.Duration {
+	if retryAfter == "" {
+		return 0
+	}
+
+	seconds, err := strconv.Atoi(retryAfter)
+	if err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	at, err := http.ParseTime(retryAfter)
+	if err == nil {
+		return at.Sub(time.Now())
 	}
 
-	return int64(uploadedBytes), nil
+	return 0
 }
@@ -1,15 +1,17 @@
 package uploader_test
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
-	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	. "github.com/cloudfoundry-incubator/executor/uploader"
@@ -26,31 +28,34 @@ var _ = Describe("Uploader", func() {
 	var serverRequests []*http.Request
 	var serverRequestBody []string
 
+	retryPolicy := RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    10 * time.Millisecond,
+	}
+
 	BeforeEach(func() {
 		testServer = nil
 		serverRequestBody = []string{}
 		serverRequests = []*http.Request{}
 
-		uploader = New(100 * time.Millisecond)
+		uploader = New(100*time.Millisecond, 1024, retryPolicy)
 	})
 
 	Describe("upload", func() {
 		var url *url.URL
-		var file *os.File
+		var contentString string
 		var expectedBytes int
 		var expectedMD5 string
 
 		BeforeEach(func() {
-			file, _ = ioutil.TempFile("", "foo")
-			contentString := "content that we can check later"
-			expectedBytes, _ = file.WriteString(contentString)
+			contentString = "content that we can check later"
+			expectedBytes = len(contentString)
 			rawMD5 := md5.Sum([]byte(contentString))
 			expectedMD5 = base64.StdEncoding.EncodeToString(rawMD5[:])
-			file.Close()
 		})
 
 		AfterEach(func() {
-			file.Close()
 			if testServer != nil {
 				testServer.Close()
 			}
@@ -75,7 +80,7 @@ var _ = Describe("Uploader", func() {
 			var err error
 			var numBytes int64
 			JustBeforeEach(func() {
-				numBytes, err = uploader.Upload(file.Name(), url, nil)
+				numBytes, err = uploader.Upload(context.Background(), strings.NewReader(contentString), url, nil)
 			})
 
 			It("uploads the file to the url", func() {
@@ -98,6 +103,210 @@ var _ = Describe("Uploader", func() {
 			It("does not return an error", func() {
 				Ω(err).ShouldNot(HaveOccurred())
 			})
+
+			It("does not send a content range", func() {
+				Ω(serverRequests[0].Header.Get("Content-Range")).Should(BeEmpty())
+			})
+		})
+
+		Context("when the content is larger than a chunk", func() {
+			BeforeEach(func() {
+				uploader = New(100*time.Millisecond, 10, retryPolicy)
+			})
+
+			Context("and the destination does not take chunks", func() {
+				var trailers []http.Header
+				var failAfterReading bool
+
+				BeforeEach(func() {
+					trailers = []http.Header{}
+					failAfterReading = false
+
+					testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						if r.Method == "HEAD" {
+							w.WriteHeader(http.StatusMethodNotAllowed)
+							return
+						}
+
+						serverRequests = append(serverRequests, r)
+
+						data, err := ioutil.ReadAll(r.Body)
+						Ω(err).ShouldNot(HaveOccurred())
+						serverRequestBody = append(serverRequestBody, string(data))
+						trailers = append(trailers, r.Trailer)
+
+						if failAfterReading {
+							w.WriteHeader(http.StatusServiceUnavailable)
+						}
+					}))
+
+					url, _ = url.Parse(testServer.URL + "/somepath")
+				})
+
+				It("streams the content in a single request, with its checksum in a trailer", func() {
+					numBytes, err := uploader.Upload(context.Background(), strings.NewReader(contentString), url, nil)
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(numBytes).Should(Equal(int64(expectedBytes)))
+
+					Ω(serverRequestBody).Should(Equal([]string{contentString}))
+					Ω(serverRequests[0].Header.Get("Content-Range")).Should(BeEmpty())
+					Ω(trailers[0].Get("Content-MD5")).Should(Equal(expectedMD5))
+				})
+
+				Context("when the request fails once the content has been read", func() {
+					BeforeEach(func() {
+						failAfterReading = true
+					})
+
+					It("does not retry, as the content cannot be read again", func() {
+						_, err := uploader.Upload(context.Background(), strings.NewReader(contentString), url, nil)
+						Ω(err).Should(Equal(&UploadError{
+							Attempts:   1,
+							StatusCode: http.StatusServiceUnavailable,
+							Err:        errors.New("Upload failed: Status code 503"),
+						}))
+
+						Ω(serverRequests).Should(HaveLen(1))
+					})
+				})
+			})
+
+			Context("and the destination takes chunks", func() {
+				var committed []byte
+				var failRange string
+				var failAfterCommitting int
+
+				BeforeEach(func() {
+					committed = []byte{}
+					failRange = ""
+					failAfterCommitting = 0
+
+					testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						if r.Method == "HEAD" {
+							w.Header().Set(UploadOffsetHeader, strconv.Itoa(len(committed)))
+							return
+						}
+
+						serverRequests = append(serverRequests, r)
+
+						data, err := ioutil.ReadAll(r.Body)
+						Ω(err).ShouldNot(HaveOccurred())
+						serverRequestBody = append(serverRequestBody, string(data))
+
+						if r.Header.Get("Content-Range") == failRange {
+							failRange = ""
+							committed = append(committed, data[:failAfterCommitting]...)
+							w.WriteHeader(http.StatusServiceUnavailable)
+							return
+						}
+
+						committed = append(committed, data...)
+					}))
+
+					url, _ = url.Parse(testServer.URL + "/somepath")
+				})
+
+				It("uploads the content in ranged chunks", func() {
+					numBytes, err := uploader.Upload(context.Background(), strings.NewReader(contentString), url, nil)
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(numBytes).Should(Equal(int64(expectedBytes)))
+
+					Ω(serverRequestBody).Should(Equal([]string{
+						"content th",
+						"at we can ",
+						"check late",
+						"r",
+					}))
+
+					Ω(serverRequests[0].Header.Get("Content-Range")).Should(Equal("bytes 0-9/*"))
+					Ω(serverRequests[1].Header.Get("Content-Range")).Should(Equal("bytes 10-19/*"))
+					Ω(serverRequests[2].Header.Get("Content-Range")).Should(Equal("bytes 20-29/*"))
+					Ω(serverRequests[3].Header.Get("Content-Range")).Should(Equal("bytes 30-30/31"))
+
+					Ω(string(committed)).Should(Equal(contentString))
+				})
+
+				It("sends the checksum of each chunk, and of the whole content with the last one", func() {
+					_, err := uploader.Upload(context.Background(), strings.NewReader(contentString), url, nil)
+					Ω(err).ShouldNot(HaveOccurred())
+
+					chunkMD5 := md5.Sum([]byte("content th"))
+					Ω(serverRequests[0].Header.Get("Content-MD5")).Should(Equal(base64.StdEncoding.EncodeToString(chunkMD5[:])))
+					Ω(serverRequests[0].Header.Get(UploadContentMD5Header)).Should(BeEmpty())
+
+					Ω(serverRequests[3].Header.Get(UploadContentMD5Header)).Should(Equal(expectedMD5))
+				})
+
+				Context("when a chunk fails part way", func() {
+					BeforeEach(func() {
+						failRange = "bytes 10-19/*"
+						failAfterCommitting = 5
+					})
+
+					It("resends it from where the destination got to", func() {
+						_, err := uploader.Upload(context.Background(), strings.NewReader(contentString), url, nil)
+						Ω(err).ShouldNot(HaveOccurred())
+
+						Ω(serverRequests).Should(HaveLen(5))
+						Ω(serverRequests[2].Header.Get("Content-Range")).Should(Equal("bytes 15-19/*"))
+						Ω(serverRequestBody[2]).Should(Equal(" can "))
+
+						Ω(string(committed)).Should(Equal(contentString))
+					})
+				})
+
+				Context("when the destination already has some of the content", func() {
+					BeforeEach(func() {
+						committed = []byte("content that we ")
+					})
+
+					It("resumes from there", func() {
+						numBytes, err := uploader.Upload(context.Background(), strings.NewReader(contentString), url, nil)
+						Ω(err).ShouldNot(HaveOccurred())
+						Ω(numBytes).Should(Equal(int64(expectedBytes)))
+
+						Ω(serverRequests[0].Header.Get("Content-Range")).Should(Equal("bytes 16-25/*"))
+						Ω(serverRequests[len(serverRequests)-1].Header.Get(UploadContentMD5Header)).Should(Equal(expectedMD5))
+
+						Ω(string(committed)).Should(Equal(contentString))
+					})
+				})
+			})
+		})
+
+		Context("when the content fits in a chunk but not in the retry window", func() {
+			var content string
+			var contentLengths []int64
+
+			BeforeEach(func() {
+				content = strings.Repeat("a", MaxRetryWindow+1)
+				contentLengths = []int64{}
+
+				uploader = New(100*time.Millisecond, 2*MaxRetryWindow, retryPolicy)
+
+				testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.Method == "HEAD" {
+						w.WriteHeader(http.StatusMethodNotAllowed)
+						return
+					}
+
+					data, err := ioutil.ReadAll(r.Body)
+					Ω(err).ShouldNot(HaveOccurred())
+					serverRequestBody = append(serverRequestBody, string(data))
+					contentLengths = append(contentLengths, r.ContentLength)
+				}))
+
+				url, _ = url.Parse(testServer.URL + "/somepath")
+			})
+
+			It("streams it rather than reading it all before sending", func() {
+				numBytes, err := uploader.Upload(context.Background(), strings.NewReader(content), url, nil)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(numBytes).Should(Equal(int64(len(content))))
+
+				Ω(contentLengths).Should(Equal([]int64{-1}))
+				Ω(serverRequestBody[0] == content).Should(BeTrue())
+			})
 		})
 
 		Context("when the upload times out", func() {
@@ -123,7 +332,7 @@ var _ = Describe("Uploader", func() {
 				logger := lagertest.NewTestLogger("test")
 
 				go func() {
-					_, err := uploader.Upload(file.Name(), url, logger)
+					_, err := uploader.Upload(context.Background(), strings.NewReader(contentString), url, logger)
 					errs <- err
 				}()
 
@@ -140,6 +349,43 @@ var _ = Describe("Uploader", func() {
 			})
 		})
 
+		Context("when the context is cancelled during a request", func() {
+			var requestInitiated chan struct{}
+
+			BeforeEach(func() {
+				uploader = New(time.Minute, 1024, retryPolicy)
+
+				requestInitiated = make(chan struct{}, 3)
+
+				testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					ioutil.ReadAll(r.Body)
+					requestInitiated <- struct{}{}
+					<-r.Context().Done()
+				}))
+
+				serverUrl := testServer.URL + "/somepath"
+				url, _ = url.Parse(serverUrl)
+			})
+
+			It("gives up without retrying", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+
+				errs := make(chan error)
+
+				go func() {
+					_, err := uploader.Upload(ctx, strings.NewReader(contentString), url, nil)
+					errs <- err
+				}()
+
+				Eventually(requestInitiated).Should(Receive())
+
+				cancel()
+
+				Eventually(errs).Should(Receive(Equal(context.Canceled)))
+				Consistently(requestInitiated).ShouldNot(Receive())
+			})
+		})
+
 		Context("when the upload fails with a protocol error", func() {
 			BeforeEach(func() {
 				// No server to handle things!
@@ -149,7 +395,7 @@ var _ = Describe("Uploader", func() {
 			})
 
 			It("should return the error", func() {
-				_, err := uploader.Upload(file.Name(), url, nil)
+				_, err := uploader.Upload(context.Background(), strings.NewReader(contentString), url, nil)
 				Ω(err).NotTo(BeNil())
 			})
 		})
@@ -162,9 +408,92 @@ var _ = Describe("Uploader", func() {
 				url, _ = url.Parse(serverUrl)
 			})
 
-			It("should return the error", func() {
-				_, err := uploader.Upload(file.Name(), url, nil)
-				Ω(err).NotTo(BeNil())
+			It("should return the error without retrying", func() {
+				_, err := uploader.Upload(context.Background(), strings.NewReader(contentString), url, nil)
+				Ω(err).Should(Equal(&UploadError{
+					Attempts:   1,
+					StatusCode: http.StatusNotFound,
+					Err:        errors.New("Upload failed: Status code 404"),
+				}))
+			})
+		})
+
+		Context("when the upload fails with a retryable status code", func() {
+			var statusCodes []int
+			var retryAfter string
+
+			BeforeEach(func() {
+				statusCodes = []int{}
+				retryAfter = ""
+
+				testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					serverRequests = append(serverRequests, r)
+
+					statusCode := http.StatusOK
+					if len(statusCodes) > 0 {
+						statusCode = statusCodes[0]
+						statusCodes = statusCodes[1:]
+					}
+
+					if retryAfter != "" {
+						w.Header().Set("Retry-After", retryAfter)
+					}
+
+					w.WriteHeader(statusCode)
+				}))
+
+				url, _ = url.Parse(testServer.URL + "/somepath")
+			})
+
+			for _, statusCode := range []int{http.StatusRequestTimeout, 429, http.StatusInternalServerError, http.StatusServiceUnavailable} {
+				statusCode := statusCode
+
+				It(fmt.Sprintf("retries a %d until it succeeds", statusCode), func() {
+					statusCodes = []int{statusCode, statusCode}
+
+					numBytes, err := uploader.Upload(context.Background(), strings.NewReader(contentString), url, nil)
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(numBytes).Should(Equal(int64(expectedBytes)))
+
+					Ω(serverRequests).Should(HaveLen(3))
+				})
+			}
+
+			It("gives up after the maximum number of attempts with the last status", func() {
+				statusCodes = []int{502, 503, 503, 503}
+
+				_, err := uploader.Upload(context.Background(), strings.NewReader(contentString), url, nil)
+				Ω(err).Should(HaveOccurred())
+
+				uploadErr, ok := err.(*UploadError)
+				Ω(ok).Should(BeTrue())
+				Ω(uploadErr.Attempts).Should(Equal(3))
+				Ω(uploadErr.StatusCode).Should(Equal(503))
+
+				Ω(serverRequests).Should(HaveLen(3))
+			})
+
+			Context("and the server asks to retry after a delay", func() {
+				BeforeEach(func() {
+					retryAfter = "1"
+
+					uploader = New(100*time.Millisecond, 1024, RetryPolicy{
+						MaxAttempts: 3,
+						BaseDelay:   time.Millisecond,
+						MaxDelay:    5 * time.Second,
+					})
+				})
+
+				It("waits as long as the server asked", func() {
+					statusCodes = []int{429}
+
+					startedAt := time.Now()
+
+					_, err := uploader.Upload(context.Background(), strings.NewReader(contentString), url, nil)
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(time.Since(startedAt)).Should(BeNumerically(">=", time.Second))
+				})
 			})
 		})
 	})